@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// Condition types of Configurations and Providers
+const (
+	// ConditionReady means the cloud resources are provisioned and the outputs are up-to-date, or the Provider is ready
+	ConditionReady = "Ready"
+	// ConditionReconciling means the controller is working on the Configuration, like applying or destroying
+	ConditionReconciling = "Reconciling"
+	// ConditionStalled means the Configuration stops at a state which can't be recovered without users' intervention
+	ConditionStalled = "Stalled"
+	// ConditionSourceReady means the Terraform module, inline or from a remote git repository, is valid and fetched
+	ConditionSourceReady = "SourceReady"
+	// ConditionProviderReady means the referenced Provider is found and ready
+	ConditionProviderReady = "ProviderReady"
+	// ConditionDrifted means the spec has changed since the cloud resources were last applied
+	ConditionDrifted = "Drifted"
+	// ConditionSuspended means no Terraform Job is created or deleted for the Configuration, as it's suspended by
+	// spec.suspend or the controller is paused
+	ConditionSuspended = "Suspended"
+)
+
+// Condition reasons which are not a ConfigurationState
+const (
+	// ReasonUpToDate is the reason of the Drifted condition when the cloud resources match the spec
+	ReasonUpToDate = "UpToDate"
+	// ReasonProviderReady is the reason of the Ready condition of a ready Provider
+	ReasonProviderReady = "ProviderReady"
+	// ReasonSuspended is the reason of the Suspended condition when the Configuration is suspended by spec.suspend
+	ReasonSuspended = "Suspended"
+	// ReasonControllerPaused is the reason of the Suspended condition when the controller is paused
+	ReasonControllerPaused = "ControllerPaused"
+	// ReasonResumed is the reason of the Suspended condition when the Configuration is resumed
+	ReasonResumed = "Resumed"
+)
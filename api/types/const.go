@@ -14,6 +14,8 @@ const (
 	// TerraformContainerName is the name of the container that executes terraform in the pod
 	TerraformContainerName     = "terraform-executor"
 	TerraformInitContainerName = "terraform-init"
+	// TerraformImportContainerName is the name of the init container that imports the resources of spec.imports
+	TerraformImportContainerName = "terraform-import"
 )
 
 const (
@@ -31,6 +33,38 @@ const (
 	TerraformApply TerraformExecutionType = "apply"
 	// TerraformDestroy is the name to mark `terraform destroy`
 	TerraformDestroy TerraformExecutionType = "destroy"
+	// TerraformPlan is the name to mark `terraform plan`, which is only run by a TerraformRun
+	TerraformPlan TerraformExecutionType = "plan"
+	// TerraformRefresh is the name to mark `terraform apply -refresh-only`, which is only run by a TerraformRun
+	TerraformRefresh TerraformExecutionType = "refresh"
+	// TerraformImport is the name to mark `terraform import`, which is only run by a TerraformRun
+	TerraformImport TerraformExecutionType = "import"
+)
+
+const (
+	// RunOutcomePending means the Job of the Terraform run is not created yet
+	RunOutcomePending = "Pending"
+	// RunOutcomeRunning means the Terraform run is not finished yet
+	RunOutcomeRunning = "Running"
+	// RunOutcomeSucceeded means the Terraform run succeeded
+	RunOutcomeSucceeded = "Succeeded"
+	// RunOutcomeFailed means the Terraform run failed
+	RunOutcomeFailed = "Failed"
+)
+
+const (
+	// RunReasonCreated means the run is the first apply of the Configuration
+	RunReasonCreated = "Created"
+	// RunReasonHCLChanged means the run is triggered as the HCL of the Configuration changed
+	RunReasonHCLChanged = "HCLChanged"
+	// RunReasonVariableChanged means the run is triggered as the variables of the Configuration changed
+	RunReasonVariableChanged = "VariableChanged"
+	// RunReasonSpecChanged means the run is triggered as the spec of the Configuration changed
+	RunReasonSpecChanged = "SpecChanged"
+	// RunReasonJobRecreated means the run is triggered as the Job of the last run is gone
+	RunReasonJobRecreated = "JobRecreated"
+	// RunReasonDeleted means the run is triggered as the Configuration is deleted
+	RunReasonDeleted = "Deleted"
 )
 
 const (
@@ -50,6 +84,11 @@ const (
 	// InputTFConfigurationVolumeMountPath is the volume mount path for input Terraform Configuration
 	InputTFConfigurationVolumeMountPath = "/opt/tf-configuration"
 
+	// VariableVolumeName is the volume name for Terraform variables file
+	VariableVolumeName = "tf-variables"
+	// VariableVolumeMountPath is the volume mount path for Terraform variables file
+	VariableVolumeMountPath = "/opt/tf-variables"
+
 	// BackendVolumeName is the volume name for Terraform backend
 	BackendVolumeName = "tf-backend"
 	// BackendVolumeMountPath is the volume mount path for Terraform backend
@@ -75,3 +114,71 @@ const (
 	// TerraformCredentialsHelperConfigVolumeMountPath is the volume mount path for terraform auth configurtaion
 	TerraformCredentialsHelperConfigVolumeMountPath = "/root/.terraform.d/plugins"
 )
+
+const (
+	// LabelStack is the label of the Configurations created by a Stack, whose value is the name of the Stack
+	LabelStack = "terraform.core.oam.dev/stack"
+	// LabelStackConfiguration is the label of the Configurations created by a Stack, whose value is the name of the
+	// Configuration in the Stack
+	LabelStackConfiguration = "terraform.core.oam.dev/stack-configuration"
+)
+
+const (
+	// AnnotationExtendTTL is the annotation of a Configuration which extends its lifetime set by spec.ttl or
+	// spec.expireAt, whose value is a duration like "24h"
+	AnnotationExtendTTL = "terraform.core.oam.dev/extend-ttl"
+	// AnnotationRetry is the annotation of a Configuration which retries its timed-out Terraform Job, whose value is
+	// any token like a timestamp. The Job is recreated once the token differs from the one of the Job.
+	AnnotationRetry = "terraform.core.oam.dev/retry"
+	// AnnotationTimedOut is the annotation of a Terraform Job suspended as its pod is stuck in Pending, whose value is
+	// why the Job is timed out
+	AnnotationTimedOut = "terraform.core.oam.dev/timed-out"
+	// AnnotationConnectionSecretLabels is the annotation of a connection Secret, whose value is the comma-separated keys
+	// of the labels added by spec.connectionSecret.labels
+	AnnotationConnectionSecretLabels = "terraform.core.oam.dev/connection-secret-labels"
+)
+
+const (
+	// LabelOrphanedBy is the label of the Terraform state kept after its Configuration is deleted with the deletion
+	// policy Orphan or Export, whose value is the name of the Configuration
+	LabelOrphanedBy = "terraform.core.oam.dev/orphaned-by"
+	// LabelOrphanedNamespace is the label of the Terraform state kept after its Configuration is deleted with the
+	// deletion policy Orphan or Export, whose value is the namespace of the Configuration
+	LabelOrphanedNamespace = "terraform.core.oam.dev/orphaned-namespace"
+)
+
+const (
+	// LabelRunLogOf is the label of the archived logs of the Terraform runs, whose value is the name of the
+	// Configuration. The archived logs aren't labeled as the sub-resources of the Configuration, so they are kept
+	// after the Configuration is deleted until the retention expires.
+	LabelRunLogOf = "terraform.core.oam.dev/run-log-of"
+	// LabelRunLogNamespace is the label of the archived logs of the Terraform runs, whose value is the namespace of
+	// the Configuration
+	LabelRunLogNamespace = "terraform.core.oam.dev/run-log-namespace"
+)
+
+const (
+	// LabelOutputTarget is the label of the Secrets and ConfigMaps created for spec.outputTargets of a Configuration
+	LabelOutputTarget = "terraform.core.oam.dev/output-target"
+)
+
+const (
+	// LabelCreatedBy is the label of the sub-resources created by the controller, whose value is CreatedByController
+	LabelCreatedBy = "terraform.core.oam.dev/created-by"
+	// LabelOwnedBy is the label of the sub-resources of a Configuration, whose value is the name of the Configuration
+	LabelOwnedBy = "terraform.core.oam.dev/owned-by"
+	// LabelOwnedNamespace is the label of the sub-resources of a Configuration, whose value is the namespace of the
+	// Configuration
+	LabelOwnedNamespace = "terraform.core.oam.dev/owned-namespace"
+	// LabelOwnedUID is the label of the sub-resources of a Configuration, whose value is the UID of the Configuration
+	LabelOwnedUID = "terraform.core.oam.dev/owned-uid"
+	// LabelProviderName is the label of the Terraform Jobs, whose value is the name of the Provider of the Configuration
+	LabelProviderName = "terraform.core.oam.dev/provider-name"
+	// LabelProviderNamespace is the label of the Terraform Jobs, whose value is the namespace of the Provider of the
+	// Configuration
+	LabelProviderNamespace = "terraform.core.oam.dev/provider-namespace"
+	// LabelRun is the label of the Terraform Jobs, whose value is the name of the TerraformRun executed by the Job
+	LabelRun = "terraform.core.oam.dev/run"
+	// CreatedByController is the value of LabelCreatedBy
+	CreatedByController = "terraform-controller"
+)
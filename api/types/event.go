@@ -0,0 +1,61 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// Reasons of the Events emitted for Configurations and Providers
+const (
+	// EventReasonJobCreated means a Terraform apply or destroy Job is created
+	EventReasonJobCreated = "JobCreated"
+	// EventReasonApplySucceeded means the cloud resources are provisioned
+	EventReasonApplySucceeded = "ApplySucceeded"
+	// EventReasonApplyFailed means `terraform init` or `terraform apply` failed
+	EventReasonApplyFailed = "ApplyFailed"
+	// EventReasonReloading means the cloud resources are re-applied as the HCL or the variables changed
+	EventReasonReloading = "Reloading"
+	// EventReasonDestroyStarted means the cloud resources are being destroyed
+	EventReasonDestroyStarted = "DestroyStarted"
+	// EventReasonDestroySucceeded means the cloud resources are destroyed and the sub-resources are cleaned up
+	EventReasonDestroySucceeded = "DestroySucceeded"
+	// EventReasonDestroyFailed means `terraform destroy` failed
+	EventReasonDestroyFailed = "DestroyFailed"
+	// EventReasonUndeclaredVariables means some variables are not declared in the module, which are ignored
+	EventReasonUndeclaredVariables = "UndeclaredVariables"
+	// EventReasonJobTimedOut means a Terraform Job exceeds its deadline or its pod is stuck in Pending
+	EventReasonJobTimedOut = "JobTimedOut"
+	// EventReasonJobRetried means a timed-out Terraform Job is deleted to be recreated, as the annotation
+	// terraform.core.oam.dev/retry of the Configuration is changed
+	EventReasonJobRetried = "JobRetried"
+	// EventReasonWaitingForMaintenanceWindow means applying the changes or destroying the cloud resources waits for
+	// the maintenance window to open
+	EventReasonWaitingForMaintenanceWindow = "WaitingForMaintenanceWindow"
+	// EventReasonInvalidMaintenanceWindow means the destroy window of a Configuration being deleted is invalid, which
+	// is ignored
+	EventReasonInvalidMaintenanceWindow = "InvalidMaintenanceWindow"
+	// EventReasonExpiring means the Configuration will be deleted soon as it expires by spec.ttl or spec.expireAt
+	EventReasonExpiring = "Expiring"
+	// EventReasonExpired means the Configuration is deleted as it expires by spec.ttl or spec.expireAt
+	EventReasonExpired = "Expired"
+	// EventReasonConnectionSecretConflict means the connection Secret is owned by another Configuration
+	EventReasonConnectionSecretConflict = "ConnectionSecretConflict"
+	// EventReasonInvalidConnectionSecret means the connection Secret can't be recreated with spec.connectionSecret.type,
+	// as the data isn't valid for the type
+	EventReasonInvalidConnectionSecret = "InvalidConnectionSecret"
+	// EventReasonProviderReady means the Provider becomes ready
+	EventReasonProviderReady = "ProviderReady"
+	// EventReasonProviderNotReady means the Provider becomes not ready
+	EventReasonProviderNotReady = "ProviderNotReady"
+)
@@ -38,19 +38,34 @@ const (
 	InvalidTerraformCredentialsSecretReference          ConfigurationState = "InvalidTerraformCredentialsSecretReference"
 	InvalidTerraformRCConfigMapReference                ConfigurationState = "InvalidTerraformRCConfigMapReference"
 	InvalidTerraformCredentialsHelperConfigMapReference ConfigurationState = "InvalidTerraformCredentialsHelperConfigMapReference"
+	InvalidVariableFromReference                        ConfigurationState = "InvalidVariableFromReference"
+	WaitingForDependencies                              ConfigurationState = "WaitingForDependencies"
+	DeletionBlocked                                     ConfigurationState = "DeletionBlocked"
+	Queued                                              ConfigurationState = "Queued"
+	AuthenticationFailed                                ConfigurationState = "AuthenticationFailed"
+	QuotaExceeded                                       ConfigurationState = "QuotaExceeded"
+	ResourceConflict                                    ConfigurationState = "ResourceConflict"
+	OperationTimeout                                    ConfigurationState = "OperationTimeout"
+	TimedOut                                            ConfigurationState = "TimedOut"
+	WaitingForMaintenanceWindow                         ConfigurationState = "WaitingForMaintenanceWindow"
 )
 
 // Stage is the Terraform stage
 type Stage string
 
 const (
-	InitStage  Stage = "InitStage"
-	ApplyStage Stage = "Apply"
+	InitStage   Stage = "InitStage"
+	ImportStage Stage = "ImportStage"
+	ApplyStage  Stage = "Apply"
 )
 
 const (
 	// MessageDestroyJobNotCompleted is the message when Configuration deletion isn't completed
 	MessageDestroyJobNotCompleted = "Configuration deletion isn't completed"
+	// MessageJobQueued is the message when the Terraform Job waits for the other Jobs to finish
+	MessageJobQueued = "The Terraform Job is queued as too many Jobs are running"
+	// MessageJobTimedOut is the message when the Terraform Job exceeds its deadline or its pod is stuck in Pending
+	MessageJobTimedOut = "The Terraform Job is timed out"
 	// MessageApplyJobNotCompleted is the message when cloud resources are not created completed
 	MessageApplyJobNotCompleted = "cloud resources are not created completed"
 	// MessageCloudResourceProvisioningAndChecking is the message when cloud resource is being provisioned
@@ -71,6 +86,28 @@ const (
 	ConfigurationReloadingAsVariableChanged = "Configuration's variable has changed, and starts reloading"
 	// ErrGenerateOutputs means error to generate outputs
 	ErrGenerateOutputs = "Hit an issue to generate outputs"
+	// MessageWaitingForRun is the message when the Terraform Job waits for the Job of a TerraformRun to finish, as
+	// only one Job could write the Terraform state at a time
+	MessageWaitingForRun = "The Terraform Job waits for the running TerraformRun to finish"
+	// MessageWaitingForDependencies is the message when the Configurations depended on are not available yet
+	MessageWaitingForDependencies = "Waiting for the dependencies to be available"
+	// MessageSuspended is the message when the Configuration is suspended by spec.suspend
+	MessageSuspended = "Reconciliation is suspended by spec.suspend"
+	// MessageControllerPaused is the message when the controller is paused
+	MessageControllerPaused = "Reconciliation of all Configurations is paused by the controller"
+	// MessageResumed is the message when the Configuration is resumed
+	MessageResumed = "Reconciliation is resumed"
+	// MessageDeletionBlockedBySuspension is the message when a suspended Configuration is being deleted
+	MessageDeletionBlockedBySuspension = "Deletion is blocked until the Configuration is resumed"
+	// MessageWaitingForApplyWindow is the message when the Configuration is to be applied out of its apply window
+	MessageWaitingForApplyWindow = "Applying is queued until the apply window opens"
+	// MessageWaitingForDestroyWindow is the message when the Configuration is deleted out of its destroy window
+	MessageWaitingForDestroyWindow = "Destroying the cloud resources waits until the destroy window opens"
+	// MessageDeletionBlockedByProtection is the message when a Configuration protected by spec.deletionProtection is
+	// being deleted
+	MessageDeletionBlockedByProtection = "Deletion is blocked by spec.deletionProtection, set it to false to destroy the cloud resources"
+	// MessageDeletionBlockedByDependents is the message when the Configuration is still depended on by others
+	MessageDeletionBlockedByDependents = "Configuration is still depended on by other Configurations"
 )
 
 // ProviderState is the type for Provider state
@@ -82,3 +119,19 @@ const (
 	// ProviderIsNotReady marks the state of a Provider is not ready
 	ProviderIsNotReady ProviderState = "ProviderNotReady"
 )
+
+// StackState is the type for Stack state
+type StackState string
+
+const (
+	// StackProvisioning means some Configurations in the Stack are not Available yet
+	StackProvisioning StackState = "Provisioning"
+	// StackAvailable means all the Configurations in the Stack are Available
+	StackAvailable StackState = "Available"
+	// StackFailed means some Configurations in the Stack failed
+	StackFailed StackState = "Failed"
+	// StackSpecNotValid means the Configurations in the Stack are not valid, like a circular dependency
+	StackSpecNotValid StackState = "StackSpecNotValid"
+	// StackDeleting means the Configurations in the Stack are being deleted
+	StackDeleting StackState = "Deleting"
+)
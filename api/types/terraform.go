@@ -5,6 +5,8 @@ import "k8s.io/apimachinery/pkg/api/resource"
 const (
 	// TerraformHCLConfigurationName is the file name for Terraform hcl Configuration
 	TerraformHCLConfigurationName = "main.tf"
+	// TerraformVariablesFileName is the file name for Terraform variables, which is loaded by Terraform automatically
+	TerraformVariablesFileName = "terraform.tfvars.json"
 )
 
 // ConfigurationType is the type for Terraform Configuration
@@ -21,6 +23,12 @@ type Git struct {
 	URL  string
 	Path string
 	Ref  GitRef
+
+	// Shallow, SparseCheckout, SparsePaths and Submodules control how the repository is fetched
+	Shallow        bool
+	SparseCheckout bool
+	SparsePaths    []string
+	Submodules     bool
 }
 
 // GitRef specifies the git reference
@@ -51,11 +51,20 @@ type ProviderCredentials struct {
 type ProviderStatus struct {
 	State   types.ProviderState `json:"state,omitempty"`
 	Message string              `json:"message,omitempty"`
+
+	// Conditions are the standard conditions of the Provider, maintained along with State
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Provider is the Schema for the providers API.
@@ -36,9 +36,28 @@ type ConfigurationSpec struct {
 	// GitRef is the git branch or tag or commit hash to checkout. Only used when Remote is specified.
 	GitRef apitypes.GitRef `json:"gitRef,omitempty"`
 
+	// Variable is the Terraform variables. A value can also reference an output of another Configuration, which will
+	// be resolved when that Configuration is Available, like
+	//	vpc_id:
+	//	  valueFrom:
+	//	    configurationOutput:
+	//	      name: vpc
+	//	      key: VPC_ID
+	// A sensitive output is read from the connection Secret of that Configuration, by the key which its
+	// spec.connectionSecret.outputs maps the output to.
+	// The variables are checked against the `variable` blocks of spec.hcl before the Terraform Job is created, the
+	// undeclared ones are reported by Warning Events. The modules of spec.remote are not checked.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	Variable *runtime.RawExtension `json:"variable,omitempty"`
 
+	// DependsOn are the Configurations which must be Available before this Configuration is applied. The Configurations
+	// whose outputs are referenced in spec.variable by `valueFrom.configurationOutput` are dependencies implicitly.
+	DependsOn []ConfigurationReference `json:"dependsOn,omitempty"`
+
+	// VariableFrom sources Terraform variables from Secrets or ConfigMaps in the namespace of the Configuration.
+	// They are resolved on every reconciliation, and the values in spec.variable take precedence over them.
+	VariableFrom []VariableFromSource `json:"variableFrom,omitempty"`
+
 	// Backend describes the Terraform backend configuration.
 	// This field is needed if the users use a git repo to provide the hcl files or
 	// want to use their custom Terraform backend (instead of the default kubernetes backend type).
@@ -49,6 +68,9 @@ type ConfigurationSpec struct {
 	// Path is the sub-directory of remote git repository.
 	Path string `json:"path,omitempty"`
 
+	// GitOptions tunes how the remote git repository is fetched. Only used when Remote is specified.
+	GitOptions *GitOptions `json:"gitOptions,omitempty"`
+
 	// WriteConnectionSecretToReference specifies the namespace and name of a
 	// Secret to which any connection details for this managed resource should
 	// be written. Connection details frequently include the endpoint, username,
@@ -56,8 +78,52 @@ type ConfigurationSpec struct {
 	// +optional
 	WriteConnectionSecretToReference *types.SecretReference `json:"writeConnectionSecretToRef,omitempty"`
 
+	// ConnectionSecret customizes the Secret written to WriteConnectionSecretToReference. By default, all outputs are
+	// written to an Opaque Secret with their names as keys.
+	// +optional
+	ConnectionSecret *ConnectionSecret `json:"connectionSecret,omitempty"`
+
+	// OutputTargets publish the outputs to more Secrets or ConfigMaps, which could be in other namespaces allowed by
+	// the controller. They are deleted along with the Configuration, or once removed from the list.
+	// +optional
+	OutputTargets []OutputTarget `json:"outputTargets,omitempty"`
+
 	// ProviderReference specifies the reference to Provider
 	ProviderReference *types.Reference `json:"providerRef,omitempty"`
+
+	// Suspend stops the controller from creating or deleting the Terraform Jobs of the Configuration, while the status
+	// of a running Job is still reported. The changes made during the suspension are applied once it's resumed, and
+	// deleting a suspended Configuration is blocked until it's resumed.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// JobPolicy tunes the timeouts and the retries of the Terraform Jobs. It takes effect from the next Job.
+	// +optional
+	JobPolicy *JobPolicy `json:"jobPolicy,omitempty"`
+
+	// ApplyWindow restricts creating the Terraform apply Jobs to a recurring maintenance window, including the first
+	// apply of a Configuration, the apply of the changes of the HCL or the variables, and the retry of a timed-out Job.
+	// The ones out of the window are queued until it opens, while a Job which is running is left as it is. It overrides
+	// the MaintenancePolicy of the namespace.
+	// +optional
+	ApplyWindow *MaintenanceWindow `json:"applyWindow,omitempty"`
+
+	// DestroyWindow restricts destroying the cloud resources to a recurring maintenance window, the deletion of the
+	// Configuration waits until it opens. It overrides the MaintenancePolicy of the namespace.
+	// +optional
+	DestroyWindow *MaintenanceWindow `json:"destroyWindow,omitempty"`
+
+	// TTL is how long the Configuration lives after it's created, then it's deleted along with the cloud resources.
+	// Warning Events are emitted a day and an hour before it expires, and the annotation
+	// `terraform.core.oam.dev/extend-ttl`, like "24h", extends its lifetime.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+
+	// ExpireAt is when the Configuration is deleted along with the cloud resources, the earlier one of it and TTL takes
+	// effect. It's extended by the annotation `terraform.core.oam.dev/extend-ttl` too.
+	// +optional
+	ExpireAt *metav1.Time `json:"expireAt,omitempty"`
+
 	// +kubebuilder:pruning:PreserveUnknownFields
 	JobEnv *runtime.RawExtension `json:"JobEnv,omitempty"`
 	// InlineCredentials specifies the credentials in spec.HCl field as below.
@@ -69,10 +135,36 @@ type ConfigurationSpec struct {
 	// Or indicates a Terraform module or configuration don't need credentials at all, like provider `random`
 	InlineCredentials bool `json:"inlineCredentials,omitempty"`
 
+	// DeletionProtection refuses to destroy the cloud resources and delete the Configuration until it's set to false.
+	// The deletion is rejected by the validating webhook if it's enabled, or else blocked by the controller. The
+	// TerraformRuns of the type destroy are failed too.
+	// +optional
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
 	// DeleteResource will determine whether provisioned cloud resources will be deleted when CR is deleted
+	// Deprecated: use DeletionPolicy instead, which takes precedence over it. false is the same as Orphan.
 	// +kubebuilder:default:=true
 	DeleteResource *bool `json:"deleteResource,omitempty"`
 
+	// DeletionPolicy is what happens to the cloud resources when the Configuration is deleted. Delete destroys them.
+	// Orphan keeps them, along with the Terraform state in the backend which is labeled for adoption. Export keeps them
+	// too, and copies the Terraform state into the Secret ExportStateTo, the deletion fails until the state is exported.
+	// It defaults to Delete.
+	// +kubebuilder:validation:Enum=Delete;Orphan;Export
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// ExportStateTo is the Secret which the Terraform state is copied into under the key `tfstate`, when the
+	// Configuration is deleted with the deletion policy Export. The namespace defaults to the one of the Configuration.
+	// +optional
+	ExportStateTo *types.SecretReference `json:"exportStateTo,omitempty"`
+
+	// Imports adopts the existing cloud resources into the Terraform state before they're applied, instead of
+	// creating them. They're rendered as `import` blocks for Terraform 1.5+, or imported by `terraform import` before
+	// `terraform apply` for the older versions. The imports added later take effect from the next apply.
+	// +optional
+	Imports []TerraformRunImport `json:"imports,omitempty"`
+
 	// Region is cloud provider's region. It will override the region in the region field of ProviderReference
 	Region string `json:"customRegion,omitempty"`
 
@@ -93,6 +185,42 @@ type ConfigurationSpec struct {
 	TerraformCredentialsHelperConfigMapReference *v1.SecretReference `json:"terraformCredentialsHelperConfigMapReference,omitempty"`
 }
 
+// DeletionPolicy is what happens to the cloud resources when the Configuration is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete destroys the cloud resources
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyOrphan keeps the cloud resources and the Terraform state in the backend
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+	// DeletionPolicyExport keeps the cloud resources, and copies the Terraform state into a Secret
+	DeletionPolicyExport DeletionPolicy = "Export"
+)
+
+// JobPolicy tunes the timeouts and the retries of the Terraform Jobs of a Configuration
+type JobPolicy struct {
+	// ActiveDeadlineSeconds is the maximum duration in seconds a Terraform Job runs, including its retries. Then the
+	// Job is terminated and the Configuration turns TimedOut. There is no deadline by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
+
+	// BackoffLimit is the number of retries before a Terraform Job is failed. The retries are delayed with an
+	// exponential back-off (10s, 20s, 40s ...) capped at six minutes. It defaults to the setting of the controller,
+	// which is 3. Set it to 0 for the modules which are not idempotent.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BackoffLimit *int32 `json:"backoffLimit,omitempty"`
+
+	// PendingTimeoutSeconds is the maximum duration in seconds a pod of a Terraform Job stays Pending, like being
+	// unschedulable or in ImagePullBackOff. Then the Job is suspended and the Configuration turns TimedOut. It
+	// defaults to 600. A timed-out Job is recreated when the spec or the variables of the Configuration change, or
+	// when the annotation terraform.core.oam.dev/retry of the Configuration is set to a new value, like a timestamp.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	PendingTimeoutSeconds *int64 `json:"pendingTimeoutSeconds,omitempty"`
+}
+
 // ConfigurationStatus defines the observed state of Configuration
 type ConfigurationStatus struct {
 	// observedGeneration is the most recent generation observed for this Configuration. It corresponds to the
@@ -103,6 +231,68 @@ type ConfigurationStatus struct {
 
 	Apply   ConfigurationApplyStatus   `json:"apply,omitempty"`
 	Destroy ConfigurationDestroyStatus `json:"destroy,omitempty"`
+
+	// Conditions are the standard conditions of the Configuration, like Ready, Reconciling and Stalled. They are
+	// maintained along with the states in Apply and Destroy.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// History is the records of the latest Terraform runs, the oldest record comes first
+	// +optional
+	History []RunRecord `json:"history,omitempty"`
+
+	// ExpireAt is when the Configuration is deleted as it expires by spec.ttl or spec.expireAt
+	// +optional
+	ExpireAt *metav1.Time `json:"expireAt,omitempty"`
+	// LastExpiryWarningTime is when the latest warning Event about the expiry is emitted
+	// +optional
+	LastExpiryWarningTime *metav1.Time `json:"lastExpiryWarningTime,omitempty"`
+
+	// Imports are the outcomes of importing the resources of spec.imports
+	// +optional
+	Imports []ImportStatus `json:"imports,omitempty"`
+}
+
+// ImportStatus is the outcome of importing a resource into the Terraform state
+type ImportStatus struct {
+	Address string `json:"address"`
+	ID      string `json:"id"`
+	// Phase is Pending, Succeeded or Failed
+	Phase   string `json:"phase"`
+	Message string `json:"message,omitempty"`
+}
+
+// RunRecord is the record of a Terraform run, which is a Terraform Job
+type RunRecord struct {
+	// ID identifies the run among the runs of the Configuration
+	ID      string                          `json:"id"`
+	Type    apitypes.TerraformExecutionType `json:"type"`
+	JobName string                          `json:"jobName"`
+	// Reason is why the run is triggered, like Created, HCLChanged, VariableChanged and Deleted
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// ObservedGeneration is the generation of the Configuration the run is for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// ConfigurationHash is the hash of the rendered Terraform configuration the run is for
+	// +optional
+	ConfigurationHash string      `json:"configurationHash,omitempty"`
+	StartTime         metav1.Time `json:"startTime"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Outcome is Running, Succeeded or Failed
+	Outcome string `json:"outcome"`
+	// Message is the summary of the result
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LogRef is where the full logs of the run are archived, like configmap://<namespace>/<name> or
+	// s3://<bucket>/<key>
+	// +optional
+	LogRef string `json:"logRef,omitempty"`
 }
 
 // ConfigurationApplyStatus is the status for Configuration apply
@@ -113,17 +303,153 @@ type ConfigurationApplyStatus struct {
 	// Region is the region for the cloud resources created by this Configuration. If spec.region is not empty, it's the
 	// value of it. Otherwise, it's the value of spec.providerReference.region.
 	Region string `json:"region,omitempty"`
+	// Diagnostics are the errors and warnings reported by Terraform in the last failed run. They are only reported by
+	// the Terraform images of 0.15.3+, which support the machine-readable output
+	// +optional
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
 }
 
 // ConfigurationDestroyStatus is the status for Configuration destroy
 type ConfigurationDestroyStatus struct {
 	State   apitypes.ConfigurationState `json:"state,omitempty"`
 	Message string                      `json:"message,omitempty"`
+	// Diagnostics are the errors and warnings reported by Terraform in the last failed run. They are only reported by
+	// the Terraform images of 0.15.3+, which support the machine-readable output
+	// +optional
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Diagnostic is an error or a warning reported by Terraform
+type Diagnostic struct {
+	// Severity is either error or warning
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	// +optional
+	Detail string `json:"detail,omitempty"`
+	// Address is the address of the resource the diagnostic is about, like alicloud_oss_bucket.bucket
+	// +optional
+	Address string `json:"address,omitempty"`
+	// Filename is the file of the Terraform configuration the diagnostic is about
+	// +optional
+	Filename string `json:"filename,omitempty"`
+	// Line is the line in the file the diagnostic is about
+	// +optional
+	Line int `json:"line,omitempty"`
 }
 
 // Property is the property for an output
 type Property struct {
+	// Value is the value of the output. The value of a structured type, like a list, map or object, is in JSON.
+	// It's empty if the output is sensitive.
 	Value string `json:"value,omitempty"`
+
+	// Type is the Terraform type of the output, like `string`, `number`, `bool`, or a structured type in JSON
+	// like `["list","string"]`
+	Type string `json:"type,omitempty"`
+
+	// Sensitive marks the output is sensitive in Terraform. Its value is only written to the connection Secret.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// ConfigurationReference references a Configuration
+type ConfigurationReference struct {
+	// Name of the referenced Configuration
+	Name string `json:"name"`
+
+	// Namespace of the referenced Configuration. Default to the namespace of the referencing Configuration. Another
+	// namespace is only allowed if it's in the controller flag `--allowed-dependency-namespaces`.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ConfigurationOutputReference references an output of a Configuration
+type ConfigurationOutputReference struct {
+	ConfigurationReference `json:",inline"`
+
+	// Key is the name of the output
+	Key string `json:"key"`
+}
+
+// VariableFromSource references a Secret or a ConfigMap which provides Terraform variables
+type VariableFromSource struct {
+	// Kind is the kind of the referenced object
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Name is the name of the referenced object
+	Name string `json:"name"`
+
+	// Key is the key in the referenced object. If it's empty, every key of the object is taken as a variable.
+	Key string `json:"key,omitempty"`
+
+	// VariableName is the name of the Terraform variable to set from Key. Default to Key.
+	VariableName string `json:"variableName,omitempty"`
+
+	// Optional specifies whether the referenced object or key can be missing
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ConnectionSecret describes how the outputs are written to the connection Secret
+type ConnectionSecret struct {
+	// Outputs are the outputs written to the Secret. If it's empty, all outputs are written.
+	Outputs []ConnectionSecretOutput `json:"outputs,omitempty"`
+
+	// Templates are extra keys of the Secret rendered by Go templates, with the values of all outputs as the data,
+	// like `postgres://{{.username}}:{{.password}}@{{.host}}`. A key can't be the same as the key of an output.
+	Templates map[string]string `json:"templates,omitempty"`
+
+	// Type is the type of the Secret, like `kubernetes.io/dockerconfigjson`. Default to `Opaque`.
+	Type v1.SecretType `json:"type,omitempty"`
+
+	// Labels are added to the Secret, and removed from it once they are removed from here
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are added to the Secret
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ConnectionSecretOutput selects an output to write to the connection Secret, or to publish to a Secret or a ConfigMap
+// of spec.outputTargets
+type ConnectionSecretOutput struct {
+	// Name is the name of the output
+	Name string `json:"name"`
+
+	// Key is the key of the output in the Secret or the ConfigMap. Default to Name.
+	Key string `json:"key,omitempty"`
+}
+
+// OutputTarget is a Secret or a ConfigMap the outputs are published to
+type OutputTarget struct {
+	// Kind is the kind of the target
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	Kind string `json:"kind"`
+
+	// Name is the name of the target
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the target. Default to the namespace of the Configuration.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Outputs are the outputs published to the target. If it's empty, all outputs are published, except the sensitive
+	// ones for a ConfigMap.
+	Outputs []ConnectionSecretOutput `json:"outputs,omitempty"`
+}
+
+// GitOptions describes how to fetch the remote git repository
+type GitOptions struct {
+	// Shallow fetches only the commit pointed by GitRef instead of the whole history. A commit of gitRef.commit is
+	// fetched by its SHA, which needs the git server to allow it, like `uploadpack.allowReachableSHA1InWant`.
+	// Otherwise, all the branches are fetched with the whole history to find the commit.
+	Shallow bool `json:"shallow,omitempty"`
+
+	// SparseCheckout checks out only spec.path and SparsePaths instead of the whole repository
+	SparseCheckout bool `json:"sparseCheckout,omitempty"`
+
+	// SparsePaths are the extra directories to check out along with spec.path, like shared modules
+	// referenced by relative paths. Only used when SparseCheckout is true.
+	SparsePaths []string `json:"sparsePaths,omitempty"`
+
+	// Submodules initializes and updates the git submodules recursively
+	Submodules bool `json:"submodules,omitempty"`
 }
 
 // Backend describes the Terraform backend configuration
@@ -168,8 +494,12 @@ type S3BackendConf struct {
 // Configuration is the Schema for the configurations API
 // +kubebuilder:storageversion
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 // +kubebuilder:printcolumn:name="APPLY",type="string",JSONPath=".status.apply.state"
 // +kubebuilder:printcolumn:name="DESTROY",type="string",JSONPath=".status.destroy.state"
+// +kubebuilder:printcolumn:name="SUSPENDED",type="boolean",JSONPath=".spec.suspend",priority=1
+// +kubebuilder:printcolumn:name="PROTECTED",type="boolean",JSONPath=".spec.deletionProtection",priority=1
+// +kubebuilder:printcolumn:name="EXPIRES",type="date",JSONPath=".status.expireAt",priority=1
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 type Configuration struct {
 	metav1.TypeMeta   `json:",inline"`
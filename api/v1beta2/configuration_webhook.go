@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-terraform-core-oam-dev-v1beta2-configuration,mutating=false,failurePolicy=fail,sideEffects=None,groups=terraform.core.oam.dev,resources=configurations,verbs=delete,versions=v1beta2,name=vconfiguration.terraform.core.oam.dev,admissionReviewVersions=v1
+
+// ConfigurationValidator rejects deleting the Configurations protected by spec.deletionProtection
+// +kubebuilder:object:generate=false
+type ConfigurationValidator struct{}
+
+var _ webhook.CustomValidator = &ConfigurationValidator{}
+
+// SetupWebhookWithManager registers the validating webhook of Configuration
+func (v *ConfigurationValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&Configuration{}).WithValidator(v).Complete()
+}
+
+// ValidateCreate allows creating any Configuration
+func (v *ConfigurationValidator) ValidateCreate(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate allows updating any Configuration, including lifting its deletion protection
+func (v *ConfigurationValidator) ValidateUpdate(_ context.Context, _, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateDelete rejects deleting the Configuration protected by spec.deletionProtection
+func (v *ConfigurationValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	configuration, ok := obj.(*Configuration)
+	if !ok {
+		return nil, fmt.Errorf("expected a Configuration but got %T", obj)
+	}
+	if configuration.Spec.DeletionProtection {
+		return nil, fmt.Errorf("the Configuration %s/%s is protected by spec.deletionProtection, set it to false before deleting it", configuration.Namespace, configuration.Name)
+	}
+	return nil, nil
+}
@@ -0,0 +1,23 @@
+package v1beta2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigurationValidateDelete(t *testing.T) {
+	v := &ConfigurationValidator{}
+	configuration := &Configuration{ObjectMeta: metav1.ObjectMeta{Name: "rds", Namespace: "prod"}}
+	_, err := v.ValidateDelete(context.Background(), configuration)
+	assert.Nil(t, err)
+
+	configuration.Spec.DeletionProtection = true
+	_, err = v.ValidateDelete(context.Background(), configuration)
+	assert.EqualError(t, err, "the Configuration prod/rds is protected by spec.deletionProtection, set it to false before deleting it")
+
+	_, err = v.ValidateDelete(context.Background(), &Stack{})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaintenanceWindow is a recurring period of time when the changes to the cloud resources are allowed, like every
+// Saturday from 02:00 for four hours
+type MaintenanceWindow struct {
+	// Schedule is when the window opens in the cron format of five numeric fields: minute, hour, day of month, month
+	// and day of week, like "0 2 * * 6". Each field supports `*`, lists, ranges and steps.
+	Schedule string `json:"schedule"`
+	// TimeZone is the IANA time zone of the schedule, like "Asia/Shanghai". It defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Duration is how long the window stays open, like "4h"
+	Duration metav1.Duration `json:"duration"`
+}
+
+// MaintenancePolicySpec defines the desired state of MaintenancePolicy
+type MaintenancePolicySpec struct {
+	// ConfigurationSelector selects the Configurations in the namespace which the policy applies to, all of them if
+	// it's empty
+	// +optional
+	ConfigurationSelector *metav1.LabelSelector `json:"configurationSelector,omitempty"`
+	// ApplyWindow is the maintenance window of the selected Configurations which don't set spec.applyWindow
+	// +optional
+	ApplyWindow *MaintenanceWindow `json:"applyWindow,omitempty"`
+	// DestroyWindow is the maintenance window of the selected Configurations which don't set spec.destroyWindow
+	// +optional
+	DestroyWindow *MaintenanceWindow `json:"destroyWindow,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenancePolicy is the Schema for the maintenancepolicies API, which sets the maintenance windows of the
+// Configurations in its namespace. A Configuration selected by more than one policy follows the first one by name.
+// +kubebuilder:printcolumn:name="APPLY",type="string",JSONPath=".spec.applyWindow.schedule"
+// +kubebuilder:printcolumn:name="DESTROY",type="string",JSONPath=".spec.destroyWindow.schedule"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+type MaintenancePolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec MaintenancePolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenancePolicyList contains a list of MaintenancePolicy
+type MaintenancePolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenancePolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenancePolicy{}, &MaintenancePolicyList{})
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/oam-dev/terraform-controller/api/types"
+)
+
+// StackSpec defines the desired state of Stack
+type StackSpec struct {
+	// Configurations are the templates of the Configurations in the Stack. They are created as
+	// `<stack name>-<name>` in the namespace of the Stack, once the Configurations they depend on are Available,
+	// and they are deleted in the reverse order when the Stack is deleted.
+	Configurations []StackConfiguration `json:"configurations"`
+}
+
+// StackConfiguration is the template of a Configuration in a Stack
+type StackConfiguration struct {
+	// Name of the Configuration in the Stack
+	Name string `json:"name"`
+
+	// DependsOn are the names of the Configurations in the Stack which this one depends on. The Configurations in the
+	// Stack whose outputs are referenced in spec.variable by `valueFrom.configurationOutput` are dependencies implicitly.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Spec is the spec of the Configuration. The name in `valueFrom.configurationOutput` of a variable refers to
+	// a Configuration in the Stack when its namespace is not set and a Configuration in the Stack has that name.
+	Spec ConfigurationSpec `json:"spec"`
+}
+
+// StackStatus defines the observed state of Stack
+type StackStatus struct {
+	// observedGeneration is the most recent generation observed for this Stack
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	State   apitypes.StackState `json:"state,omitempty"`
+	Message string              `json:"message,omitempty"`
+
+	// Configurations are the states of the Configurations in the Stack, in the order they are applied
+	Configurations []StackConfigurationStatus `json:"configurations,omitempty"`
+}
+
+// StackConfigurationStatus is the status of a Configuration in a Stack
+type StackConfigurationStatus struct {
+	// Name of the Configuration in the Stack
+	Name string `json:"name"`
+
+	// ConfigurationName is the name of the created Configuration
+	ConfigurationName string `json:"configurationName"`
+
+	State   apitypes.ConfigurationState `json:"state,omitempty"`
+	Message string                      `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// Stack is the Schema for the stacks API, which orchestrates a group of dependent Configurations
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+type Stack struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StackSpec   `json:"spec,omitempty"`
+	Status StackStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// StackList contains a list of Stack
+type StackList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Stack `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Stack{}, &StackList{})
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	apitypes "github.com/oam-dev/terraform-controller/api/types"
+	types "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+)
+
+// TerraformRunSpec defines the desired state of TerraformRun
+type TerraformRunSpec struct {
+	// ConfigurationName is the name of the Configuration in the same namespace which the run is for
+	ConfigurationName string `json:"configurationName"`
+
+	// Type is the Terraform operation. A TerraformRun created by users runs with the rendered configuration and the
+	// variables of the latest apply of the Configuration.
+	// +kubebuilder:validation:Enum=apply;destroy;plan;refresh;import
+	Type apitypes.TerraformExecutionType `json:"type"`
+
+	// Targets limit the operation to the resources, which are the same as the `-target` options of Terraform
+	// +optional
+	Targets []string `json:"targets,omitempty"`
+
+	// Import is the resource to import, it's required when the type is import
+	// +optional
+	Import *TerraformRunImport `json:"import,omitempty"`
+}
+
+// TerraformRunImport is the resource to import into the Terraform state
+type TerraformRunImport struct {
+	// Address is the address of the resource in the configuration, like alicloud_oss_bucket.bucket,
+	// module.oss.alicloud_oss_bucket.bucket[0] or alicloud_oss_bucket.bucket["logs"]
+	// +kubebuilder:validation:Pattern=`^(module\.[A-Za-z_][A-Za-z0-9_-]*(\[([0-9]+|"[^"\\$%\r\n]*")\])?\.)*[A-Za-z_][A-Za-z0-9_-]*\.[A-Za-z_][A-Za-z0-9_-]*(\[([0-9]+|"[^"\\$%\r\n]*")\])?$`
+	Address string `json:"address"`
+	// ID is the ID of the cloud resource
+	ID string `json:"id"`
+}
+
+// TerraformRunStatus defines the observed state of TerraformRun
+type TerraformRunStatus struct {
+	// Phase is Pending, Running, Succeeded or Failed
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// JobName and JobNamespace are of the Terraform Job which executes the run
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+	// +optional
+	JobNamespace string `json:"jobNamespace,omitempty"`
+
+	// ConfigurationHash is the hash of the rendered Terraform configuration
+	// +optional
+	ConfigurationHash string `json:"configurationHash,omitempty"`
+	// VariableHash is the hash of the variables, including the credentials from the Provider
+	// +optional
+	VariableHash string `json:"variableHash,omitempty"`
+	// Image is the Terraform image
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ProviderReference is the Provider the run uses
+	// +optional
+	ProviderReference *types.Reference `json:"providerRef,omitempty"`
+
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TerraformRun is the Schema for the terraformruns API, which is an execution of Terraform for a Configuration
+// A TerraformRun created by users waits until no other Terraform Job of the Configuration is running, the maintenance
+// window is open and the limits of the Terraform Jobs allow, then its Job is created and owned by it. While it's
+// running, the Configuration turns Queued instead of creating or replacing its apply or destroy Job. The Jobs of the
+// runs created by the controller keep their names, like `<name>-apply`, and aren't owned by the runs, otherwise
+// deleting such a run would delete the apply Job and trigger another apply.
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="CONFIGURATION",type="string",JSONPath=".spec.configurationName"
+// +kubebuilder:printcolumn:name="TYPE",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="PHASE",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+type TerraformRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TerraformRunSpec   `json:"spec,omitempty"`
+	Status TerraformRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// TerraformRunList contains a list of TerraformRun
+type TerraformRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TerraformRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TerraformRun{}, &TerraformRunList{})
+}
@@ -46,6 +46,11 @@ type Backend interface {
 	// CleanUp is used to clean up the backend when delete the configuration object
 	// For example, if the configuration use kubernetes backend, CleanUp will delete the backend secret
 	CleanUp(ctx context.Context) error
+
+	// Release marks the Terraform state with the labels, as it's kept for others to adopt when the configuration
+	// object is deleted without destroying the cloud resources
+	// For example, if the configuration use kubernetes backend, Release will label the backend secret
+	Release(ctx context.Context, labels map[string]string) error
 }
 
 type backendInitFunc func(k8sClient client.Client, backendConf interface{}, credentials map[string]string) (Backend, error)
@@ -158,6 +158,28 @@ func (k *K8SBackend) CleanUp(ctx context.Context) error {
 	return nil
 }
 
+// Release labels the Terraform kubernetes backend secret when deleting the configuration object without destroying the
+// cloud resources
+func (k *K8SBackend) Release(ctx context.Context, labels map[string]string) error {
+	for _, name := range []string{k.legacySecretName(), k.secretName()} {
+		var kubernetesBackendSecret v1.Secret
+		if err := k.Client.Get(ctx, client.ObjectKey{Name: name, Namespace: k.SecretNS}, &kubernetesBackendSecret); err != nil {
+			continue
+		}
+		klog.InfoS("Labeling the secret which stores Kubernetes backend for adoption", "Name", name)
+		if kubernetesBackendSecret.Labels == nil {
+			kubernetesBackendSecret.Labels = map[string]string{}
+		}
+		for key, value := range labels {
+			kubernetesBackendSecret.Labels[key] = value
+		}
+		if err := k.Client.Update(ctx, &kubernetesBackendSecret); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // HCL returns the backend hcl code string
 func (k *K8SBackend) HCL() string {
 	if k.LegacySecretSuffix != "" {
@@ -222,6 +222,29 @@ func TestK8SBackend_CleanUp(t *testing.T) {
 	}
 }
 
+func TestK8SBackend_Release(t *testing.T) {
+	ctx := context.Background()
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tfstate-default-a",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "a"},
+		},
+		Type: v1.SecretTypeOpaque,
+	}
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	k := &K8SBackend{
+		Client:       k8sClient,
+		SecretSuffix: "a",
+		SecretNS:     "default",
+	}
+	assert.NilError(t, k.Release(ctx, map[string]string{"orphaned-by": "a"}))
+
+	var got v1.Secret
+	assert.NilError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), &got))
+	assert.DeepEqual(t, map[string]string{"app": "a", "orphaned-by": "a"}, got.Labels)
+}
+
 func TestMigrateLegacySecret(t *testing.T) {
 	secretNS := "default"
 	secret := v1.Secret{
@@ -148,6 +148,11 @@ func (s *S3Backend) CleanUp(_ context.Context) error {
 	return err
 }
 
+// Release does nothing for the s3 backend, the object which contains the Terraform state is kept where it is
+func (s *S3Backend) Release(_ context.Context, _ map[string]string) error {
+	return nil
+}
+
 // HCL returns the backend hcl code string
 func (s S3Backend) HCL() string {
 	fmtStr := `
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// IsFailedState checks whether the Configuration stops at a state which needs users to fix
+func IsFailedState(state types.ConfigurationState) bool {
+	switch state {
+	case types.ConfigurationStaticCheckFailed, types.ConfigurationApplyFailed, types.ConfigurationDestroyFailed,
+		types.InvalidRegion, types.TerraformInitError, types.ProviderNotFound, types.InvalidGitCredentialsSecretReference,
+		types.InvalidTerraformCredentialsSecretReference, types.InvalidTerraformRCConfigMapReference,
+		types.InvalidTerraformCredentialsHelperConfigMapReference, types.InvalidVariableFromReference,
+		types.AuthenticationFailed, types.QuotaExceeded, types.ResourceConflict, types.OperationTimeout, types.TimedOut:
+		return true
+	}
+	return false
+}
+
+// SetSuspendedCondition updates the Suspended condition of the Configuration, which is only added once the
+// Configuration is suspended. It returns whether the condition is changed.
+func SetSuspendedCondition(configuration *v1beta2.Configuration, reason, message string) bool {
+	status := metav1.ConditionTrue
+	if reason == types.ReasonResumed {
+		if apimeta.FindStatusCondition(configuration.Status.Conditions, types.ConditionSuspended) == nil {
+			return false
+		}
+		status = metav1.ConditionFalse
+	}
+	return apimeta.SetStatusCondition(&configuration.Status.Conditions, metav1.Condition{
+		Type:               types.ConditionSuspended,
+		Status:             status,
+		ObservedGeneration: configuration.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// SetConditions updates the conditions of the Configuration according to the apply or destroy state
+func SetConditions(configuration *v1beta2.Configuration, state types.ConfigurationState, message string) {
+	if state == "" {
+		return
+	}
+	set := func(conditionType string, status metav1.ConditionStatus) {
+		apimeta.SetStatusCondition(&configuration.Status.Conditions, metav1.Condition{
+			Type:               conditionType,
+			Status:             status,
+			ObservedGeneration: configuration.Generation,
+			Reason:             string(state),
+			Message:            message,
+		})
+	}
+
+	switch {
+	case state == types.Available:
+		set(types.ConditionReady, metav1.ConditionTrue)
+		set(types.ConditionReconciling, metav1.ConditionFalse)
+		set(types.ConditionStalled, metav1.ConditionFalse)
+	case IsFailedState(state):
+		set(types.ConditionReady, metav1.ConditionFalse)
+		set(types.ConditionReconciling, metav1.ConditionFalse)
+		set(types.ConditionStalled, metav1.ConditionTrue)
+	default:
+		set(types.ConditionReady, metav1.ConditionFalse)
+		set(types.ConditionReconciling, metav1.ConditionTrue)
+		set(types.ConditionStalled, metav1.ConditionFalse)
+	}
+
+	switch state {
+	case types.ProviderNotFound, types.ProviderNotReady, types.InvalidRegion, types.AuthenticationFailed:
+		set(types.ConditionProviderReady, metav1.ConditionFalse)
+	case types.ConfigurationStaticCheckFailed, types.TerraformInitError, types.InvalidGitCredentialsSecretReference,
+		types.InvalidTerraformCredentialsSecretReference, types.InvalidTerraformRCConfigMapReference,
+		types.InvalidTerraformCredentialsHelperConfigMapReference:
+		set(types.ConditionSourceReady, metav1.ConditionFalse)
+	case types.ConfigurationProvisioningAndChecking, types.Available:
+		set(types.ConditionProviderReady, metav1.ConditionTrue)
+		set(types.ConditionSourceReady, metav1.ConditionTrue)
+	}
+
+	switch state {
+	case types.ConfigurationReloading:
+		set(types.ConditionDrifted, metav1.ConditionTrue)
+	case types.Available:
+		apimeta.SetStatusCondition(&configuration.Status.Conditions, metav1.Condition{
+			Type:               types.ConditionDrifted,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: configuration.Generation,
+			Reason:             types.ReasonUpToDate,
+			Message:            message,
+		})
+	}
+}
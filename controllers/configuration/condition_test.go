@@ -0,0 +1,117 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestSetConditions(t *testing.T) {
+	testcases := map[string]struct {
+		state types.ConfigurationState
+		want  map[string]metav1.ConditionStatus
+	}{
+		"available": {
+			state: types.Available,
+			want: map[string]metav1.ConditionStatus{
+				types.ConditionReady:         metav1.ConditionTrue,
+				types.ConditionReconciling:   metav1.ConditionFalse,
+				types.ConditionStalled:       metav1.ConditionFalse,
+				types.ConditionProviderReady: metav1.ConditionTrue,
+				types.ConditionSourceReady:   metav1.ConditionTrue,
+				types.ConditionDrifted:       metav1.ConditionFalse,
+			},
+		},
+		"provisioning": {
+			state: types.ConfigurationProvisioningAndChecking,
+			want: map[string]metav1.ConditionStatus{
+				types.ConditionReady:         metav1.ConditionFalse,
+				types.ConditionReconciling:   metav1.ConditionTrue,
+				types.ConditionStalled:       metav1.ConditionFalse,
+				types.ConditionProviderReady: metav1.ConditionTrue,
+				types.ConditionSourceReady:   metav1.ConditionTrue,
+			},
+		},
+		"reloading": {
+			state: types.ConfigurationReloading,
+			want: map[string]metav1.ConditionStatus{
+				types.ConditionReady:       metav1.ConditionFalse,
+				types.ConditionReconciling: metav1.ConditionTrue,
+				types.ConditionStalled:     metav1.ConditionFalse,
+				types.ConditionDrifted:     metav1.ConditionTrue,
+			},
+		},
+		"provider not found": {
+			state: types.ProviderNotFound,
+			want: map[string]metav1.ConditionStatus{
+				types.ConditionReady:         metav1.ConditionFalse,
+				types.ConditionReconciling:   metav1.ConditionFalse,
+				types.ConditionStalled:       metav1.ConditionTrue,
+				types.ConditionProviderReady: metav1.ConditionFalse,
+			},
+		},
+		"invalid spec": {
+			state: types.ConfigurationStaticCheckFailed,
+			want: map[string]metav1.ConditionStatus{
+				types.ConditionReady:       metav1.ConditionFalse,
+				types.ConditionReconciling: metav1.ConditionFalse,
+				types.ConditionStalled:     metav1.ConditionTrue,
+				types.ConditionSourceReady: metav1.ConditionFalse,
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Generation: 3}}
+			SetConditions(configuration, tc.state, "message")
+			got := make(map[string]metav1.ConditionStatus)
+			for _, c := range configuration.Status.Conditions {
+				got[c.Type] = c.Status
+				assert.Equal(t, int64(3), c.ObservedGeneration)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	// The conditions are kept when the state changes
+	configuration := &v1beta2.Configuration{}
+	SetConditions(configuration, types.Available, "ready")
+	SetConditions(configuration, types.ConfigurationReloading, "reloading")
+	assert.Equal(t, metav1.ConditionTrue, apimeta.FindStatusCondition(configuration.Status.Conditions, types.ConditionSourceReady).Status)
+	assert.Equal(t, string(types.ConfigurationReloading), apimeta.FindStatusCondition(configuration.Status.Conditions, types.ConditionReady).Reason)
+}
+
+func TestSetSuspendedCondition(t *testing.T) {
+	configuration := &v1beta2.Configuration{}
+	assert.False(t, SetSuspendedCondition(configuration, types.ReasonResumed, types.MessageResumed))
+	assert.Empty(t, configuration.Status.Conditions)
+
+	assert.True(t, SetSuspendedCondition(configuration, types.ReasonSuspended, types.MessageSuspended))
+	assert.False(t, SetSuspendedCondition(configuration, types.ReasonSuspended, types.MessageSuspended))
+	assert.Equal(t, metav1.ConditionTrue, apimeta.FindStatusCondition(configuration.Status.Conditions, types.ConditionSuspended).Status)
+
+	assert.True(t, SetSuspendedCondition(configuration, types.ReasonResumed, types.MessageResumed))
+	assert.Equal(t, metav1.ConditionFalse, apimeta.FindStatusCondition(configuration.Status.Conditions, types.ConditionSuspended).Status)
+}
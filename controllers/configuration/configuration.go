@@ -3,6 +3,7 @@ package configuration
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -32,10 +33,25 @@ const (
 	GiteePrefix = "https://gitee.com/"
 )
 
+// importAddressRegexp matches the address of a managed resource instance, which is rendered into the import blocks
+// as is. It's the same as the pattern of TerraformRunImport.Address in the CRD.
+var importAddressRegexp = regexp.MustCompile(`^(module\.[A-Za-z_][A-Za-z0-9_-]*(\[([0-9]+|"[^"\\$%\r\n]*")\])?\.)*[A-Za-z_][A-Za-z0-9_-]*\.[A-Za-z_][A-Za-z0-9_-]*(\[([0-9]+|"[^"\\$%\r\n]*")\])?$`)
+
 const errGitHubBlockedNotBoolean = "the value of githubBlocked is not a boolean"
 
 // ValidConfigurationObject will validate a Configuration
 func ValidConfigurationObject(configuration *v1beta2.Configuration) (types.ConfigurationType, error) {
+	if configuration.Spec.DeletionPolicy == v1beta2.DeletionPolicyExport && configuration.Spec.ExportStateTo == nil {
+		return "", errors.New("spec.exportStateTo should be set for the deletion policy Export")
+	}
+	if err := ValidateConnectionSecret(configuration.Spec.ConnectionSecret); err != nil {
+		return "", err
+	}
+	for _, i := range configuration.Spec.Imports {
+		if !importAddressRegexp.MatchString(i.Address) {
+			return "", fmt.Errorf("spec.imports: %q is not a valid resource address", i.Address)
+		}
+	}
 	hcl := configuration.Spec.HCL
 	remote := configuration.Spec.Remote
 	switch {
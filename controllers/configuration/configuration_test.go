@@ -84,6 +84,52 @@ func TestValidConfigurationObject(t *testing.T) {
 				errMsg:            "spec.HCL or spec.Remote should be set",
 			},
 		},
+		{
+			name: "export state without exportStateTo",
+			args: args{
+				configuration: &v1beta2.Configuration{
+					Spec: v1beta2.ConfigurationSpec{
+						HCL:            "abc",
+						DeletionPolicy: v1beta2.DeletionPolicyExport,
+					},
+				},
+			},
+			want: want{
+				configurationType: "",
+				errMsg:            "spec.exportStateTo should be set for the deletion policy Export",
+			},
+		},
+		{
+			name: "valid import addresses",
+			args: args{
+				configuration: &v1beta2.Configuration{
+					Spec: v1beta2.ConfigurationSpec{
+						HCL: "abc",
+						Imports: []v1beta2.TerraformRunImport{
+							{Address: "alicloud_oss_bucket.bucket", ID: "a"},
+							{Address: `module.oss["logs"].alicloud_oss_bucket.bucket[0]`, ID: "b"},
+						},
+					},
+				},
+			},
+			want: want{
+				configurationType: types.ConfigurationHCL,
+			},
+		},
+		{
+			name: "invalid import address",
+			args: args{
+				configuration: &v1beta2.Configuration{
+					Spec: v1beta2.ConfigurationSpec{
+						HCL:     "abc",
+						Imports: []v1beta2.TerraformRunImport{{Address: "a.b\n}\nresource \"x\" \"y\" {", ID: "a"}},
+					},
+				},
+			},
+			want: want{
+				errMsg: "is not a valid resource address",
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// RenderConnectionSecretData renders the data of the connection Secret from the outputs. All the outputs are written
+// with their names as keys if connectionSecret is nil.
+func RenderConnectionSecretData(outputs map[string]v1beta2.Property, connectionSecret *v1beta2.ConnectionSecret) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	if connectionSecret == nil || len(connectionSecret.Outputs) == 0 {
+		for k, v := range outputs {
+			data[k] = []byte(v.Value)
+		}
+	} else {
+		for _, o := range connectionSecret.Outputs {
+			v, ok := outputs[o.Name]
+			if !ok {
+				return nil, errors.Errorf("output %q is not found", o.Name)
+			}
+			key := o.Key
+			if key == "" {
+				key = o.Name
+			}
+			if _, ok := data[key]; ok {
+				return nil, errors.Errorf("key %q is written by more than one output", key)
+			}
+			data[key] = []byte(v.Value)
+		}
+	}
+	if connectionSecret == nil {
+		return data, nil
+	}
+
+	values := make(map[string]string, len(outputs))
+	for k, v := range outputs {
+		values[k] = v.Value
+	}
+	for _, key := range sortedKeys(connectionSecret.Templates) {
+		if _, ok := data[key]; ok {
+			return nil, errors.Errorf("the template of key %q collides with the output written to the same key", key)
+		}
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(connectionSecret.Templates[key])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse the template of key %q", key)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, values); err != nil {
+			return nil, errors.Wrapf(err, "failed to render the template of key %q", key)
+		}
+		data[key] = buf.Bytes()
+	}
+	return data, nil
+}
+
+// ValidateConnectionSecret rejects the keys of spec.connectionSecret which collide with each other. The templates
+// colliding with the outputs when all outputs are written are only found once the outputs are known.
+func ValidateConnectionSecret(connectionSecret *v1beta2.ConnectionSecret) error {
+	if connectionSecret == nil {
+		return nil
+	}
+	keys := make(map[string]bool, len(connectionSecret.Outputs))
+	for _, o := range connectionSecret.Outputs {
+		key := o.Key
+		if key == "" {
+			key = o.Name
+		}
+		if keys[key] {
+			return errors.Errorf("spec.connectionSecret: key %q is written by more than one output", key)
+		}
+		keys[key] = true
+	}
+	for key := range connectionSecret.Templates {
+		if keys[key] {
+			return errors.Errorf("spec.connectionSecret: the template of key %q collides with the output written to the same key", key)
+		}
+	}
+	return nil
+}
+
+// ConnectionSecretKey finds the key of the output in the connection Secret, which is renamed or filtered out by
+// connectionSecret.outputs. It returns false if the output isn't written to the Secret.
+func ConnectionSecretKey(name string, connectionSecret *v1beta2.ConnectionSecret) (string, bool) {
+	if connectionSecret == nil || len(connectionSecret.Outputs) == 0 {
+		return name, true
+	}
+	for _, o := range connectionSecret.Outputs {
+		if o.Name != name {
+			continue
+		}
+		if o.Key == "" {
+			return o.Name, true
+		}
+		return o.Key, true
+	}
+	return "", false
+}
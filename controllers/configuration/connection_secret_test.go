@@ -0,0 +1,135 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestRenderConnectionSecretData(t *testing.T) {
+	outputs := map[string]v1beta2.Property{
+		"username": {Value: "admin"},
+		"password": {Value: "p@ss", Sensitive: true},
+		"host":     {Value: "db.local"},
+	}
+	testcases := map[string]struct {
+		connectionSecret *v1beta2.ConnectionSecret
+		want             map[string][]byte
+		errMsg           string
+	}{
+		"all outputs by default": {
+			want: map[string][]byte{"username": []byte("admin"), "password": []byte("p@ss"), "host": []byte("db.local")},
+		},
+		"selected and renamed outputs": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Outputs: []v1beta2.ConnectionSecretOutput{{Name: "host"}, {Name: "password", Key: "DB_PASSWORD"}},
+			},
+			want: map[string][]byte{"host": []byte("db.local"), "DB_PASSWORD": []byte("p@ss")},
+		},
+		"templates": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Outputs:   []v1beta2.ConnectionSecretOutput{{Name: "host"}},
+				Templates: map[string]string{"dsn": "postgres://{{.username}}:{{.password}}@{{.host}}"},
+			},
+			want: map[string][]byte{"host": []byte("db.local"), "dsn": []byte("postgres://admin:p@ss@db.local")},
+		},
+		"output not found": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Outputs: []v1beta2.ConnectionSecretOutput{{Name: "port"}},
+			},
+			errMsg: `output "port" is not found`,
+		},
+		"template references an unknown output": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Templates: map[string]string{"dsn": "{{.host}}:{{.port}}"},
+			},
+			errMsg: `failed to render the template of key "dsn"`,
+		},
+		"template collides with a selected output": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Outputs:   []v1beta2.ConnectionSecretOutput{{Name: "password", Key: "dsn"}},
+				Templates: map[string]string{"dsn": "{{.host}}"},
+			},
+			errMsg: `the template of key "dsn" collides with the output written to the same key`,
+		},
+		"template collides with an output written by default": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Templates: map[string]string{"host": "{{.host}}:5432"},
+			},
+			errMsg: `the template of key "host" collides with the output written to the same key`,
+		},
+		"outputs written to the same key": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Outputs: []v1beta2.ConnectionSecretOutput{{Name: "host"}, {Name: "username", Key: "host"}},
+			},
+			errMsg: `key "host" is written by more than one output`,
+		},
+		"invalid template": {
+			connectionSecret: &v1beta2.ConnectionSecret{
+				Templates: map[string]string{"dsn": "{{.host"},
+			},
+			errMsg: `failed to parse the template of key "dsn"`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got, err := RenderConnectionSecretData(outputs, tc.connectionSecret)
+			if tc.errMsg != "" {
+				assert.ErrorContains(t, err, tc.errMsg)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateConnectionSecret(t *testing.T) {
+	assert.Nil(t, ValidateConnectionSecret(nil))
+	assert.Nil(t, ValidateConnectionSecret(&v1beta2.ConnectionSecret{
+		Outputs:   []v1beta2.ConnectionSecretOutput{{Name: "host"}, {Name: "password", Key: "DB_PASSWORD"}},
+		Templates: map[string]string{"password": "{{.password}}"},
+	}))
+	assert.EqualError(t, ValidateConnectionSecret(&v1beta2.ConnectionSecret{
+		Outputs:   []v1beta2.ConnectionSecretOutput{{Name: "password", Key: "DB_PASSWORD"}},
+		Templates: map[string]string{"DB_PASSWORD": "{{.password}}"},
+	}), `spec.connectionSecret: the template of key "DB_PASSWORD" collides with the output written to the same key`)
+	assert.EqualError(t, ValidateConnectionSecret(&v1beta2.ConnectionSecret{
+		Outputs: []v1beta2.ConnectionSecretOutput{{Name: "host", Key: "HOST"}, {Name: "endpoint", Key: "HOST"}},
+	}), `spec.connectionSecret: key "HOST" is written by more than one output`)
+}
+
+func TestConnectionSecretKey(t *testing.T) {
+	key, ok := ConnectionSecretKey("password", nil)
+	assert.True(t, ok)
+	assert.Equal(t, "password", key)
+
+	connectionSecret := &v1beta2.ConnectionSecret{Outputs: []v1beta2.ConnectionSecretOutput{{Name: "password", Key: "PASSWORD"}, {Name: "host"}}}
+	key, ok = ConnectionSecretKey("password", connectionSecret)
+	assert.True(t, ok)
+	assert.Equal(t, "PASSWORD", key)
+	key, ok = ConnectionSecretKey("host", connectionSecret)
+	assert.True(t, ok)
+	assert.Equal(t, "host", key)
+	_, ok = ConnectionSecretKey("username", connectionSecret)
+	assert.False(t, ok)
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// GetOutputReferences returns the variables in spec.variable whose values reference an output of another Configuration,
+// keyed by the variable name
+func GetOutputReferences(configuration *v1beta2.Configuration) (map[string]v1beta2.ConfigurationOutputReference, error) {
+	variables, err := RawExtension2Map(configuration.Spec.Variable)
+	if err != nil {
+		return nil, err
+	}
+	references := make(map[string]v1beta2.ConfigurationOutputReference)
+	for k, v := range variables {
+		ref, err := parseOutputReference(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "variable %q is not a valid output reference", k)
+		}
+		if ref == nil {
+			continue
+		}
+		if ref.Namespace == "" {
+			ref.Namespace = configuration.Namespace
+		}
+		references[k] = *ref
+	}
+	return references, nil
+}
+
+// parseOutputReference returns nil if the value is not like `{"valueFrom": {"configurationOutput": {...}}}`
+func parseOutputReference(value interface{}) (*v1beta2.ConfigurationOutputReference, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 1 {
+		return nil, nil
+	}
+	valueFrom, ok := m["valueFrom"].(map[string]interface{})
+	if !ok || len(valueFrom) != 1 {
+		return nil, nil
+	}
+	output, ok := valueFrom["configurationOutput"]
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, err
+	}
+	var ref v1beta2.ConfigurationOutputReference
+	if err := json.Unmarshal(data, &ref); err != nil {
+		return nil, err
+	}
+	if ref.Name == "" || ref.Key == "" {
+		return nil, errors.New("name and key of configurationOutput are required")
+	}
+	return &ref, nil
+}
+
+// GetDependencies returns the Configurations which the Configuration depends on, including the ones in spec.dependsOn
+// and the ones whose outputs are referenced. The namespaces of them are always set.
+func GetDependencies(configuration *v1beta2.Configuration) ([]v1beta2.ConfigurationReference, error) {
+	var dependencies []v1beta2.ConfigurationReference
+	seen := make(map[v1beta2.ConfigurationReference]bool)
+	add := func(ref v1beta2.ConfigurationReference) {
+		if ref.Namespace == "" {
+			ref.Namespace = configuration.Namespace
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			dependencies = append(dependencies, ref)
+		}
+	}
+
+	for _, ref := range configuration.Spec.DependsOn {
+		add(ref)
+	}
+	references, err := GetOutputReferences(configuration)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range references {
+		add(ref.ConfigurationReference)
+	}
+
+	sort.Slice(dependencies, func(i, j int) bool {
+		return DependencyKey(dependencies[i]) < DependencyKey(dependencies[j])
+	})
+	for _, dep := range dependencies {
+		if dep.Name == configuration.Name && dep.Namespace == configuration.Namespace {
+			return nil, errors.New("Configuration could not depend on itself")
+		}
+	}
+	return dependencies, nil
+}
+
+// DependencyKey is the `namespace/name` of the referenced Configuration
+func DependencyKey(ref v1beta2.ConfigurationReference) string {
+	return fmt.Sprintf("%s/%s", ref.Namespace, ref.Name)
+}
+
+// FindDependencyCycle walks the dependencies of the Configuration and returns the path of a circular dependency which
+// leads back to the Configuration, like `default/a -> default/b -> default/a`, or "" if there is none. The Configurations
+// which are not found or whose dependencies are not valid are skipped, as they block nothing but themselves.
+func FindDependencyCycle(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration) (string, error) {
+	start := DependencyKey(v1beta2.ConfigurationReference{Name: configuration.Name, Namespace: configuration.Namespace})
+	visited := map[string]bool{start: true}
+
+	var walk func(c *v1beta2.Configuration, path []string) ([]string, error)
+	walk = func(c *v1beta2.Configuration, path []string) ([]string, error) {
+		dependencies, err := GetDependencies(c)
+		if err != nil {
+			return nil, nil
+		}
+		for _, dep := range dependencies {
+			key := DependencyKey(dep)
+			if key == start {
+				return append(path, key), nil
+			}
+			if visited[key] {
+				continue
+			}
+			visited[key] = true
+			var next v1beta2.Configuration
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: dep.Name, Namespace: dep.Namespace}, &next); err != nil {
+				if kerrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			cycle, err := walk(&next, append(path, key))
+			if err != nil || cycle != nil {
+				return cycle, err
+			}
+		}
+		return nil, nil
+	}
+
+	cycle, err := walk(configuration, []string{start})
+	if err != nil || cycle == nil {
+		return "", err
+	}
+	return strings.Join(cycle, " -> "), nil
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestGetDependencies(t *testing.T) {
+	testcases := map[string]struct {
+		dependsOn []v1beta2.ConfigurationReference
+		variable  string
+		want      []v1beta2.ConfigurationReference
+		errMsg    string
+	}{
+		"no dependencies": {
+			variable: `{"name":"abc"}`,
+		},
+		"dependsOn and output references are merged": {
+			dependsOn: []v1beta2.ConfigurationReference{{Name: "vpc"}, {Name: "db", Namespace: "other"}},
+			variable:  `{"name":"abc","vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"id"}}}}`,
+			want: []v1beta2.ConfigurationReference{
+				{Name: "vpc", Namespace: "default"},
+				{Name: "db", Namespace: "other"},
+			},
+		},
+		"a map variable which is not an output reference": {
+			variable: `{"tags":{"valueFrom":"abc"}}`,
+		},
+		"invalid output reference": {
+			variable: `{"vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc"}}}}`,
+			errMsg:   `variable "vpc_id" is not a valid output reference: name and key of configurationOutput are required`,
+		},
+		"depend on itself": {
+			dependsOn: []v1beta2.ConfigurationReference{{Name: "abc"}},
+			errMsg:    "Configuration could not depend on itself",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Name: "abc", Namespace: "default"},
+				Spec:       v1beta2.ConfigurationSpec{DependsOn: tc.dependsOn},
+			}
+			if tc.variable != "" {
+				configuration.Spec.Variable = &runtime.RawExtension{Raw: []byte(tc.variable)}
+			}
+			got, err := GetDependencies(configuration)
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestGetOutputReferences(t *testing.T) {
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "abc", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			Variable: &runtime.RawExtension{
+				Raw: []byte(`{"name":"abc","vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","namespace":"network","key":"id"}}},"db":{"valueFrom":{"configurationOutput":{"name":"db","key":"host"}}}}`),
+			},
+		},
+	}
+	got, err := GetOutputReferences(configuration)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]v1beta2.ConfigurationOutputReference{
+		"vpc_id": {ConfigurationReference: v1beta2.ConfigurationReference{Name: "vpc", Namespace: "network"}, Key: "id"},
+		"db":     {ConfigurationReference: v1beta2.ConfigurationReference{Name: "db", Namespace: "default"}, Key: "host"},
+	}, got)
+}
+
+func TestFindDependencyCycle(t *testing.T) {
+	newConfiguration := func(name string, dependsOn ...string) *v1beta2.Configuration {
+		c := &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+		for _, dep := range dependsOn {
+			c.Spec.DependsOn = append(c.Spec.DependsOn, v1beta2.ConfigurationReference{Name: dep})
+		}
+		return c
+	}
+	testcases := map[string]struct {
+		objects []client.Object
+		want    string
+	}{
+		"no cycle": {
+			objects: []client.Object{newConfiguration("b", "c"), newConfiguration("c")},
+		},
+		"dependency not found": {
+			objects: []client.Object{newConfiguration("b", "not-exist")},
+		},
+		"direct cycle": {
+			objects: []client.Object{newConfiguration("b", "a")},
+			want:    "default/a -> default/b -> default/a",
+		},
+		"indirect cycle": {
+			objects: []client.Object{newConfiguration("b", "c"), newConfiguration("c", "d", "a"), newConfiguration("d")},
+			want:    "default/a -> default/b -> default/c -> default/a",
+		},
+		"cycle not leading back": {
+			objects: []client.Object{newConfiguration("b", "c"), newConfiguration("c", "b")},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	assert.Nil(t, v1beta2.AddToScheme(scheme))
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.objects...).Build()
+			got, err := FindDependencyCycle(context.Background(), k8sClient, newConfiguration("a", "b"))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
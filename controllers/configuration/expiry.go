@@ -0,0 +1,72 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// ExpiryWarningPeriods are how long before a Configuration expires the warning Events are emitted, the longest first
+var ExpiryWarningPeriods = []time.Duration{24 * time.Hour, time.Hour}
+
+// GetExpireAt returns when the Configuration expires by spec.ttl or spec.expireAt, whichever is earlier, extended by
+// the annotation types.AnnotationExtendTTL. It's nil if the Configuration never expires.
+func GetExpireAt(configuration *v1beta2.Configuration) (*metav1.Time, error) {
+	var expireAt *metav1.Time
+	if configuration.Spec.TTL != nil {
+		t := metav1.NewTime(configuration.CreationTimestamp.Add(configuration.Spec.TTL.Duration))
+		expireAt = &t
+	}
+	if configuration.Spec.ExpireAt != nil && (expireAt == nil || configuration.Spec.ExpireAt.Before(expireAt)) {
+		expireAt = configuration.Spec.ExpireAt.DeepCopy()
+	}
+	if expireAt == nil {
+		return nil, nil
+	}
+	if extension, ok := configuration.Annotations[types.AnnotationExtendTTL]; ok {
+		d, err := time.ParseDuration(extension)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid annotation %s %q, it should be a duration like 24h", types.AnnotationExtendTTL, extension)
+		}
+		expireAt.Time = expireAt.Add(d)
+	}
+	// The time in the status is stored in seconds
+	expireAt.Time = expireAt.Truncate(time.Second)
+	return expireAt, nil
+}
+
+// DueExpiryWarning returns the warning period which the Configuration expiring at the time has entered, and hasn't
+// been warned about since the latest warning. It's zero if no warning is due.
+func DueExpiryWarning(expireAt time.Time, lastWarning *metav1.Time, now time.Time) time.Duration {
+	remaining := expireAt.Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+	for i := len(ExpiryWarningPeriods) - 1; i >= 0; i-- {
+		period := ExpiryWarningPeriods[i]
+		if remaining <= period && (lastWarning == nil || lastWarning.Time.Before(expireAt.Add(-period))) {
+			return period
+		}
+	}
+	return 0
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestGetExpireAt(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ttl := &metav1.Duration{Duration: 48 * time.Hour}
+	testcases := map[string]struct {
+		spec        v1beta2.ConfigurationSpec
+		annotations map[string]string
+		want        *metav1.Time
+		wantErr     bool
+	}{
+		"never expires": {},
+		"ttl": {
+			spec: v1beta2.ConfigurationSpec{TTL: ttl},
+			want: &metav1.Time{Time: created.Add(48 * time.Hour)},
+		},
+		"expireAt is earlier than ttl": {
+			spec: v1beta2.ConfigurationSpec{TTL: ttl, ExpireAt: &metav1.Time{Time: created.Add(time.Hour)}},
+			want: &metav1.Time{Time: created.Add(time.Hour)},
+		},
+		"extended": {
+			spec:        v1beta2.ConfigurationSpec{TTL: ttl},
+			annotations: map[string]string{types.AnnotationExtendTTL: "24h"},
+			want:        &metav1.Time{Time: created.Add(72 * time.Hour)},
+		},
+		"invalid extension": {
+			spec:        v1beta2.ConfigurationSpec{TTL: ttl},
+			annotations: map[string]string{types.AnnotationExtendTTL: "a day"},
+			wantErr:     true,
+		},
+		"extension without ttl": {
+			annotations: map[string]string{types.AnnotationExtendTTL: "24h"},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			configuration := &v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Time{Time: created}, Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			got, err := GetExpireAt(configuration)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.True(t, tc.want.Equal(got), "got %v", got)
+		})
+	}
+}
+
+func TestDueExpiryWarning(t *testing.T) {
+	expireAt := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	testcases := map[string]struct {
+		now         time.Time
+		lastWarning *metav1.Time
+		want        time.Duration
+	}{
+		"not yet": {
+			now: expireAt.Add(-25 * time.Hour),
+		},
+		"a day before": {
+			now:  expireAt.Add(-23 * time.Hour),
+			want: 24 * time.Hour,
+		},
+		"warned a day before": {
+			now:         expireAt.Add(-2 * time.Hour),
+			lastWarning: &metav1.Time{Time: expireAt.Add(-23 * time.Hour)},
+		},
+		"an hour before": {
+			now:         expireAt.Add(-30 * time.Minute),
+			lastWarning: &metav1.Time{Time: expireAt.Add(-23 * time.Hour)},
+			want:        time.Hour,
+		},
+		"warned an hour before": {
+			now:         expireAt.Add(-10 * time.Minute),
+			lastWarning: &metav1.Time{Time: expireAt.Add(-30 * time.Minute)},
+		},
+		"expired": {
+			now: expireAt.Add(time.Minute),
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, DueExpiryWarning(expireAt, tc.lastWarning, tc.now))
+		})
+	}
+}
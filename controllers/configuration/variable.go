@@ -0,0 +1,141 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/pkg/errors"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// DeclaredVariable is a `variable` block declared in a Terraform module
+type DeclaredVariable struct {
+	Name     string
+	Required bool
+	// Type is the type constraint of the variable, it's cty.DynamicPseudoType if the type is not declared or can't be parsed
+	Type cty.Type
+}
+
+// GetDeclaredVariables parses the `variable` blocks in the hcl code. It returns an error if the hcl code can't be parsed.
+func GetDeclaredVariables(hclCode string) (map[string]DeclaredVariable, error) {
+	hclFile, diags := hclparse.NewParser().ParseHCL([]byte(hclCode), "main.tf")
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("there are syntax errors in the hcl code: %w", diags)
+	}
+	content, _, diags := hclFile.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "variable", LabelNames: []string{"name"}}},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to get the variable blocks: %w", diags)
+	}
+
+	variables := make(map[string]DeclaredVariable, len(content.Blocks))
+	for _, block := range content.Blocks {
+		attrs, _ := block.Body.JustAttributes()
+		v := DeclaredVariable{Name: block.Labels[0], Type: cty.DynamicPseudoType}
+		_, hasDefault := attrs["default"]
+		v.Required = !hasDefault
+		if typeAttr, ok := attrs["type"]; ok {
+			// Type constraints which are not supported by the parser, like `optional()`, are not checked
+			if t, diags := typeexpr.TypeConstraint(typeAttr.Expr); !diags.HasErrors() {
+				v.Type = t
+			}
+		}
+		variables[v.Name] = v
+	}
+	return variables, nil
+}
+
+// ValidateVariables checks the variables against the `variable` blocks declared in the hcl code. It reports missing
+// required variables and values which can't be converted to the declared type as an error, and returns the names of
+// the undeclared variables, which are ignored by Terraform. Variables in `setVariables` are only known to be set, like
+// the ones from TF_VAR_ environments, their values are not checked.
+func ValidateVariables(hclCode string, variables map[string]interface{}, setVariables []string) ([]string, error) {
+	declared, err := GetDeclaredVariables(hclCode)
+	if err != nil {
+		// Leave the syntax errors to Terraform, which reports them with more context
+		return nil, nil
+	}
+
+	var problems, undeclared []string
+	for _, name := range sortedKeys(variables) {
+		v, ok := declared[name]
+		if !ok {
+			undeclared = append(undeclared, name)
+			continue
+		}
+		if err := checkVariableType(variables[name], v.Type); err != nil {
+			problems = append(problems, fmt.Sprintf("variable %q is not valid: %s", name, err.Error()))
+		}
+	}
+
+	set := make(map[string]bool, len(variables)+len(setVariables))
+	for name := range variables {
+		set[name] = true
+	}
+	for _, name := range setVariables {
+		set[name] = true
+	}
+	for _, name := range sortedKeys(declared) {
+		if declared[name].Required && !set[name] {
+			problems = append(problems, fmt.Sprintf("required variable %q is not set", name))
+		}
+	}
+
+	if len(problems) != 0 {
+		return undeclared, errors.New(strings.Join(problems, "; "))
+	}
+	return undeclared, nil
+}
+
+func checkVariableType(value interface{}, want cty.Type) error {
+	if want == cty.DynamicPseudoType {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	impliedType, err := ctyjson.ImpliedType(data)
+	if err != nil {
+		return err
+	}
+	v, err := ctyjson.Unmarshal(data, impliedType)
+	if err != nil {
+		return err
+	}
+	_, err = convert.Convert(v, want)
+	return err
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
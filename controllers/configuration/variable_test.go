@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const moduleHCL = `
+variable "name" {
+  type = string
+}
+
+variable "bandwidth" {
+  type    = number
+  default = 1
+
+  validation {
+    condition     = var.bandwidth > 0
+    error_message = "bandwidth must be positive"
+  }
+}
+
+variable "tags" {
+  type    = map(string)
+  default = {}
+}
+
+variable "subnets" {
+  type = list(object({
+    cidr = string
+    zone = string
+  }))
+  default = []
+}
+
+variable "settings" {
+  type    = object({ size = optional(number) })
+  default = null
+}
+
+variable "anything" {
+  default = null
+}
+
+resource "random_id" "server" {
+  byte_length = var.bandwidth
+}
+`
+
+func TestGetDeclaredVariables(t *testing.T) {
+	variables, err := GetDeclaredVariables(moduleHCL)
+	assert.Nil(t, err)
+	assert.Equal(t, DeclaredVariable{Name: "name", Required: true, Type: cty.String}, variables["name"])
+	assert.Equal(t, DeclaredVariable{Name: "bandwidth", Type: cty.Number}, variables["bandwidth"])
+	assert.Equal(t, DeclaredVariable{Name: "settings", Type: cty.DynamicPseudoType}, variables["settings"])
+	assert.Equal(t, DeclaredVariable{Name: "anything", Type: cty.DynamicPseudoType}, variables["anything"])
+	assert.Len(t, variables, 6)
+
+	_, err = GetDeclaredVariables(`variable "a" {`)
+	assert.Contains(t, err.Error(), "there are syntax errors in the hcl code")
+}
+
+func TestValidateVariables(t *testing.T) {
+	testcases := map[string]struct {
+		hcl          string
+		variables    map[string]interface{}
+		setVariables []string
+		undeclared   []string
+		errMsg       string
+	}{
+		"valid variables": {
+			hcl: moduleHCL,
+			variables: map[string]interface{}{
+				"name":      "abc",
+				"bandwidth": "10",
+				"tags":      map[string]interface{}{"env": "prod"},
+				"subnets":   []interface{}{map[string]interface{}{"cidr": "10.0.0.0/24", "zone": "a"}},
+				"settings":  map[string]interface{}{"size": 1},
+				"anything":  []interface{}{1, "a"},
+			},
+		},
+		"required variable is set by environment": {
+			hcl:          moduleHCL,
+			setVariables: []string{"name"},
+		},
+		"undeclared variables are only reported": {
+			hcl:        moduleHCL,
+			variables:  map[string]interface{}{"name": "abc", "unused": "a"},
+			undeclared: []string{"unused"},
+		},
+		"unknown, missing and mistyped variables": {
+			hcl: moduleHCL,
+			variables: map[string]interface{}{
+				"nmae":      "abc",
+				"bandwidth": "high",
+				"subnets":   []interface{}{map[string]interface{}{"cidr": "10.0.0.0/24"}},
+			},
+			undeclared: []string{"nmae"},
+			errMsg: `variable "bandwidth" is not valid: a number is required; ` +
+				`variable "subnets" is not valid: element 0: attribute "zone" is required; ` +
+				`required variable "name" is not set`,
+		},
+		"hcl with syntax errors is left to Terraform": {
+			hcl:       `variable "a" {`,
+			variables: map[string]interface{}{"b": "c"},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			undeclared, err := ValidateVariables(tc.hcl, tc.variables, tc.setVariables)
+			assert.Equal(t, tc.undeclared, undeclared)
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
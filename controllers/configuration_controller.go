@@ -20,12 +20,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"math"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/oam-dev/terraform-controller/controllers/process"
+	"github.com/oam-dev/terraform-controller/controllers/runlog"
 	"github.com/oam-dev/terraform-controller/controllers/util"
 
 	"github.com/go-logr/logr"
@@ -36,16 +37,25 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/util/feature"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/oam-dev/terraform-controller/api/types"
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
 	"github.com/oam-dev/terraform-controller/controllers/features"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
+	"github.com/oam-dev/terraform-controller/controllers/maintenance"
+	"github.com/oam-dev/terraform-controller/controllers/metrics"
 	"github.com/oam-dev/terraform-controller/controllers/provider"
 	"github.com/oam-dev/terraform-controller/controllers/terraform"
 )
@@ -53,6 +63,16 @@ import (
 const (
 	defaultNamespace       = "default"
 	configurationFinalizer = "configuration.finalizers.terraform-controller"
+	variableFromIndexKey   = "spec.variableFrom"
+	dependencyIndexKey     = "spec.dependencies"
+
+	// minJobRequeueInterval and maxJobRequeueInterval bound the interval to check a running Job again
+	minJobRequeueInterval = 10 * time.Second
+	maxJobRequeueInterval = 2 * time.Minute
+
+	// defaultJobBackoffLimit is the number of retries of a Terraform Job, unless it's set by JOB_BACKOFF_LIMIT or the
+	// Configuration. Retrying a module which isn't idempotent could create duplicate cloud resources.
+	defaultJobBackoffLimit = 3
 )
 
 // ConfigurationReconciler reconciles a Configuration object.
@@ -62,10 +82,25 @@ type ConfigurationReconciler struct {
 	ControllerNamespace string
 	ProviderName        string
 	Scheme              *runtime.Scheme
+	// AllowedOutputNamespaces are the namespaces which spec.outputTargets of any Configuration can publish to
+	AllowedOutputNamespaces []string
+	// AllowedDependencyNamespaces are the namespaces which the Configurations depended on by any Configuration can be in
+	AllowedDependencyNamespaces []string
+	Recorder                    record.EventRecorder
+	// MaxConcurrentReconciles is the maximum number of Configurations reconciled at the same time
+	MaxConcurrentReconciles int
+	// JobLimiter queues the Terraform apply Jobs when too many Terraform Jobs are running
+	JobLimiter *limiter.JobLimiter
+	// LogArchiver archives the full logs of the Terraform runs, the logs are not archived if it's nil
+	LogArchiver runlog.Archiver
+	// Paused suspends all the Configurations, no Terraform Job is created or deleted
+	Paused bool
 }
 
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=configurations,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=configurations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=maintenancepolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile will reconcile periodically
 func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -76,7 +111,10 @@ func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	meta := process.New(req, configuration, r.Client, process.ControllerNamespaceOption(r.ControllerNamespace))
+	meta := process.New(req, configuration, r.Client, process.ControllerNamespaceOption(r.ControllerNamespace),
+		process.AllowedOutputNamespacesOption(r.AllowedOutputNamespaces), process.EventRecorderOption(r.Recorder), process.JobLimiterOption(r.JobLimiter),
+		process.AllowedDependencyNamespacesOption(r.AllowedDependencyNamespaces),
+		process.LogArchiverOption(r.LogArchiver))
 
 	// add finalizer
 	var isDeleting = !configuration.ObjectMeta.DeletionTimestamp.IsZero()
@@ -89,35 +127,104 @@ func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
+	reason, message := r.suspension(configuration)
+	meta.Suspended = reason != types.ReasonResumed
+	if err := meta.UpdateSuspendedCondition(ctx, r.Client, reason, message); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// A suspended Configuration isn't deleted when it expires, as the deletion is blocked until it's resumed
+	if !isDeleting && !meta.Suspended {
+		expired, err := r.checkExpiry(ctx, configuration, meta)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if expired {
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if err := r.checkMaintenanceWindows(ctx, &configuration, meta); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// pre-check Configuration
 	if err := r.preCheck(ctx, &configuration, meta); err != nil && !isDeleting {
+		if err.Error() == types.MessageWaitingForDependencies {
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
 		return ctrl.Result{}, err
 	}
 
 	if isDeleting {
+		if configuration.Spec.DeletionProtection {
+			klog.InfoS(types.MessageDeletionBlockedByProtection, "Namespace", req.Namespace, "Name", req.Name)
+			return ctrl.Result{}, meta.UpdateDestroyStatus(ctx, r.Client, types.DeletionBlocked, types.MessageDeletionBlockedByProtection)
+		}
+		if meta.Suspended {
+			msg := fmt.Sprintf("%s: %s", types.MessageDeletionBlockedBySuspension, message)
+			klog.InfoS(msg, "Namespace", req.Namespace, "Name", req.Name)
+			return ctrl.Result{}, meta.UpdateDestroyStatus(ctx, r.Client, types.DeletionBlocked, msg)
+		}
+
+		// Configurations which depend on this one have to be deleted first
+		dependents, err := r.getDependents(ctx, configuration)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if len(dependents) != 0 {
+			msg := fmt.Sprintf("%s: %s", types.MessageDeletionBlockedByDependents, strings.Join(dependents, ", "))
+			klog.InfoS(msg, "Namespace", req.Namespace, "Name", req.Name)
+			if err := meta.UpdateDestroyStatus(ctx, r.Client, types.DeletionBlocked, msg); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+
 		// terraform destroy
 		klog.InfoS("performing Configuration Destroy", "Namespace", req.Namespace, "Name", req.Name, "JobName", meta.DestroyJobName)
 		// if allow to delete halfway, we will not check the status of the apply job.
 
-		_, err := terraform.GetTerraformStatus(ctx, meta.ControllerNamespace, meta.DestroyJobName, types.TerraformContainerName, types.TerraformInitContainerName)
+		_, diagnostics, err := terraform.GetTerraformStatus(ctx, meta.ControllerNamespace, meta.DestroyJobName, types.TerraformContainerName, types.TerraformInitContainerName)
 		if err != nil {
+			meta.Diagnostics = diagnostics
 			klog.ErrorS(err, "Terraform destroy failed")
+			if configuration.Status.Destroy.State != types.ConfigurationDestroyFailed {
+				msg := terraform.TrimErrorMessage(err.Error())
+				meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonDestroyFailed, "%s", msg)
+				var destroyJob batchv1.Job
+				if err := r.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.ControllerNamespace}, &destroyJob); err == nil {
+					r.recordRunMetrics(ctx, types.TerraformDestroy, &destroyJob, metrics.OutcomeFailed)
+					r.recordRunHistory(ctx, meta, types.TerraformDestroy, &destroyJob, types.RunOutcomeFailed, msg)
+				}
+			}
 			if updateErr := meta.UpdateDestroyStatus(ctx, r.Client, types.ConfigurationDestroyFailed, err.Error()); updateErr != nil {
 				return ctrl.Result{}, updateErr
 			}
+			meta.Diagnostics = nil
 		}
 
 		// If no tfState has been generated, then perform a quick cleanup without dispatching destroying job.
 		if meta.IsTFStateGenerated(ctx) {
 			if err := r.terraformDestroy(ctx, configuration, meta); err != nil {
+				if err.Error() == types.MessageWaitingForRun {
+					return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+				}
 				if err.Error() == types.MessageDestroyJobNotCompleted {
-					return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+					return ctrl.Result{RequeueAfter: r.jobRequeueAfter(ctx, meta.ControllerNamespace, meta.DestroyJobName)}, nil
+				}
+				if err.Error() == types.MessageWaitingForDestroyWindow {
+					return ctrl.Result{RequeueAfter: requeueAfterWindow(meta.NextDestroyWindow)}, nil
 				}
 				return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "continue reconciling to destroy cloud resource")
 			}
 		} else {
 			klog.Infof("No need to execute terraform destroy command, because tfstate file not found: %s/%s", configuration.Namespace, configuration.Name)
 			if err := r.cleanUpSubResources(ctx, configuration, meta); err != nil {
+				// The Configuration isn't deleted until its Terraform state is exported
+				if meta.DeletionPolicy == v1beta2.DeletionPolicyExport {
+					return ctrl.Result{RequeueAfter: 3 * time.Second}, err
+				}
 				klog.Warningf("Ignoring error when clean up sub-resources, for no resource is actually created: %s", err)
 			}
 		}
@@ -126,6 +233,13 @@ func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		if err != nil {
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
+		if controllerutil.ContainsFinalizer(&configuration, configurationFinalizer) {
+			msg := "Cloud resources are destroyed"
+			if !meta.DeleteResource {
+				msg = fmt.Sprintf("Cloud resources are kept by the deletion policy %s", meta.DeletionPolicy)
+			}
+			meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonDestroySucceeded, "%s", msg)
+		}
 		if controllerutil.ContainsFinalizer(&configuration, configurationFinalizer) {
 			controllerutil.RemoveFinalizer(&configuration, configurationFinalizer)
 			if err := r.Update(ctx, &configuration); err != nil {
@@ -135,9 +249,19 @@ func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, nil
 	}
 
+	if meta.Suspended {
+		return r.reconcileSuspended(ctx, configuration, meta)
+	}
+
 	var tfExecutionJob = &batchv1.Job{}
-	if err := meta.GetApplyJob(ctx, r.Client, tfExecutionJob); err == nil {
+	getJobErr := meta.GetApplyJob(ctx, r.Client, tfExecutionJob)
+	// No apply Job is created out of the apply window, neither for the changes nor for the first apply or a retry
+	if meta.ApplyWindowClosed && (meta.ConfigurationChanged || meta.EnvChanged || kerrors.IsNotFound(getJobErr)) {
+		return r.waitForApplyWindow(ctx, configuration, meta)
+	}
+	if getJobErr == nil {
 		if !meta.EnvChanged && !meta.ConfigurationChanged && tfExecutionJob.Status.Succeeded == int32(1) {
+			r.recordApplySucceeded(ctx, configuration, meta, tfExecutionJob)
 			err = meta.UpdateApplyStatus(ctx, r.Client, types.Available, types.MessageCloudResourceDeployed)
 			return ctrl.Result{}, err
 		}
@@ -147,21 +271,209 @@ func (r *ConfigurationReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	klog.InfoS("performing Terraform Apply (cloud resource create/update)", "Namespace", req.Namespace, "Name", req.Name)
 	if err := r.terraformApply(ctx, configuration, meta); err != nil {
 		if err.Error() == types.MessageApplyJobNotCompleted {
-			return ctrl.Result{RequeueAfter: 3 * time.Second}, nil
+			return ctrl.Result{RequeueAfter: r.jobRequeueAfter(ctx, meta.ControllerNamespace, meta.ApplyJobName)}, nil
+		}
+		if err.Error() == types.MessageJobQueued || err.Error() == types.MessageWaitingForRun {
+			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+		}
+		if err.Error() == types.MessageJobTimedOut {
+			return ctrl.Result{RequeueAfter: r.jobRequeueAfter(ctx, meta.ControllerNamespace, meta.ApplyJobName)}, nil
 		}
 		return ctrl.Result{RequeueAfter: 3 * time.Second}, errors.Wrap(err, "failed to create/update cloud resource")
 	}
-	state, err := terraform.GetTerraformStatus(ctx, meta.ControllerNamespace, meta.ApplyJobName, types.TerraformContainerName, types.TerraformInitContainerName)
+	if err := r.checkApplyStatus(ctx, configuration, meta); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The events of the Job and its pods trigger the reconciliation, the requeue is only a safety net
+	return ctrl.Result{RequeueAfter: r.jobRequeueAfter(ctx, meta.ControllerNamespace, meta.ApplyJobName)}, nil
+}
+
+// checkApplyStatus reports the failure of the apply Job analyzed from its logs
+func (r *ConfigurationReconciler) checkApplyStatus(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) error {
+	state, diagnostics, err := terraform.GetTerraformStatus(ctx, meta.ControllerNamespace, meta.ApplyJobName, types.TerraformContainerName, types.TerraformInitContainerName)
+	if err == nil {
+		return nil
+	}
+	meta.Diagnostics = diagnostics
+	klog.ErrorS(err, "Terraform apply failed")
+	if configuration.Status.Apply.State != state {
+		msg := terraform.TrimErrorMessage(err.Error())
+		meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonApplyFailed, "%s", msg)
+		var applyJob batchv1.Job
+		if err := meta.GetApplyJob(ctx, r.Client, &applyJob); err == nil {
+			r.recordRunMetrics(ctx, types.TerraformApply, &applyJob, metrics.OutcomeFailed)
+			r.recordRunHistory(ctx, meta, types.TerraformApply, &applyJob, types.RunOutcomeFailed, msg)
+		}
+	}
+	return meta.UpdateApplyStatus(ctx, r.Client, state, err.Error())
+}
+
+// suspension tells why the Configuration is suspended, the reason is types.ReasonResumed if it isn't
+func (r *ConfigurationReconciler) suspension(configuration v1beta2.Configuration) (string, string) {
+	switch {
+	case r.Paused:
+		return types.ReasonControllerPaused, types.MessageControllerPaused
+	case configuration.Spec.Suspend:
+		return types.ReasonSuspended, types.MessageSuspended
+	default:
+		return types.ReasonResumed, types.MessageResumed
+	}
+}
+
+// reconcileSuspended reports the status of the suspended Configuration without creating or deleting any Terraform
+// Jobs. The apply Job started before the suspension runs to the end, unless it's out of date, which is replaced once
+// the Configuration is resumed.
+func (r *ConfigurationReconciler) reconcileSuspended(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) (ctrl.Result, error) {
+	klog.InfoS("the Configuration is suspended, no Terraform Job is created or deleted", "Namespace", configuration.Namespace, "Name", configuration.Name)
+	var job batchv1.Job
+	if meta.EnvChanged || meta.ConfigurationChanged || meta.GetApplyJob(ctx, r.Client, &job) != nil {
+		return ctrl.Result{}, nil
+	}
+	if job.Status.Succeeded == int32(1) {
+		r.recordApplySucceeded(ctx, configuration, meta, &job)
+		return ctrl.Result{}, meta.UpdateApplyStatus(ctx, r.Client, types.Available, types.MessageCloudResourceDeployed)
+	}
+	if err := r.checkApplyStatus(ctx, configuration, meta); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.jobRequeueAfter(ctx, meta.ControllerNamespace, meta.ApplyJobName)}, nil
+}
+
+// checkExpiry deletes the Configuration once it expires by spec.ttl or spec.expireAt, and emits warning Events before
+// that. It tells whether the Configuration is deleted.
+func (r *ConfigurationReconciler) checkExpiry(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) (bool, error) {
+	expireAt, err := tfcfg.GetExpireAt(&configuration)
 	if err != nil {
-		klog.ErrorS(err, "Terraform apply failed")
-		if updateErr := meta.UpdateApplyStatus(ctx, r.Client, state, err.Error()); updateErr != nil {
-			return ctrl.Result{}, updateErr
+		if updateErr := meta.UpdateApplyStatus(ctx, r.Client, types.ConfigurationStaticCheckFailed, err.Error()); updateErr != nil {
+			return false, updateErr
 		}
+		return false, err
+	}
 
-		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+	now := time.Now()
+	var warning time.Duration
+	if expireAt != nil {
+		warning = tfcfg.DueExpiryWarning(expireAt.Time, configuration.Status.LastExpiryWarningTime, now)
+	}
+	if warning != 0 {
+		meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonExpiring,
+			"The Configuration expires at %s, then it's deleted along with the cloud resources. Annotate it with %s to extend its lifetime.",
+			expireAt.Format(time.RFC3339), types.AnnotationExtendTTL)
+	}
+	if warning != 0 || !expireAt.Equal(configuration.Status.ExpireAt) {
+		var latest v1beta2.Configuration
+		if err := r.Get(ctx, client.ObjectKeyFromObject(&configuration), &latest); err != nil {
+			return false, client.IgnoreNotFound(err)
+		}
+		latest.Status.ExpireAt = expireAt
+		if warning != 0 {
+			latest.Status.LastExpiryWarningTime = &metav1.Time{Time: now}
+		}
+		if err := r.Status().Update(ctx, &latest); err != nil {
+			return false, err
+		}
+	}
+
+	// A protected Configuration is kept until the protection is lifted
+	if expireAt == nil || now.Before(expireAt.Time) || configuration.Spec.DeletionProtection {
+		return false, nil
+	}
+	klog.InfoS("the Configuration expires, deleting it", "Namespace", configuration.Namespace, "Name", configuration.Name, "ExpireAt", expireAt)
+	meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonExpired, "The Configuration expired at %s, deleting it along with the cloud resources", expireAt.Format(time.RFC3339))
+	return true, client.IgnoreNotFound(r.Delete(ctx, &configuration))
+}
+
+// checkMaintenanceWindows checks whether the apply and the destroy windows of the Configuration are open now. While
+// the Configuration is being deleted, only the destroy window matters, and an invalid one is reported by a Warning
+// Event instead of blocking the deletion.
+func (r *ConfigurationReconciler) checkMaintenanceWindows(ctx context.Context, configuration *v1beta2.Configuration, meta *process.TFConfigurationMeta) error {
+	now := time.Now()
+	applyWindow, destroyWindow, err := maintenance.GetWindows(ctx, r.Client, configuration)
+	if !configuration.DeletionTimestamp.IsZero() {
+		if err == nil {
+			var destroyOpen bool
+			destroyOpen, meta.NextDestroyWindow, err = maintenance.Check(destroyWindow, now)
+			meta.DestroyWindowClosed = err == nil && !destroyOpen
+		}
+		if err != nil {
+			klog.ErrorS(err, "Ignoring the destroy window of the Configuration being deleted", "Namespace", configuration.Namespace, "Name", configuration.Name)
+			meta.RecordEvent(configuration, v1.EventTypeWarning, types.EventReasonInvalidMaintenanceWindow,
+				"Invalid destroy window, the cloud resources are destroyed regardless of it: %s", err.Error())
+		}
+		return nil
+	}
+	if err != nil {
+		return err
 	}
 
-	return ctrl.Result{}, nil
+	staticCheckFailed := func(err error) error {
+		if updateErr := meta.UpdateApplyStatus(ctx, r.Client, types.ConfigurationStaticCheckFailed, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+	applyOpen, nextApply, err := maintenance.Check(applyWindow, now)
+	if err != nil {
+		return staticCheckFailed(errors.Wrap(err, "invalid apply window"))
+	}
+	destroyOpen, nextDestroy, err := maintenance.Check(destroyWindow, now)
+	if err != nil {
+		return staticCheckFailed(errors.Wrap(err, "invalid destroy window"))
+	}
+	meta.ApplyWindowClosed, meta.NextApplyWindow = !applyOpen, nextApply
+	meta.DestroyWindowClosed, meta.NextDestroyWindow = !destroyOpen, nextDestroy
+	return nil
+}
+
+// waitForApplyWindow queues the changes of the Configuration, or its first apply, until its apply window opens, the
+// Terraform Job which is running is left as it is
+func (r *ConfigurationReconciler) waitForApplyWindow(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) (ctrl.Result, error) {
+	msg := windowMessage(types.MessageWaitingForApplyWindow, meta.NextApplyWindow)
+	klog.InfoS(msg, "Namespace", configuration.Namespace, "Name", configuration.Name)
+	if configuration.Status.Apply.State != types.WaitingForMaintenanceWindow {
+		meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonWaitingForMaintenanceWindow, "%s", msg)
+	}
+	if err := meta.UpdateApplyStatus(ctx, r.Client, types.WaitingForMaintenanceWindow, msg); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfterWindow(meta.NextApplyWindow)}, nil
+}
+
+// windowMessage tells when the maintenance window opens next
+func windowMessage(message string, next time.Time) string {
+	if next.IsZero() {
+		return message + ", but it never opens"
+	}
+	return fmt.Sprintf("%s at %s", message, next.Format(time.RFC3339))
+}
+
+// requeueAfterWindow is the interval to reconcile again once the maintenance window opens, there is no requeue if it
+// never opens
+func requeueAfterWindow(next time.Time) time.Duration {
+	if next.IsZero() {
+		return 0
+	}
+	if d := time.Until(next); d > time.Second {
+		return d
+	}
+	return time.Second
+}
+
+// jobRequeueAfter is the interval to check a running Job again, which backs off as the Job runs longer
+func (r *ConfigurationReconciler) jobRequeueAfter(ctx context.Context, namespace, name string) time.Duration {
+	var job batchv1.Job
+	if err := r.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, &job); err != nil || job.Status.StartTime == nil {
+		return minJobRequeueInterval
+	}
+	interval := time.Since(job.Status.StartTime.Time)
+	if interval < minJobRequeueInterval {
+		return minJobRequeueInterval
+	}
+	if interval > maxJobRequeueInterval {
+		return maxJobRequeueInterval
+	}
+	return interval
 }
 
 func (r *ConfigurationReconciler) terraformApply(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) error {
@@ -171,9 +483,19 @@ func (r *ConfigurationReconciler) terraformApply(ctx context.Context, configurat
 		tfExecutionJob batchv1.Job
 	)
 
-	if err := meta.GetApplyJob(ctx, k8sClient, &tfExecutionJob); err != nil {
+	err := meta.GetApplyJob(ctx, k8sClient, &tfExecutionJob)
+	if kerrors.IsNotFound(err) || meta.EnvChanged || meta.ConfigurationChanged {
+		if err := meta.WaitForRuns(ctx, k8sClient, types.TerraformApply); err != nil {
+			return err
+		}
+	}
+	if err != nil {
 		if kerrors.IsNotFound(err) {
-			return meta.AssembleAndTriggerJob(ctx, k8sClient, types.TerraformApply)
+			if err := meta.AssembleAndTriggerJob(ctx, k8sClient, types.TerraformApply); err != nil {
+				return err
+			}
+			meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonJobCreated, "Created Terraform apply Job %s/%s", meta.ControllerNamespace, meta.ApplyJobName)
+			return nil
 		}
 	}
 	klog.InfoS("terraform apply job", "Namespace", tfExecutionJob.Namespace, "Name", tfExecutionJob.Name)
@@ -184,10 +506,31 @@ func (r *ConfigurationReconciler) terraformApply(ctx context.Context, configurat
 	}
 
 	if !meta.EnvChanged && tfExecutionJob.Status.Succeeded == int32(1) {
+		r.recordApplySucceeded(ctx, configuration, meta, &tfExecutionJob)
 		if err := meta.UpdateApplyStatus(ctx, k8sClient, types.Available, types.MessageCloudResourceDeployed); err != nil {
 			return err
 		}
 	} else {
+		if !meta.EnvChanged && !meta.ConfigurationChanged {
+			timedOut, err := meta.CheckJobTimeout(ctx, k8sClient, &tfExecutionJob)
+			if err != nil {
+				return err
+			}
+			if timedOut != "" {
+				retried, err := r.retryTimedOutJob(ctx, configuration, meta, &tfExecutionJob)
+				if err != nil {
+					return err
+				}
+				if retried {
+					return errors.New(types.MessageJobQueued)
+				}
+				r.recordJobTimedOut(ctx, configuration, meta, types.TerraformApply, &tfExecutionJob, configuration.Status.Apply.State, timedOut)
+				if err := meta.UpdateApplyStatus(ctx, k8sClient, types.TimedOut, timedOut); err != nil {
+					return err
+				}
+				return errors.New(types.MessageJobTimedOut)
+			}
+		}
 		// start provisioning and check the status of the provision
 		// If the state is types.InvalidRegion, no need to continue checking
 		if configuration.Status.Apply.State != types.ConfigurationProvisioningAndChecking &&
@@ -219,19 +562,41 @@ func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configur
 	// If the configuration is deletable, and it is caused by AllowDeleteProvisioningResource feature, the apply job may be still running, we should clean it first to avoid data race.
 	needCleanApplyJob := deletable && feature.DefaultFeatureGate.Enabled(features.AllowDeleteProvisioningResource)
 
+	forceDelete := configuration.Spec.ForceDelete != nil && *configuration.Spec.ForceDelete
 	if !notWaitingDestroyJob {
+		// The destroy Job which is created in the destroy window runs to the end
+		if meta.DestroyWindowClosed && !forceDelete {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.ControllerNamespace}, &destroyJob)
+			if kerrors.IsNotFound(err) {
+				msg := windowMessage(types.MessageWaitingForDestroyWindow, meta.NextDestroyWindow)
+				if configuration.Status.Destroy.State != types.WaitingForMaintenanceWindow {
+					meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonWaitingForMaintenanceWindow, "%s", msg)
+				}
+				if err := meta.UpdateDestroyStatus(ctx, k8sClient, types.WaitingForMaintenanceWindow, msg); err != nil {
+					return err
+				}
+				return errors.New(types.MessageWaitingForDestroyWindow)
+			}
+		}
 		if needCleanApplyJob {
 			err := deleteApplyJob(ctx, meta, k8sClient)
 			if err != nil {
 				return err
 			}
 		}
-		if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.ControllerNamespace}, &destroyJob); err != nil {
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.ControllerNamespace}, &destroyJob)
+		if kerrors.IsNotFound(err) || meta.EnvChanged || meta.ConfigurationChanged {
+			if err := meta.WaitForRuns(ctx, k8sClient, types.TerraformDestroy); err != nil {
+				return err
+			}
+		}
+		if err != nil {
 			if kerrors.IsNotFound(err) {
 				if err := r.Client.Get(ctx, client.ObjectKey{Name: configuration.Name, Namespace: configuration.Namespace}, &v1beta2.Configuration{}); err == nil {
 					if err = meta.AssembleAndTriggerJob(ctx, k8sClient, types.TerraformDestroy); err != nil {
 						return err
 					}
+					meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonJobCreated, "Created Terraform destroy Job %s/%s", meta.ControllerNamespace, meta.DestroyJobName)
 				}
 			}
 		}
@@ -239,14 +604,37 @@ func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configur
 			klog.ErrorS(err, types.ErrUpdateTerraformApplyJob, "Name", meta.DestroyJobName)
 			return errors.Wrap(err, types.ErrUpdateTerraformApplyJob)
 		}
+		if destroyJob.Name != "" && !forceDelete && !meta.EnvChanged && !meta.ConfigurationChanged {
+			timedOut, err := meta.CheckJobTimeout(ctx, k8sClient, &destroyJob)
+			if err != nil {
+				return err
+			}
+			if timedOut != "" {
+				retried, err := r.retryTimedOutJob(ctx, configuration, meta, &destroyJob)
+				if err != nil {
+					return err
+				}
+				if retried {
+					return errors.New(types.MessageDestroyJobNotCompleted)
+				}
+				r.recordJobTimedOut(ctx, configuration, meta, types.TerraformDestroy, &destroyJob, configuration.Status.Destroy.State, timedOut)
+				if err := meta.UpdateDestroyStatus(ctx, k8sClient, types.TimedOut, timedOut); err != nil {
+					return err
+				}
+				return errors.New(types.MessageDestroyJobNotCompleted)
+			}
+		}
 	}
 
 	// destroying
+	if configuration.Status.Destroy.State != types.ConfigurationDestroying {
+		meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonDestroyStarted, types.MessageCloudResourceDestroying)
+	}
 	if err := meta.UpdateDestroyStatus(ctx, k8sClient, types.ConfigurationDestroying, types.MessageCloudResourceDestroying); err != nil {
 		return err
 	}
 
-	if configuration.Spec.ForceDelete != nil && *configuration.Spec.ForceDelete {
+	if forceDelete {
 		// Try to clean up as more sub-resources as possible. Ignore the issues if it hit any.
 		if err := r.cleanUpSubResources(ctx, configuration, meta); err != nil {
 			klog.Warningf("Failed to clean up sub-resources, but it's ignored as the resources are being forced to delete: %s", err)
@@ -259,6 +647,8 @@ func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configur
 			return err
 		}
 		if destroyJob.Status.Succeeded == int32(1) {
+			r.recordRunMetrics(ctx, types.TerraformDestroy, &destroyJob, metrics.OutcomeSucceeded)
+			r.recordRunHistory(ctx, meta, types.TerraformDestroy, &destroyJob, types.RunOutcomeSucceeded, "Cloud resources are destroyed")
 			return r.cleanUpSubResources(ctx, configuration, meta)
 		}
 	} else {
@@ -268,9 +658,86 @@ func (r *ConfigurationReconciler) terraformDestroy(ctx context.Context, configur
 	return errors.New(types.MessageDestroyJobNotCompleted)
 }
 
+// recordApplySucceeded emits an Event, records the metrics and the run history when the Configuration turns Available
+func (r *ConfigurationReconciler) recordApplySucceeded(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta, job *batchv1.Job) {
+	if configuration.Status.Apply.State != types.Available {
+		meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonApplySucceeded, types.MessageCloudResourceDeployed)
+		r.recordRunMetrics(ctx, types.TerraformApply, job, metrics.OutcomeSucceeded)
+		r.recordRunHistory(ctx, meta, types.TerraformApply, job, types.RunOutcomeSucceeded, types.MessageCloudResourceDeployed)
+	}
+}
+
+// recordJobTimedOut emits an Event, records the metrics and the run history when a Terraform Job turns TimedOut
+func (r *ConfigurationReconciler) recordJobTimedOut(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta, executionType types.TerraformExecutionType, job *batchv1.Job, state types.ConfigurationState, message string) {
+	if state == types.TimedOut {
+		return
+	}
+	meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonJobTimedOut, "%s", message)
+	r.recordRunMetrics(ctx, executionType, job, metrics.OutcomeTimedOut)
+	r.recordRunHistory(ctx, meta, executionType, job, types.RunOutcomeFailed, message)
+}
+
+// retryTimedOutJob deletes the timed-out Job to be recreated if the annotation types.AnnotationRetry is changed
+func (r *ConfigurationReconciler) retryTimedOutJob(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta, job *batchv1.Job) (bool, error) {
+	retried, err := meta.RetryTimedOutJob(ctx, r.Client, job)
+	if retried {
+		meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonJobRetried, "Retrying the timed-out Terraform Job %s/%s", job.Namespace, job.Name)
+	}
+	return retried, err
+}
+
+// recordRunHistory completes the record of the finished Terraform Job in the run history, along with its archived logs
+func (r *ConfigurationReconciler) recordRunHistory(ctx context.Context, meta *process.TFConfigurationMeta, executionType types.TerraformExecutionType, job *batchv1.Job, outcome, message string) {
+	getLogs := func() (string, error) {
+		return terraform.GetJobLogs(ctx, job.Namespace, job.Name, types.TerraformContainerName, types.TerraformInitContainerName)
+	}
+	if err := meta.FinishRun(ctx, r.Client, executionType, job, outcome, message, getLogs); err != nil {
+		klog.InfoS("Failed to record the Terraform run", "Namespace", meta.Namespace, "Name", meta.Name, "Job", job.Name, "error", err)
+	}
+}
+
+// recordRunMetrics records the duration, the retries and the time spent in each stage of a finished Terraform Job
+func (r *ConfigurationReconciler) recordRunMetrics(ctx context.Context, executionType types.TerraformExecutionType, job *batchv1.Job, outcome string) {
+	var duration time.Duration
+	if job.Status.StartTime != nil {
+		end := time.Now()
+		if job.Status.CompletionTime != nil {
+			end = job.Status.CompletionTime.Time
+		}
+		duration = end.Sub(job.Status.StartTime.Time)
+	}
+	metrics.RecordRun(executionType, outcome, duration, job.Status.Failed)
+
+	var pods v1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		klog.InfoS("Failed to list the pods of the Job", "Namespace", job.Namespace, "Name", job.Name, "error", err)
+		return
+	}
+	for _, pod := range pods.Items {
+		for _, c := range pod.Status.InitContainerStatuses {
+			if c.Name == types.TerraformInitContainerName && c.State.Terminated != nil {
+				metrics.RecordStage(executionType, metrics.StageInit, c.State.Terminated.FinishedAt.Sub(c.State.Terminated.StartedAt.Time))
+			}
+		}
+		for _, c := range pod.Status.ContainerStatuses {
+			if c.Name == types.TerraformContainerName && c.State.Terminated != nil {
+				metrics.RecordStage(executionType, metrics.StageExecution, c.State.Terminated.FinishedAt.Sub(c.State.Terminated.StartedAt.Time))
+			}
+		}
+	}
+}
+
 func (r *ConfigurationReconciler) cleanUpSubResources(ctx context.Context, configuration v1beta2.Configuration, meta *process.TFConfigurationMeta) error {
 	var k8sClient = r.Client
 
+	// 0. keep the Terraform state for adoption if the cloud resources are not destroyed
+	if err := meta.ReleaseState(ctx, k8sClient, configuration); err != nil {
+		if updateErr := meta.UpdateDestroyStatus(ctx, k8sClient, types.ConfigurationDestroyFailed, err.Error()); updateErr != nil {
+			return updateErr
+		}
+		return err
+	}
+
 	// 1. delete connectionSecret
 	if configuration.Spec.WriteConnectionSecretToReference != nil {
 		secretName := configuration.Spec.WriteConnectionSecretToReference.Name
@@ -280,7 +747,17 @@ func (r *ConfigurationReconciler) cleanUpSubResources(ctx context.Context, confi
 		}
 	}
 
-	// 2~5. delete the jobs, variable secrets, configuration configmaps
+	// 2. delete the Secrets and ConfigMaps the outputs are published to
+	if err := process.DeleteOutputTargets(ctx, k8sClient, configuration, nil); err != nil {
+		return err
+	}
+
+	// 3. delete the sub-resources labeled with the Configuration wherever they are
+	if err := meta.DeleteSubResources(ctx, k8sClient); err != nil {
+		return err
+	}
+
+	// 4~7. delete the jobs, variable secrets, configuration configmaps created before the sub-resources were labeled
 	type cleanupResourceFunc func(ctx context.Context, meta *process.TFConfigurationMeta, k8sClient client.Client) error
 	resourceToCleanup := []cleanupResourceFunc{
 		deleteApplyJob,
@@ -294,7 +771,7 @@ func (r *ConfigurationReconciler) cleanUpSubResources(ctx context.Context, confi
 		}
 	}
 
-	// 6. delete Kubernetes backend secret
+	// 8. delete Kubernetes backend secret
 	if meta.Backend != nil && meta.DeleteResource {
 		if err := meta.Backend.CleanUp(ctx); err != nil {
 			return err
@@ -366,12 +843,15 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 		meta.GitImage = "alpine/git:latest"
 	}
 
-	meta.BackoffLimit = math.MaxInt32
+	meta.BackoffLimit = defaultJobBackoffLimit
 	if backoffLimit := os.Getenv("JOB_BACKOFF_LIMIT"); backoffLimit != "" {
 		if backoffLimitNumber, parserErr := strconv.ParseInt(backoffLimit, 10, 32); parserErr == nil {
 			meta.BackoffLimit = int32(backoffLimitNumber)
 		}
 	}
+	if policy := configuration.Spec.JobPolicy; policy != nil && policy.BackoffLimit != nil {
+		meta.BackoffLimit = *policy.BackoffLimit
+	}
 
 	if err := r.preCheckResourcesSetting(meta); err != nil {
 		return err
@@ -391,11 +871,11 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 	if !configuration.Spec.InlineCredentials {
 		p, err := provider.GetProviderFromConfiguration(ctx, k8sClient, meta.ProviderReference.Namespace, meta.ProviderReference.Name)
 		if p == nil {
-			msg := types.ErrProviderNotFound
+			state, msg := types.ProviderNotFound, types.ErrProviderNotFound
 			if err != nil {
-				msg = err.Error()
+				state, msg = types.Authorizing, err.Error()
 			}
-			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.Authorizing, msg); updateStatusErr != nil {
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, state, msg); updateStatusErr != nil {
 				return errors.Wrap(updateStatusErr, msg)
 			}
 			return errors.New(msg)
@@ -408,6 +888,10 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 			meta.JobEnv = jobEnv
 		}
 		if err := meta.GetCredentials(ctx, k8sClient, p); err != nil {
+			msg := fmt.Sprintf("%s: %s", types.ErrProviderNotReady, err.Error())
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ProviderNotReady, msg); updateStatusErr != nil {
+				return errors.Wrap(updateStatusErr, msg)
+			}
 			return err
 		}
 	}
@@ -433,21 +917,38 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 		return err
 	}
 
-	if configuration.ObjectMeta.DeletionTimestamp.IsZero() {
+	// The held changes aren't stored, so that they're applied once the Configuration is resumed or in its apply window
+	if configuration.ObjectMeta.DeletionTimestamp.IsZero() && !(meta.ConfigurationChanged && meta.ChangesHeld()) {
 		if err := meta.StoreTFConfiguration(ctx, k8sClient); err != nil {
 			return err
 		}
 	}
 
+	if meta.ConfigurationChanged && meta.ChangesHeld() {
+		return nil
+	}
 	if meta.ConfigurationChanged {
 		klog.InfoS("Configuration hanged, reloading...")
+		if configuration.Status.Apply.State != types.ConfigurationReloading {
+			meta.RecordEvent(configuration, v1.EventTypeNormal, types.EventReasonReloading, types.ConfigurationReloadingAsHCLChanged)
+			metrics.DriftsTotal.WithLabelValues(metrics.DriftReasonHCLChanged).Inc()
+		}
 		return meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationReloading, types.ConfigurationReloadingAsHCLChanged)
 	}
 
 	// Check whether env changes
+	if err := meta.GetReferencedVariables(ctx, k8sClient, configuration); err != nil {
+		return err
+	}
+	if err := meta.ResolveDependencies(ctx, k8sClient, configuration); err != nil {
+		return err
+	}
 	if err := meta.PrepareTFVariables(configuration); err != nil {
 		return err
 	}
+	if err := meta.ValidateVariables(ctx, k8sClient, configuration); err != nil {
+		return err
+	}
 
 	var variableInSecret v1.Secret
 	err = k8sClient.Get(ctx, client.ObjectKey{Name: meta.VariableSecretName, Namespace: meta.ControllerNamespace}, &variableInSecret)
@@ -461,15 +962,26 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 			TypeMeta: metav1.TypeMeta{Kind: "Secret"},
 			Data:     meta.VariableSecretData,
 		}
+		meta.SetOwnership(&secret)
 
 		if err := k8sClient.Create(ctx, &secret); err != nil {
 			return err
 		}
 	case err == nil:
+		if err := meta.MigrateLegacyVariableSecret(ctx, k8sClient, &variableInSecret); err != nil {
+			return err
+		}
 		for k, v := range meta.VariableSecretData {
 			if val, ok := variableInSecret.Data[k]; !ok || !bytes.Equal(v, val) {
 				meta.EnvChanged = true
 				klog.Info("Job's env changed")
+				if meta.ChangesHeld() {
+					break
+				}
+				if configuration.Status.Apply.State != types.ConfigurationReloading {
+					meta.RecordEvent(configuration, v1.EventTypeNormal, types.EventReasonReloading, types.ConfigurationReloadingAsVariableChanged)
+					metrics.DriftsTotal.WithLabelValues(metrics.DriftReasonVariableChanged).Inc()
+				}
 				if err := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationReloading, types.ConfigurationReloadingAsVariableChanged); err != nil {
 					return err
 				}
@@ -485,19 +997,145 @@ func (r *ConfigurationReconciler) preCheck(ctx context.Context, configuration *v
 
 // SetupWithManager setups with a manager
 func (r *ConfigurationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("terraform-controller")
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta2.Configuration{}, variableFromIndexKey, indexVariableFrom); err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &v1beta2.Configuration{}, dependencyIndexKey, indexDependencies); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		For(&v1beta2.Configuration{}).
+		Watches(&v1beta2.Configuration{}, handler.EnqueueRequestsFromMapFunc(r.findDependentConfigurations)).
+		Watches(&v1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.findConfigurationsForVariableFrom("Secret")), builder.OnlyMetadata).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.findConfigurationsForVariableFrom("ConfigMap")), builder.OnlyMetadata).
+		Watches(&v1beta2.MaintenancePolicy{}, handler.EnqueueRequestsFromMapFunc(r.findConfigurationsForMaintenancePolicy)).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(findConfigurationForJob)).
+		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(findConfigurationForJob)).
 		Complete(r)
 }
 
+// findConfigurationForJob maps a Terraform Job, or a pod of the Job, to the Configuration which the Job runs for
+func findConfigurationForJob(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels[types.LabelCreatedBy] != types.CreatedByController || labels[types.LabelOwnedBy] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{
+		Name:      labels[types.LabelOwnedBy],
+		Namespace: labels[types.LabelOwnedNamespace],
+	}}}
+}
+
+// findConfigurationsForMaintenancePolicy enqueues the Configurations in the namespace of the changed MaintenancePolicy,
+// so that their maintenance windows are checked again
+func (r *ConfigurationReconciler) findConfigurationsForMaintenancePolicy(ctx context.Context, obj client.Object) []reconcile.Request {
+	var configurations v1beta2.ConfigurationList
+	if err := r.List(ctx, &configurations, client.InNamespace(obj.GetNamespace())); err != nil {
+		klog.ErrorS(err, "failed to list the Configurations for the MaintenancePolicy", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(configurations.Items))
+	for _, c := range configurations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: c.Name, Namespace: c.Namespace}})
+	}
+	return requests
+}
+
+// indexVariableFrom indexes a Configuration by the Secrets and ConfigMaps referenced in spec.variableFrom
+func indexVariableFrom(obj client.Object) []string {
+	configuration, ok := obj.(*v1beta2.Configuration)
+	if !ok {
+		return nil
+	}
+	var keys []string
+	for _, ref := range configuration.Spec.VariableFrom {
+		keys = append(keys, variableFromIndexValue(ref.Kind, ref.Name))
+	}
+	return keys
+}
+
+func variableFromIndexValue(kind, name string) string {
+	return kind + "/" + name
+}
+
+// indexDependencies indexes a Configuration by the `namespace/name` of the Configurations it depends on
+func indexDependencies(obj client.Object) []string {
+	configuration, ok := obj.(*v1beta2.Configuration)
+	if !ok {
+		return nil
+	}
+	dependencies, err := tfcfg.GetDependencies(configuration)
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(dependencies))
+	for _, dep := range dependencies {
+		keys = append(keys, tfcfg.DependencyKey(dep))
+	}
+	return keys
+}
+
+// getDependents returns the `namespace/name` of the Configurations which depend on the Configuration
+func (r *ConfigurationReconciler) getDependents(ctx context.Context, configuration v1beta2.Configuration) ([]string, error) {
+	var configurations v1beta2.ConfigurationList
+	key := tfcfg.DependencyKey(v1beta2.ConfigurationReference{Name: configuration.Name, Namespace: configuration.Namespace})
+	if err := r.List(ctx, &configurations, client.MatchingFields{dependencyIndexKey: key}); err != nil {
+		return nil, errors.Wrap(err, "failed to list the Configurations depending on it")
+	}
+	dependents := make([]string, 0, len(configurations.Items))
+	for _, c := range configurations.Items {
+		dependents = append(dependents, fmt.Sprintf("%s/%s", c.Namespace, c.Name))
+	}
+	return dependents, nil
+}
+
+// findDependentConfigurations enqueues the Configurations depending on the changed one, so they can start applying once
+// it's Available, or reload when its outputs change
+func (r *ConfigurationReconciler) findDependentConfigurations(ctx context.Context, obj client.Object) []reconcile.Request {
+	var configurations v1beta2.ConfigurationList
+	key := tfcfg.DependencyKey(v1beta2.ConfigurationReference{Name: obj.GetName(), Namespace: obj.GetNamespace()})
+	if err := r.List(ctx, &configurations, client.MatchingFields{dependencyIndexKey: key}); err != nil {
+		klog.ErrorS(err, "failed to list the Configurations depending on it", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(configurations.Items))
+	for _, c := range configurations.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: c.Name, Namespace: c.Namespace}})
+	}
+	return requests
+}
+
+// findConfigurationsForVariableFrom enqueues the Configurations whose variables come from the changed Secret or ConfigMap,
+// so that the variable change will be picked up and the Configuration will be reloaded
+func (r *ConfigurationReconciler) findConfigurationsForVariableFrom(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var configurations v1beta2.ConfigurationList
+		if err := r.List(ctx, &configurations, client.InNamespace(obj.GetNamespace()),
+			client.MatchingFields{variableFromIndexKey: variableFromIndexValue(kind, obj.GetName())}); err != nil {
+			klog.ErrorS(err, "failed to list Configurations referencing the object", "Kind", kind, "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(configurations.Items))
+		for _, c := range configurations.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: k8stypes.NamespacedName{Name: c.Name, Namespace: c.Namespace}})
+		}
+		return requests
+	}
+}
+
 func deleteConfigMap(ctx context.Context, meta *process.TFConfigurationMeta, k8sClient client.Client) error {
 	var cm v1.ConfigMap
-	// We have four cases when upgrading. There are three combinations of name and namespace.
-	// TODO compatible for case 4
+	// The sub-resources are labeled with the Configuration and deleted by TFConfigurationMeta.DeleteSubResources. The
+	// ones created before they were labeled are found by names. We have four cases when upgrading. There are three
+	// combinations of name and namespace.
 	// 1. no "controller-namespace" -> specify "controller-namespace"
 	// 2. no "controller-namespace" -> no "controller-namespace"
 	// 3. specify "controller-namespace" -> specify "controller-namespace"
-	// 4. specify "controller-namespace" -> no "controller-namespace" (NOT SUPPORTED)
+	// 4. specify "controller-namespace" -> no "controller-namespace" (NOT SUPPORTED for unlabeled sub-resources)
 	possibleCombination := [][2]string{
 		{meta.LegacySubResources.ConfigurationCMName, meta.LegacySubResources.Namespace},
 		{meta.ConfigurationCMName, meta.ControllerNamespace},
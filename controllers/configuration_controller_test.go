@@ -17,17 +17,21 @@ import (
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+	"github.com/oam-dev/terraform-controller/controllers/metrics"
 	"github.com/oam-dev/terraform-controller/controllers/process"
 	providerpkg "github.com/oam-dev/terraform-controller/controllers/provider"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -202,7 +206,8 @@ func TestConfigurationReconcile(t *testing.T) {
 	}
 
 	r3 := &ConfigurationReconciler{}
-	r3.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration3, destroyJob3).Build()
+	r3.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration3, destroyJob3).
+		WithIndex(&v1beta2.Configuration{}, dependencyIndexKey, indexDependencies).Build()
 
 	configuration4 := &v1beta2.Configuration{
 		ObjectMeta: metav1.ObjectMeta{
@@ -243,7 +248,8 @@ func TestConfigurationReconcile(t *testing.T) {
 	}
 
 	r4 := &ConfigurationReconciler{}
-	r4.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration4, destroyJob4, backendSecret).Build()
+	r4.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration4, destroyJob4, backendSecret).
+		WithIndex(&v1beta2.Configuration{}, dependencyIndexKey, indexDependencies).Build()
 
 	configuration5 := &v1beta2.Configuration{
 		ObjectMeta: metav1.ObjectMeta{
@@ -272,7 +278,8 @@ func TestConfigurationReconcile(t *testing.T) {
 	}
 
 	r5 := &ConfigurationReconciler{}
-	r5.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration5, destroyJob5).Build()
+	r5.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(secret, provider, configuration5, destroyJob5).
+		WithIndex(&v1beta2.Configuration{}, dependencyIndexKey, indexDependencies).Build()
 
 	// @step: create the setup for the job namespace tests
 	configuration6 := &v1beta2.Configuration{
@@ -319,11 +326,11 @@ terraform {
 			Namespace: req.Namespace,
 		},
 		Data: map[string][]byte{
-			"TF_VAR_name":             []byte(varMap["name"]),
-			"ALICLOUD_ACCESS_KEY":     []byte(ak.AccessKeyID),
-			"ALICLOUD_SECRET_KEY":     []byte(ak.AccessKeySecret),
-			"ALICLOUD_REGION":         []byte(provider.Spec.Region),
-			"ALICLOUD_SECURITY_TOKEN": []byte(""),
+			types.TerraformVariablesFileName: []byte(`{"name":"abc"}`),
+			"ALICLOUD_ACCESS_KEY":            []byte(ak.AccessKeyID),
+			"ALICLOUD_SECRET_KEY":            []byte(ak.AccessKeySecret),
+			"ALICLOUD_REGION":                []byte(provider.Spec.Region),
+			"ALICLOUD_SECURITY_TOKEN":        []byte(""),
 		},
 		Type: corev1.SecretTypeOpaque,
 	}
@@ -830,6 +837,63 @@ func TestPreCheck(t *testing.T) {
 	}
 }
 
+func TestPreCheckProviderNotReady(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta1.AddToScheme(s)
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec:       v1beta2.ConfigurationSpec{HCL: "bbb", Region: "cn-beijing"},
+	}
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Provider: "alibaba",
+			Credentials: v1beta1.ProviderCredentials{
+				Source:    crossplane.CredentialsSourceSecret,
+				SecretRef: &crossplane.SecretKeySelector{SecretReference: crossplane.SecretReference{Name: "missing", Namespace: "default"}, Key: "credentials"},
+			},
+		},
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, provider).WithStatusSubresource(configuration).Build()
+
+	testcases := map[string]struct {
+		provider string
+		state    types.ConfigurationState
+	}{
+		"provider not found": {
+			provider: "not-found",
+			state:    types.ProviderNotFound,
+		},
+		"credentials not retrieved": {
+			provider: "default",
+			state:    types.ProviderNotReady,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			meta := &process.TFConfigurationMeta{
+				Name:              "a",
+				Namespace:         "default",
+				ProviderReference: &crossplane.Reference{Name: tc.provider, Namespace: "default"},
+			}
+			assert.NotNil(t, r.preCheck(ctx, configuration.DeepCopy(), meta))
+
+			var got v1beta2.Configuration
+			assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+			assert.Equal(t, tc.state, got.Status.Apply.State)
+			condition := apimeta.FindStatusCondition(got.Status.Conditions, types.ConditionProviderReady)
+			assert.NotNil(t, condition)
+			assert.Equal(t, metav1.ConditionFalse, condition.Status)
+			assert.Equal(t, string(tc.state), condition.Reason)
+		})
+	}
+}
+
 func TestPreCheckWhenConfigurationIsChanged(t *testing.T) {
 	r := &ConfigurationReconciler{}
 	ctx := context.Background()
@@ -1160,6 +1224,12 @@ func TestTerraformDestroy(t *testing.T) {
 	}
 	completeDestroyJob := baseDestroyJob.DeepCopy()
 	completeDestroyJob.Status.Succeeded = int32(1)
+	timedOutDestroyJob := baseDestroyJob.DeepCopy()
+	timedOutDestroyJob.Status.Conditions = []batchv1.JobCondition{{
+		Type:   batchv1.JobFailed,
+		Status: corev1.ConditionTrue,
+		Reason: batchv1.JobReasonDeadlineExceeded,
+	}}
 
 	// Resources to be GC
 	baseConfigurationCM := &corev1.ConfigMap{
@@ -1270,6 +1340,18 @@ func TestTerraformDestroy(t *testing.T) {
 			objects:       []client.Object{readyProvider, baseConfiguration, baseConfigurationCM},
 			keptResources: []client.Object{baseConfigurationCM},
 		},
+		{
+			name: "destroy job is timed out, subresource not cleanup",
+			args: args{
+				configuration: baseConfiguration,
+				meta:          &baseMeta,
+			},
+			want: want{
+				errMsg: types.MessageDestroyJobNotCompleted,
+			},
+			objects:       []client.Object{readyProvider, baseConfiguration, baseConfigurationCM, timedOutDestroyJob},
+			keptResources: []client.Object{baseConfigurationCM, timedOutDestroyJob},
+		},
 		{
 			name: "provider is not ready, cloud resource couldn't be created, delete directly",
 			args: args{
@@ -1368,3 +1450,641 @@ func TestTerraformDestroy(t *testing.T) {
 		})
 	}
 }
+
+func TestFindConfigurationsForVariableFrom(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+
+	referencing := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			VariableFrom: []v1beta2.VariableFromSource{{Kind: "Secret", Name: "db"}},
+		},
+	}
+	referencingConfigMap := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			VariableFrom: []v1beta2.VariableFromSource{{Kind: "ConfigMap", Name: "db"}},
+		},
+	}
+	otherNamespace := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "other"},
+		Spec: v1beta2.ConfigurationSpec{
+			VariableFrom: []v1beta2.VariableFromSource{{Kind: "Secret", Name: "db"}},
+		},
+	}
+	r := &ConfigurationReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).
+			WithObjects(referencing, referencingConfigMap, otherNamespace).
+			WithIndex(&v1beta2.Configuration{}, variableFromIndexKey, indexVariableFrom).
+			Build(),
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	requests := r.findConfigurationsForVariableFrom("Secret")(ctx, secret)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: "a", Namespace: "default"}}}, requests)
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+	requests = r.findConfigurationsForVariableFrom("ConfigMap")(ctx, cm)
+	assert.Equal(t, []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: "b", Namespace: "default"}}}, requests)
+
+	unrelated := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}}
+	assert.Empty(t, r.findConfigurationsForVariableFrom("Secret")(ctx, unrelated))
+}
+
+func TestFindDependentConfigurations(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+
+	vpc := &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "default"}}
+	dependsOn := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			DependsOn: []v1beta2.ConfigurationReference{{Name: "vpc"}},
+		},
+	}
+	referencingOutput := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "other"},
+		Spec: v1beta2.ConfigurationSpec{
+			Variable: &runtime.RawExtension{
+				Raw: []byte(`{"vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","namespace":"default","key":"id"}}}}`),
+			},
+		},
+	}
+	unrelated := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			DependsOn: []v1beta2.ConfigurationReference{{Name: "vpc", Namespace: "other"}},
+		},
+	}
+	r := &ConfigurationReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).
+			WithObjects(vpc, dependsOn, referencingOutput, unrelated).
+			WithIndex(&v1beta2.Configuration{}, dependencyIndexKey, indexDependencies).
+			Build(),
+	}
+
+	requests := r.findDependentConfigurations(ctx, vpc)
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: k8stypes.NamespacedName{Name: "a", Namespace: "default"}},
+		{NamespacedName: k8stypes.NamespacedName{Name: "b", Namespace: "other"}},
+	}, requests)
+
+	dependents, err := r.getDependents(ctx, *vpc)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"default/a", "other/b"}, dependents)
+
+	dependents, err = r.getDependents(ctx, *dependsOn)
+	assert.Nil(t, err)
+	assert.Empty(t, dependents)
+}
+
+func TestReconcileDeletionBlockedByDependents(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	now := metav1.NewTime(time.Now())
+	vpc := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "vpc",
+			Namespace:         "default",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{configurationFinalizer},
+		},
+		Spec: v1beta2.ConfigurationSpec{HCL: "c"},
+	}
+	dependent := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			DependsOn: []v1beta2.ConfigurationReference{{Name: "vpc"}},
+		},
+	}
+	r := &ConfigurationReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).
+			WithObjects(vpc, dependent).
+			WithStatusSubresource(vpc).
+			WithIndex(&v1beta2.Configuration{}, dependencyIndexKey, indexDependencies).
+			Build(),
+	}
+
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "vpc", Namespace: "default"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, result.RequeueAfter)
+
+	var configuration v1beta2.Configuration
+	assert.Nil(t, r.Get(ctx, k8stypes.NamespacedName{Name: "vpc", Namespace: "default"}, &configuration))
+	assert.Equal(t, types.DeletionBlocked, configuration.Status.Destroy.State)
+	assert.Contains(t, configuration.Status.Destroy.Message, "default/a")
+	assert.Contains(t, configuration.Finalizers, configurationFinalizer)
+}
+
+func TestReconcileDeletionProtection(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	now := metav1.NewTime(time.Now())
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "rds",
+			Namespace:         "default",
+			DeletionTimestamp: &now,
+			Finalizers:        []string{configurationFinalizer},
+		},
+		Spec: v1beta2.ConfigurationSpec{HCL: "c", DeletionProtection: true},
+	}
+	r := &ConfigurationReconciler{
+		Client: fake.NewClientBuilder().WithScheme(s).WithObjects(configuration).WithStatusSubresource(configuration).Build(),
+	}
+
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var got v1beta2.Configuration
+	assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+	assert.Equal(t, types.DeletionBlocked, got.Status.Destroy.State)
+	assert.Equal(t, types.MessageDeletionBlockedByProtection, got.Status.Destroy.Message)
+	assert.Contains(t, got.Finalizers, configurationFinalizer)
+}
+
+func TestRecordRunMetrics(t *testing.T) {
+	s := runtime.NewScheme()
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	start := metav1.NewTime(time.Now().Add(-2 * time.Minute))
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system"},
+		Status: batchv1.JobStatus{
+			StartTime:      &start,
+			CompletionTime: &metav1.Time{Time: start.Add(time.Minute)},
+			Failed:         1,
+		},
+	}
+	terminated := func(d time.Duration) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+			StartedAt:  start,
+			FinishedAt: metav1.NewTime(start.Add(d)),
+		}}
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "a-apply-xyz", Namespace: "vela-system", Labels: map[string]string{"job-name": "a-apply"}},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{{Name: types.TerraformInitContainerName, State: terminated(10 * time.Second)}},
+			ContainerStatuses:     []corev1.ContainerStatus{{Name: types.TerraformContainerName, State: terminated(40 * time.Second)}},
+		},
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(job, pod).Build()
+
+	runs := metrics.RunsTotal.WithLabelValues("destroy", metrics.OutcomeSucceeded)
+	retries := metrics.JobRetriesTotal.WithLabelValues("destroy")
+	runsBefore, retriesBefore := testutil.ToFloat64(runs), testutil.ToFloat64(retries)
+
+	r.recordRunMetrics(context.Background(), types.TerraformDestroy, job, metrics.OutcomeSucceeded)
+	assert.Equal(t, runsBefore+1, testutil.ToFloat64(runs))
+	assert.Equal(t, retriesBefore+1, testutil.ToFloat64(retries))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.StageDurationSeconds, "terraform_controller_stage_duration_seconds"))
+}
+
+func TestFindConfigurationForJob(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system", Labels: map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "a",
+		types.LabelOwnedNamespace: "default",
+	}}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: "a", Namespace: "default"}}},
+		findConfigurationForJob(context.Background(), job))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Labels: map[string]string{"app": "b"}}}
+	assert.Nil(t, findConfigurationForJob(context.Background(), pod))
+}
+
+func TestJobRequeueAfter(t *testing.T) {
+	s := runtime.NewScheme()
+	batchv1.AddToScheme(s)
+
+	newJob := func(name string, running time.Duration) *batchv1.Job {
+		start := metav1.NewTime(time.Now().Add(-running))
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vela-system"},
+			Status:     batchv1.JobStatus{StartTime: &start},
+		}
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).
+		WithObjects(newJob("just-started", time.Second), newJob("running", time.Minute), newJob("long-running", time.Hour)).Build()
+
+	ctx := context.Background()
+	assert.Equal(t, minJobRequeueInterval, r.jobRequeueAfter(ctx, "vela-system", "not-found"))
+	assert.Equal(t, minJobRequeueInterval, r.jobRequeueAfter(ctx, "vela-system", "just-started"))
+	interval := r.jobRequeueAfter(ctx, "vela-system", "running")
+	assert.True(t, interval >= time.Minute && interval < maxJobRequeueInterval)
+	assert.Equal(t, maxJobRequeueInterval, r.jobRequeueAfter(ctx, "vela-system", "long-running"))
+}
+
+func TestTerraformApplyTimedOut(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Status: v1beta2.ConfigurationStatus{
+			Apply: v1beta2.ConfigurationApplyStatus{State: types.ConfigurationProvisioningAndChecking},
+		},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "a-apply-xyz",
+			Namespace:         "vela-system",
+			Labels:            map[string]string{"job-name": "a-apply"},
+			CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  types.TerraformInitContainerName,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+			}},
+		},
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, job, pod).
+		WithStatusSubresource(configuration, pod).Build()
+	meta := &process.TFConfigurationMeta{
+		Name:                "a",
+		Namespace:           "default",
+		ApplyJobName:        "a-apply",
+		ControllerNamespace: "vela-system",
+		PendingTimeout:      process.DefaultPendingTimeout,
+	}
+
+	err := r.terraformApply(ctx, *configuration, meta)
+	assert.EqualError(t, err, types.MessageJobTimedOut)
+	var got v1beta2.Configuration
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.TimedOut, got.Status.Apply.State)
+	assert.Contains(t, got.Status.Apply.Message, "ImagePullBackOff")
+	assert.Len(t, got.Status.History, 1)
+	assert.Equal(t, types.RunOutcomeFailed, got.Status.History[0].Outcome)
+
+	// The Job is suspended, so it stays TimedOut after its pod is deleted
+	var suspended batchv1.Job
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(job), &suspended))
+	assert.True(t, *suspended.Spec.Suspend)
+	assert.Nil(t, r.Delete(ctx, pod))
+	err = r.terraformApply(ctx, got, meta)
+	assert.EqualError(t, err, types.MessageJobTimedOut)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.TimedOut, got.Status.Apply.State)
+	assert.Contains(t, got.Status.Apply.Message, "ImagePullBackOff")
+
+	// The Job is retried by the annotation of the Configuration
+	meta.RetryToken = "1"
+	err = r.terraformApply(ctx, got, meta)
+	assert.EqualError(t, err, types.MessageJobQueued)
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})))
+}
+
+func TestReconcileSuspended(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+	rbacv1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Finalizers: []string{configurationFinalizer}},
+		Spec: v1beta2.ConfigurationSpec{
+			HCL:               "new",
+			InlineCredentials: true,
+			Suspend:           true,
+		},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf-a", Namespace: "default"},
+		Data:       map[string]string{types.TerraformHCLConfigurationName: "old"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "default"}}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, cm, job).
+		WithStatusSubresource(configuration).Build()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}
+	getConfiguration := func() *v1beta2.Configuration {
+		var got v1beta2.Configuration
+		assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+		return &got
+	}
+
+	// The change is neither stored nor applied while the Configuration is suspended
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, ctrl.Result{}, result)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	assert.Equal(t, "old", cm.Data[types.TerraformHCLConfigurationName])
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{}))
+	condition := apimeta.FindStatusCondition(getConfiguration().Status.Conditions, types.ConditionSuspended)
+	assert.NotNil(t, condition)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.Equal(t, types.ReasonSuspended, condition.Reason)
+
+	// The controller is paused
+	r.Paused = true
+	got := getConfiguration()
+	got.Spec.Suspend = false
+	assert.Nil(t, r.Update(ctx, got))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	condition = apimeta.FindStatusCondition(getConfiguration().Status.Conditions, types.ConditionSuspended)
+	assert.Equal(t, types.ReasonControllerPaused, condition.Reason)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{}))
+
+	// The accumulated change is applied once resumed
+	r.Paused = false
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	condition = apimeta.FindStatusCondition(getConfiguration().Status.Conditions, types.ConditionSuspended)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, types.ReasonResumed, condition.Reason)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	assert.NotEqual(t, "old", cm.Data[types.TerraformHCLConfigurationName])
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})))
+
+	// Deleting a suspended Configuration is blocked
+	got = getConfiguration()
+	got.Spec.Suspend = true
+	assert.Nil(t, r.Update(ctx, got))
+	assert.Nil(t, r.Delete(ctx, got))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	got = getConfiguration()
+	assert.Equal(t, types.DeletionBlocked, got.Status.Destroy.State)
+	assert.Contains(t, got.Finalizers, configurationFinalizer)
+}
+
+func TestReconcileApplyWindow(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+	rbacv1.AddToScheme(s)
+
+	closed := &v1beta2.MaintenanceWindow{Schedule: "0 0 1 1 *", Duration: metav1.Duration{Duration: time.Minute}}
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Finalizers: []string{configurationFinalizer}},
+		Spec: v1beta2.ConfigurationSpec{
+			HCL:               "new",
+			InlineCredentials: true,
+			ApplyWindow:       closed,
+		},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf-a", Namespace: "default"},
+		Data:       map[string]string{types.TerraformHCLConfigurationName: "old"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "default"}}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, cm, job).
+		WithStatusSubresource(configuration).Build()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}
+	getConfiguration := func() *v1beta2.Configuration {
+		var got v1beta2.Configuration
+		assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+		return &got
+	}
+
+	// The change is queued until the window opens
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.True(t, result.RequeueAfter > 0)
+	got := getConfiguration()
+	assert.Equal(t, types.WaitingForMaintenanceWindow, got.Status.Apply.State)
+	assert.Contains(t, got.Status.Apply.Message, types.MessageWaitingForApplyWindow)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	assert.Equal(t, "old", cm.Data[types.TerraformHCLConfigurationName])
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{}))
+
+	// The first apply is queued as well
+	first := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Finalizers: []string{configurationFinalizer}},
+		Spec:       v1beta2.ConfigurationSpec{HCL: "new", InlineCredentials: true, ApplyWindow: closed},
+	}
+	assert.Nil(t, r.Create(ctx, first))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(first)})
+	assert.Nil(t, err)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(first), first))
+	assert.Equal(t, types.WaitingForMaintenanceWindow, first.Status.Apply.State)
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKey{Name: "b-apply", Namespace: "default"}, &batchv1.Job{})))
+
+	// The window of the Configuration overrides the policy of the namespace
+	assert.Nil(t, r.Create(ctx, &v1beta2.MaintenancePolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "always", Namespace: "default"},
+		Spec: v1beta2.MaintenancePolicySpec{
+			ApplyWindow: &v1beta2.MaintenanceWindow{Schedule: "* * * * *", Duration: metav1.Duration{Duration: time.Hour}},
+		},
+	}))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, types.WaitingForMaintenanceWindow, getConfiguration().Status.Apply.State)
+
+	// The queued change is applied once the window opens
+	got = getConfiguration()
+	got.Spec.ApplyWindow = nil
+	assert.Nil(t, r.Update(ctx, got))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(cm), cm))
+	assert.NotEqual(t, "old", cm.Data[types.TerraformHCLConfigurationName])
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(job), &batchv1.Job{})))
+
+	// An invalid window fails the static check
+	got = getConfiguration()
+	got.Spec.ApplyWindow = &v1beta2.MaintenanceWindow{Schedule: "0 25 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+	assert.Nil(t, r.Update(ctx, got))
+	_, err = r.Reconcile(ctx, req)
+	assert.NotNil(t, err)
+	assert.Equal(t, types.ConfigurationStaticCheckFailed, getConfiguration().Status.Apply.State)
+}
+
+func TestTerraformDestroyWaitingForWindow(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			HCL:               "hcl",
+			InlineCredentials: true,
+			DestroyWindow:     &v1beta2.MaintenanceWindow{Schedule: "0 0 1 1 *", Duration: metav1.Duration{Duration: time.Minute}},
+		},
+		Status: v1beta2.ConfigurationStatus{Apply: v1beta2.ConfigurationApplyStatus{State: types.Available}},
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration).WithStatusSubresource(configuration).Build()
+	meta := process.New(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}, *configuration, r.Client)
+	meta.DeleteResource = true
+	assert.Nil(t, r.checkMaintenanceWindows(ctx, configuration, meta))
+	assert.True(t, meta.DestroyWindowClosed)
+	assert.False(t, meta.ApplyWindowClosed)
+
+	err := r.terraformDestroy(ctx, *configuration, meta)
+	assert.EqualError(t, err, types.MessageWaitingForDestroyWindow)
+	var got v1beta2.Configuration
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.WaitingForMaintenanceWindow, got.Status.Destroy.State)
+	assert.True(t, apierrors.IsNotFound(r.Get(ctx, client.ObjectKey{Name: meta.DestroyJobName, Namespace: meta.ControllerNamespace}, &batchv1.Job{})))
+	assert.True(t, requeueAfterWindow(meta.NextDestroyWindow) > time.Minute)
+	assert.Equal(t, time.Duration(0), requeueAfterWindow(time.Time{}))
+
+	// While deleting, an invalid apply window is ignored, and an invalid destroy window doesn't block the deletion
+	now := metav1.Now()
+	configuration.DeletionTimestamp = &now
+	configuration.Spec.ApplyWindow = &v1beta2.MaintenanceWindow{Schedule: "0 25 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+	meta = process.New(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}, *configuration, r.Client)
+	assert.Nil(t, r.checkMaintenanceWindows(ctx, configuration, meta))
+	assert.True(t, meta.DestroyWindowClosed)
+	configuration.Spec.DestroyWindow = &v1beta2.MaintenanceWindow{Schedule: "0 25 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+	assert.Nil(t, r.checkMaintenanceWindows(ctx, configuration, meta))
+	assert.False(t, meta.DestroyWindowClosed)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.Available, got.Status.Apply.State)
+}
+
+func TestTerraformApplyWaitingForRun(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	batchv1.AddToScheme(s)
+	corev1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec:       v1beta2.ConfigurationSpec{HCL: "hcl", InlineCredentials: true},
+		Status:     v1beta2.ConfigurationStatus{Apply: v1beta2.ConfigurationApplyStatus{State: types.Available}},
+	}
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration).WithStatusSubresource(configuration).Build()
+	meta := process.New(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}, *configuration, r.Client)
+	labels := map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "a",
+		types.LabelOwnedNamespace: "default",
+	}
+	withRun := func(run string) map[string]string {
+		l := map[string]string{types.LabelRun: run}
+		for k, v := range labels {
+			l[k] = v
+		}
+		return l
+	}
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: meta.ApplyJobName, Namespace: meta.ControllerNamespace, Labels: withRun("a-apply-1")},
+		Status:     batchv1.JobStatus{Succeeded: 1},
+	}
+	runJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "refresh-12345678", Namespace: meta.ControllerNamespace, Labels: withRun("refresh")}}
+	assert.Nil(t, r.Create(ctx, applyJob))
+	assert.Nil(t, r.Create(ctx, runJob))
+
+	// The spec changed, but the apply Job isn't replaced while the Job of a TerraformRun is writing the state
+	meta.ConfigurationChanged = true
+	err := r.terraformApply(ctx, *configuration, meta)
+	assert.EqualError(t, err, types.MessageWaitingForRun)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(applyJob), &batchv1.Job{}))
+	var got v1beta2.Configuration
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.Queued, got.Status.Apply.State)
+	assert.Contains(t, got.Status.Apply.Message, "TerraformRun default/refresh is running Job")
+
+	// The finished runs don't block the apply
+	runJob.Status.Succeeded = 1
+	assert.Nil(t, r.Status().Update(ctx, runJob))
+	assert.Nil(t, meta.WaitForRuns(ctx, r.Client, types.TerraformApply))
+}
+
+func TestFindConfigurationsForMaintenancePolicy(t *testing.T) {
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(
+		&v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}},
+		&v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "other"}},
+	).Build()
+	policy := &v1beta2.MaintenancePolicy{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "default"}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{Name: "a", Namespace: "default"}}},
+		r.findConfigurationsForMaintenancePolicy(context.Background(), policy))
+}
+
+func TestCheckExpiry(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+
+	created := metav1.NewTime(time.Now().Add(-47 * time.Hour).Truncate(time.Second))
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "a",
+			Namespace:         "default",
+			CreationTimestamp: created,
+			Finalizers:        []string{configurationFinalizer},
+		},
+		Spec: v1beta2.ConfigurationSpec{TTL: &metav1.Duration{Duration: 48 * time.Hour}},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &ConfigurationReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration).WithStatusSubresource(configuration).Build()
+	meta := process.New(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(configuration)}, *configuration, r.Client,
+		process.EventRecorderOption(recorder))
+	getConfiguration := func() v1beta2.Configuration {
+		var got v1beta2.Configuration
+		assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+		return got
+	}
+
+	// It's warned an hour before the expiry
+	expired, err := r.checkExpiry(ctx, getConfiguration(), meta)
+	assert.Nil(t, err)
+	assert.False(t, expired)
+	got := getConfiguration()
+	assert.True(t, got.Status.ExpireAt.Equal(&metav1.Time{Time: created.Add(48 * time.Hour)}))
+	assert.NotNil(t, got.Status.LastExpiryWarningTime)
+	assert.Contains(t, <-recorder.Events, types.EventReasonExpiring)
+
+	// Only once
+	_, err = r.checkExpiry(ctx, getConfiguration(), meta)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Events)
+
+	// It's deleted once expired
+	got.Spec.TTL = &metav1.Duration{Duration: time.Hour}
+	assert.Nil(t, r.Update(ctx, &got))
+	expired, err = r.checkExpiry(ctx, getConfiguration(), meta)
+	assert.Nil(t, err)
+	assert.True(t, expired)
+	assert.Contains(t, <-recorder.Events, types.EventReasonExpired)
+	got = getConfiguration()
+	assert.False(t, got.DeletionTimestamp.IsZero())
+
+	// The annotation extends the lifetime
+	got.Annotations = map[string]string{types.AnnotationExtendTTL: "72h"}
+	assert.Nil(t, r.Update(ctx, &got))
+	expired, err = r.checkExpiry(ctx, getConfiguration(), meta)
+	assert.Nil(t, err)
+	assert.False(t, expired)
+}
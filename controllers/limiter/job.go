@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+// pendingTTL is how long a created Job is counted before it shows up in the cache
+const pendingTTL = time.Minute
+
+// Limits are the maximum numbers of the Terraform Jobs running at the same time, 0 means unlimited. All the Jobs created
+// by the controller are counted, including the destroy Jobs which are never queued.
+type Limits struct {
+	// Global limits the Jobs of all Configurations
+	Global int
+	// PerNamespace limits the Jobs of the Configurations in a namespace
+	PerNamespace int
+	// PerProvider limits the Jobs of the Configurations using a Provider
+	PerProvider int
+}
+
+// JobLimiter caps the number of the running Terraform Jobs. It gates the apply Jobs and the Jobs of the TerraformRuns,
+// while the destroy Jobs are created directly so that the cloud resources can always be cleaned up, but still take
+// their slots.
+type JobLimiter struct {
+	limits Limits
+
+	mu sync.Mutex
+	// pending are the Jobs created recently, which may not be in the cache yet
+	pending map[k8stypes.NamespacedName]pendingJob
+}
+
+type pendingJob struct {
+	labels  map[string]string
+	created time.Time
+}
+
+// NewJobLimiter creates a JobLimiter
+func NewJobLimiter(limits Limits) *JobLimiter {
+	return &JobLimiter{limits: limits, pending: make(map[k8stypes.NamespacedName]pendingJob)}
+}
+
+// Create creates the Job if the limits allow one more Job to run. Otherwise, the Job isn't created and the returned
+// message tells which limit is reached.
+func (l *JobLimiter) Create(ctx context.Context, k8sClient client.Client, job *batchv1.Job) (string, error) {
+	if l == nil || l.limits == (Limits{}) {
+		return "", k8sClient.Create(ctx, job)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	running, err := l.runningJobs(ctx, k8sClient)
+	if err != nil {
+		return "", err
+	}
+	var global, namespace, provider int
+	for _, labels := range running {
+		global++
+		if labels[types.LabelOwnedNamespace] == job.Labels[types.LabelOwnedNamespace] {
+			namespace++
+		}
+		if job.Labels[types.LabelProviderName] != "" &&
+			labels[types.LabelProviderName] == job.Labels[types.LabelProviderName] &&
+			labels[types.LabelProviderNamespace] == job.Labels[types.LabelProviderNamespace] {
+			provider++
+		}
+	}
+	switch {
+	case reached(l.limits.Global, global):
+		return fmt.Sprintf("%s: %d Jobs are running", types.MessageJobQueued, global), nil
+	case reached(l.limits.PerNamespace, namespace):
+		return fmt.Sprintf("%s: %d Jobs are running in namespace %s", types.MessageJobQueued, namespace,
+			job.Labels[types.LabelOwnedNamespace]), nil
+	case job.Labels[types.LabelProviderName] != "" && reached(l.limits.PerProvider, provider):
+		return fmt.Sprintf("%s: %d Jobs are running with Provider %s/%s", types.MessageJobQueued, provider,
+			job.Labels[types.LabelProviderNamespace], job.Labels[types.LabelProviderName]), nil
+	}
+
+	if err := k8sClient.Create(ctx, job); err != nil {
+		return "", err
+	}
+	l.pending[client.ObjectKeyFromObject(job)] = pendingJob{labels: job.Labels, created: time.Now()}
+	return "", nil
+}
+
+func reached(limit, count int) bool {
+	return limit > 0 && count >= limit
+}
+
+// runningJobs returns the labels of the running Jobs, including the ones just created
+func (l *JobLimiter) runningJobs(ctx context.Context, k8sClient client.Client) (map[k8stypes.NamespacedName]map[string]string, error) {
+	var jobs batchv1.JobList
+	if err := k8sClient.List(ctx, &jobs, client.MatchingLabels{types.LabelCreatedBy: types.CreatedByController}); err != nil {
+		return nil, err
+	}
+	running := make(map[k8stypes.NamespacedName]map[string]string)
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		key := client.ObjectKeyFromObject(job)
+		delete(l.pending, key)
+		if isRunning(job) {
+			running[key] = job.Labels
+		}
+	}
+	for key, p := range l.pending {
+		if time.Since(p.created) > pendingTTL {
+			delete(l.pending, key)
+			continue
+		}
+		running[key] = p.labels
+	}
+	return running, nil
+}
+
+func isRunning(job *batchv1.Job) bool {
+	// The Jobs whose pods are stuck in Pending are suspended
+	if job.Status.Succeeded > 0 || (job.Spec.Suspend != nil && *job.Spec.Suspend) {
+		return false
+	}
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == v1.ConditionTrue {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func newJob(name, namespace, provider string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name + "-apply", Namespace: "vela-system", Labels: map[string]string{
+		types.LabelCreatedBy:         types.CreatedByController,
+		types.LabelOwnedBy:           name,
+		types.LabelOwnedNamespace:    namespace,
+		types.LabelProviderName:      provider,
+		types.LabelProviderNamespace: "default",
+	}}}
+}
+
+func TestJobLimiterCreate(t *testing.T) {
+	ctx := context.Background()
+	completed := newJob("completed", "a", "aws")
+	completed.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: v1.ConditionTrue}}
+
+	testcases := map[string]struct {
+		limits  Limits
+		running []client.Object
+		job     *batchv1.Job
+		queued  string
+	}{
+		"unlimited": {
+			running: []client.Object{newJob("x", "a", "aws")},
+			job:     newJob("y", "a", "aws"),
+		},
+		"global limit": {
+			limits:  Limits{Global: 1},
+			running: []client.Object{newJob("x", "b", "ali")},
+			job:     newJob("y", "a", "aws"),
+			queued:  types.MessageJobQueued + ": 1 Jobs are running",
+		},
+		"namespace limit": {
+			limits:  Limits{Global: 3, PerNamespace: 1},
+			running: []client.Object{newJob("x", "a", "ali"), completed},
+			job:     newJob("y", "a", "aws"),
+			queued:  types.MessageJobQueued + ": 1 Jobs are running in namespace a",
+		},
+		"provider limit": {
+			limits:  Limits{PerProvider: 1},
+			running: []client.Object{newJob("x", "b", "aws")},
+			job:     newJob("y", "a", "aws"),
+			queued:  types.MessageJobQueued + ": 1 Jobs are running with Provider default/aws",
+		},
+		"completed Jobs are not counted": {
+			limits:  Limits{Global: 1},
+			running: []client.Object{completed},
+			job:     newJob("y", "a", "aws"),
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().WithObjects(tc.running...).Build()
+			queued, err := NewJobLimiter(tc.limits).Create(ctx, k8sClient, tc.job)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.queued, queued)
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tc.job), &batchv1.Job{})
+			assert.Equal(t, tc.queued == "", err == nil)
+		})
+	}
+}
+
+func TestJobLimiterCountsPendingJobs(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().Build()
+	l := NewJobLimiter(Limits{Global: 1})
+	queued, err := l.Create(ctx, k8sClient, newJob("x", "a", "aws"))
+	assert.Nil(t, err)
+	assert.Empty(t, queued)
+
+	// The Job created is counted even if it isn't listed yet
+	queued, err = l.Create(ctx, fake.NewClientBuilder().Build(), newJob("y", "a", "aws"))
+	assert.Nil(t, err)
+	assert.Equal(t, types.MessageJobQueued+": 1 Jobs are running", queued)
+
+	var nilLimiter *JobLimiter
+	queued, err = nilLimiter.Create(ctx, k8sClient, newJob("z", "a", "aws"))
+	assert.Nil(t, err)
+	assert.Empty(t, queued)
+}
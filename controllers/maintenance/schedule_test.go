@@ -0,0 +1,81 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSchedule(t *testing.T) {
+	testcases := map[string]string{
+		"too few fields":       "0 2 * *",
+		"minute out of range":  "60 2 * * *",
+		"invalid range":        "0 5-2 * * *",
+		"zero step":            "*/0 * * * *",
+		"day of month is zero": "0 0 0 * *",
+		"not a number":         "0 2 * * SAT",
+	}
+	for name, spec := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseSchedule(spec)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// 2024-01-03 is a Wednesday
+	from := time.Date(2024, 1, 3, 10, 30, 20, 0, time.UTC)
+	testcases := map[string]struct {
+		spec string
+		want time.Time
+	}{
+		"every minute": {
+			spec: "* * * * *",
+			want: time.Date(2024, 1, 3, 10, 31, 0, 0, time.UTC),
+		},
+		"every 15 minutes": {
+			spec: "*/15 * * * *",
+			want: time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC),
+		},
+		"step from a value": {
+			spec: "5/20 * * * *",
+			want: time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC),
+		},
+		"every Saturday": {
+			spec: "0 2 * * 6",
+			want: time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+		},
+		"Sunday as 7": {
+			spec: "0 2 * * 7",
+			want: time.Date(2024, 1, 7, 2, 0, 0, 0, time.UTC),
+		},
+		"list and range": {
+			spec: "30 1,22 * * 1-5",
+			want: time.Date(2024, 1, 3, 22, 30, 0, 0, time.UTC),
+		},
+		"day of month or day of week": {
+			spec: "0 0 5 * 1",
+			want: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		"next year": {
+			spec: "0 0 1 1 *",
+			want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"leap day": {
+			spec: "0 0 29 2 *",
+			want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		"never": {
+			spec: "0 0 30 2 *",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			s, err := ParseSchedule(tc.spec)
+			assert.Nil(t, err)
+			assert.True(t, tc.want.Equal(s.Next(from)), "got %s", s.Next(from))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// Check tells whether the maintenance window is open at the time. It also returns when the window closes if it's
+// open, or when it opens next if it isn't, which is the zero time if it never opens. A nil window is always open.
+func Check(window *v1beta2.MaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	if window == nil {
+		return true, time.Time{}, nil
+	}
+	schedule, err := ParseSchedule(window.Schedule)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	location := time.UTC
+	if window.TimeZone != "" {
+		if location, err = time.LoadLocation(window.TimeZone); err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid time zone %q: %w", window.TimeZone, err)
+		}
+	}
+	duration := window.Duration.Duration
+	if duration <= 0 {
+		return false, time.Time{}, fmt.Errorf("the duration of the maintenance window should be positive, but got %s", duration)
+	}
+
+	// The window is open if it opened within the duration
+	start := schedule.Next(now.In(location).Add(-duration))
+	switch {
+	case start.IsZero():
+		return false, time.Time{}, nil
+	case start.After(now):
+		return false, start, nil
+	default:
+		return true, start.Add(duration), nil
+	}
+}
+
+// GetWindows returns the apply and the destroy windows of the Configuration, which are set by the Configuration, or by
+// the first MaintenancePolicy selecting it by name in its namespace. A window is nil if there isn't any.
+func GetWindows(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration) (*v1beta2.MaintenanceWindow, *v1beta2.MaintenanceWindow, error) {
+	applyWindow, destroyWindow := configuration.Spec.ApplyWindow, configuration.Spec.DestroyWindow
+	if applyWindow != nil && destroyWindow != nil {
+		return applyWindow, destroyWindow, nil
+	}
+
+	var policies v1beta2.MaintenancePolicyList
+	if err := k8sClient.List(ctx, &policies, client.InNamespace(configuration.Namespace)); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to list the MaintenancePolicies")
+	}
+	sort.Slice(policies.Items, func(i, j int) bool {
+		return policies.Items[i].Name < policies.Items[j].Name
+	})
+	for _, policy := range policies.Items {
+		selected, err := Selects(&policy, configuration)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !selected {
+			continue
+		}
+		if applyWindow == nil {
+			applyWindow = policy.Spec.ApplyWindow
+		}
+		if destroyWindow == nil {
+			destroyWindow = policy.Spec.DestroyWindow
+		}
+		break
+	}
+	return applyWindow, destroyWindow, nil
+}
+
+// Selects checks whether the MaintenancePolicy applies to the Configuration
+func Selects(policy *v1beta2.MaintenancePolicy, configuration *v1beta2.Configuration) (bool, error) {
+	if policy.Namespace != configuration.Namespace {
+		return false, nil
+	}
+	if policy.Spec.ConfigurationSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.Spec.ConfigurationSelector)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid configurationSelector of MaintenancePolicy %s", policy.Name)
+	}
+	return selector.Matches(labels.Set(configuration.Labels)), nil
+}
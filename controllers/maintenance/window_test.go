@@ -0,0 +1,147 @@
+package maintenance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestCheck(t *testing.T) {
+	// 2024-01-06 is a Saturday
+	saturday := &v1beta2.MaintenanceWindow{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}}
+	testcases := map[string]struct {
+		window  *v1beta2.MaintenanceWindow
+		now     time.Time
+		open    bool
+		next    time.Time
+		wantErr bool
+	}{
+		"no window": {
+			now:  time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
+			open: true,
+		},
+		"before the window": {
+			window: saturday,
+			now:    time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
+			next:   time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+		},
+		"the window just opens": {
+			window: saturday,
+			now:    time.Date(2024, 1, 6, 2, 0, 0, 0, time.UTC),
+			open:   true,
+			next:   time.Date(2024, 1, 6, 6, 0, 0, 0, time.UTC),
+		},
+		"in the window": {
+			window: saturday,
+			now:    time.Date(2024, 1, 6, 5, 59, 59, 0, time.UTC),
+			open:   true,
+			next:   time.Date(2024, 1, 6, 6, 0, 0, 0, time.UTC),
+		},
+		"the window is closed": {
+			window: saturday,
+			now:    time.Date(2024, 1, 6, 6, 0, 0, 0, time.UTC),
+			next:   time.Date(2024, 1, 13, 2, 0, 0, 0, time.UTC),
+		},
+		"time zone": {
+			window: &v1beta2.MaintenanceWindow{Schedule: "0 2 * * 6", TimeZone: "Asia/Shanghai", Duration: metav1.Duration{Duration: time.Hour}},
+			now:    time.Date(2024, 1, 5, 18, 30, 0, 0, time.UTC),
+			open:   true,
+			next:   time.Date(2024, 1, 5, 19, 0, 0, 0, time.UTC),
+		},
+		"never opens": {
+			window: &v1beta2.MaintenanceWindow{Schedule: "0 0 30 2 *", Duration: metav1.Duration{Duration: time.Hour}},
+			now:    time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC),
+		},
+		"invalid time zone": {
+			window:  &v1beta2.MaintenanceWindow{Schedule: "0 2 * * 6", TimeZone: "Mars/Olympus", Duration: metav1.Duration{Duration: time.Hour}},
+			wantErr: true,
+		},
+		"invalid duration": {
+			window:  &v1beta2.MaintenanceWindow{Schedule: "0 2 * * 6"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			open, next, err := Check(tc.window, tc.now)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.open, open)
+			assert.True(t, tc.next.Equal(next), "got %s", next)
+		})
+	}
+}
+
+func TestGetWindows(t *testing.T) {
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+
+	nightly := &v1beta2.MaintenanceWindow{Schedule: "0 0 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+	weekly := &v1beta2.MaintenanceWindow{Schedule: "0 0 * * 6", Duration: metav1.Duration{Duration: time.Hour}}
+	own := &v1beta2.MaintenanceWindow{Schedule: "0 12 * * *", Duration: metav1.Duration{Duration: time.Hour}}
+	k8sClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		&v1beta2.MaintenancePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-databases", Namespace: "default"},
+			Spec: v1beta2.MaintenancePolicySpec{
+				ConfigurationSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "database"}},
+				ApplyWindow:           weekly,
+				DestroyWindow:         weekly,
+			},
+		},
+		&v1beta2.MaintenancePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "c-all", Namespace: "default"},
+			Spec:       v1beta2.MaintenancePolicySpec{ApplyWindow: nightly},
+		},
+		&v1beta2.MaintenancePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-other", Namespace: "other"},
+			Spec:       v1beta2.MaintenancePolicySpec{ApplyWindow: own},
+		},
+	).Build()
+
+	testcases := map[string]struct {
+		configuration *v1beta2.Configuration
+		apply         *v1beta2.MaintenanceWindow
+		destroy       *v1beta2.MaintenanceWindow
+	}{
+		"selected by labels": {
+			configuration: &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{
+				Name: "db", Namespace: "default", Labels: map[string]string{"tier": "database"},
+			}},
+			apply:   weekly,
+			destroy: weekly,
+		},
+		"selected by the policy without selector": {
+			configuration: &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}},
+			apply:         nightly,
+		},
+		"the windows of the Configuration win": {
+			configuration: &v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", Labels: map[string]string{"tier": "database"}},
+				Spec:       v1beta2.ConfigurationSpec{ApplyWindow: own},
+			},
+			apply:   own,
+			destroy: weekly,
+		},
+		"no policy": {
+			configuration: &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "empty"}},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			apply, destroy, err := GetWindows(context.Background(), k8sClient, tc.configuration)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.apply, apply)
+			assert.Equal(t, tc.destroy, destroy)
+		})
+	}
+}
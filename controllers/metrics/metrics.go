@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+const (
+	namespace = "terraform_controller"
+
+	// OutcomeSucceeded is the outcome of a Terraform run which succeeded
+	OutcomeSucceeded = "succeeded"
+	// OutcomeFailed is the outcome of a Terraform run which failed
+	OutcomeFailed = "failed"
+	// OutcomeTimedOut is the outcome of a Terraform run which exceeded its deadline or got stuck in Pending
+	OutcomeTimedOut = "timed_out"
+
+	// StageInit is the stage running `terraform init`
+	StageInit = "init"
+	// StageExecution is the stage running `terraform apply` or `terraform destroy`
+	StageExecution = "execution"
+
+	// DriftReasonHCLChanged is the reason of a drift caused by the change of the HCL
+	DriftReasonHCLChanged = "HCLChanged"
+	// DriftReasonVariableChanged is the reason of a drift caused by the change of the variables
+	DriftReasonVariableChanged = "VariableChanged"
+)
+
+var (
+	// RunsTotal counts the Terraform apply and destroy runs by outcome
+	RunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Total number of Terraform runs by type and outcome.",
+	}, []string{"type", "outcome"})
+
+	// RunDurationSeconds observes how long the Terraform apply and destroy Jobs run
+	RunDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of Terraform runs by type and outcome.",
+		Buckets:   []float64{10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"type", "outcome"})
+
+	// StageDurationSeconds observes the time spent in `terraform init` and in `terraform apply/destroy`
+	StageDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of the init and execution stages of Terraform runs.",
+		Buckets:   []float64{5, 10, 30, 60, 120, 300, 600, 1200, 1800, 3600},
+	}, []string{"type", "stage"})
+
+	// JobRetriesTotal counts the failed Pods of the Terraform Jobs, which are retried until the backoff limit
+	JobRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_retries_total",
+		Help:      "Total number of retries of Terraform Jobs by type.",
+	}, []string{"type"})
+
+	// DriftsTotal counts the reloads of Configurations as the HCL or the variables changed
+	DriftsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "drifts_total",
+		Help:      "Total number of Configurations reloaded because of drift.",
+	}, []string{"reason"})
+
+	// ProviderValidationFailuresTotal counts the failures of validating the credentials of Providers
+	ProviderValidationFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_validation_failures_total",
+		Help:      "Total number of failed Provider validations by cloud provider.",
+	}, []string{"provider"})
+
+	// configurationsDesc is the number of Configurations per state
+	configurationsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "configurations"),
+		"Number of Configurations per state.", []string{"state"}, nil)
+)
+
+// collectTimeout bounds listing the Configurations at scrape time
+const collectTimeout = 10 * time.Second
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(RunsTotal, RunDurationSeconds, StageDurationSeconds, JobRetriesTotal, DriftsTotal,
+		ProviderValidationFailuresTotal)
+}
+
+// RecordRun records a finished Terraform run
+func RecordRun(executionType types.TerraformExecutionType, outcome string, duration time.Duration, retries int32) {
+	RunsTotal.WithLabelValues(string(executionType), outcome).Inc()
+	RunDurationSeconds.WithLabelValues(string(executionType), outcome).Observe(duration.Seconds())
+	if retries > 0 {
+		JobRetriesTotal.WithLabelValues(string(executionType)).Add(float64(retries))
+	}
+}
+
+// RecordStage records the duration of a stage of a Terraform run
+func RecordStage(executionType types.TerraformExecutionType, stage string, duration time.Duration) {
+	StageDurationSeconds.WithLabelValues(string(executionType), stage).Observe(duration.Seconds())
+}
+
+// ConfigurationCollector counts the Configurations per state at scrape time. They're listed from the cache of the
+// controller, so the deleted Configurations and the ones which aren't reconciled since the controller started are
+// counted correctly.
+type ConfigurationCollector struct {
+	Reader client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *ConfigurationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- configurationsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *ConfigurationCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+	var configurations v1beta2.ConfigurationList
+	if err := c.Reader.List(ctx, &configurations); err != nil {
+		klog.ErrorS(err, "Failed to list the Configurations for the metrics")
+		return
+	}
+	counts := map[types.ConfigurationState]int{}
+	for _, configuration := range configurations.Items {
+		// The Configurations which are never reconciled have no state
+		if state := configuration.Status.Apply.State; state != "" {
+			counts[state]++
+		}
+	}
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(configurationsDesc, prometheus.GaugeValue, float64(count), string(state))
+	}
+}
+
+// RegisterConfigurationCollector registers the ConfigurationCollector which lists the Configurations by the reader
+func RegisterConfigurationCollector(reader client.Reader) error {
+	return ctrlmetrics.Registry.Register(&ConfigurationCollector{Reader: reader})
+}
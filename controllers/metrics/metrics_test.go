@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestRecordRun(t *testing.T) {
+	RecordRun(types.TerraformApply, OutcomeFailed, time.Minute, 2)
+	RecordRun(types.TerraformApply, OutcomeFailed, time.Minute, 0)
+	assert.Equal(t, float64(2), testutil.ToFloat64(RunsTotal.WithLabelValues("apply", OutcomeFailed)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(JobRetriesTotal.WithLabelValues("apply")))
+	assert.Equal(t, 1, testutil.CollectAndCount(RunDurationSeconds))
+
+	RecordStage(types.TerraformDestroy, StageInit, 10*time.Second)
+	assert.Equal(t, 1, testutil.CollectAndCount(StageDurationSeconds))
+}
+
+func TestConfigurationCollector(t *testing.T) {
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	newConfiguration := func(name string, state types.ConfigurationState) *v1beta2.Configuration {
+		return &v1beta2.Configuration{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     v1beta2.ConfigurationStatus{Apply: v1beta2.ConfigurationApplyStatus{State: state}},
+		}
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		newConfiguration("a", types.Available),
+		newConfiguration("b", types.Available),
+		newConfiguration("c", types.ConfigurationProvisioningAndChecking),
+		newConfiguration("d", ""),
+	).Build()
+
+	expected := `
+# HELP terraform_controller_configurations Number of Configurations per state.
+# TYPE terraform_controller_configurations gauge
+terraform_controller_configurations{state="Available"} 2
+terraform_controller_configurations{state="ProvisioningAndChecking"} 1
+`
+	collector := &ConfigurationCollector{Reader: k8sClient}
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	// The deleted Configurations are not counted any more
+	assert.Nil(t, k8sClient.Delete(context.Background(), newConfiguration("c", "")))
+	expected = `
+# HELP terraform_controller_configurations Number of Configurations per state.
+# TYPE terraform_controller_configurations gauge
+terraform_controller_configurations{state="Available"} 2
+`
+	assert.Nil(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
@@ -9,6 +9,10 @@ import (
 )
 
 func (a *Assembler) ApplyContainer(executionType types.TerraformExecutionType, resourceQuota types.ResourceQuota) v1.Container {
+	command := fmt.Sprintf("terraform %s -lock=false -auto-approve", executionType)
+	if a.JSONOutput {
+		command += " -json"
+	}
 
 	c := v1.Container{
 		Name:            types.TerraformContainerName,
@@ -17,7 +21,7 @@ func (a *Assembler) ApplyContainer(executionType types.TerraformExecutionType, r
 		Command: []string{
 			"bash",
 			"-c",
-			fmt.Sprintf("terraform %s -lock=false -auto-approve", executionType),
+			command,
 		},
 		VolumeMounts: []v1.VolumeMount{
 			{
@@ -2,6 +2,7 @@ package container
 
 import (
 	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	v1 "k8s.io/api/core/v1"
 )
 
@@ -13,13 +14,16 @@ type Assembler struct {
 	TerraformCredential        bool
 	TerraformRC                bool
 	TerraformCredentialsHelper bool
+	TerraformVariables         bool
+	JSONOutput                 bool
 
 	TerraformImage string
 	BusyboxImage   string
 	GitImage       string
 
-	Git  types.Git
-	Envs []v1.EnvVar
+	Git     types.Git
+	Envs    []v1.EnvVar
+	Imports []v1beta2.TerraformRunImport
 }
 
 func NewAssembler(name string) *Assembler {
@@ -74,7 +78,22 @@ func (a *Assembler) SetGit(git types.Git) *Assembler {
 	return a
 }
 
+func (a *Assembler) SetTerraformVariables(enabled bool) *Assembler {
+	a.TerraformVariables = enabled
+	return a
+}
+
+func (a *Assembler) SetJSONOutput(enabled bool) *Assembler {
+	a.JSONOutput = enabled
+	return a
+}
+
 func (a *Assembler) SetEnvs(envs []v1.EnvVar) *Assembler {
 	a.Envs = envs
 	return a
 }
+
+func (a *Assembler) SetImports(imports []v1beta2.TerraformRunImport) *Assembler {
+	a.Imports = imports
+	return a
+}
@@ -3,6 +3,7 @@ package container
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/oam-dev/terraform-controller/api/types"
 
@@ -45,14 +46,18 @@ func (a *Assembler) GitContainer() v1.Container {
 func (a *Assembler) getCloneCommand() []string {
 	var cmd string
 	hclPath := filepath.Join(types.BackendVolumeMountPath, a.Git.Path)
-	copyCommand := fmt.Sprintf("cp -r %s/* %s", hclPath, types.WorkingVolumeMountPath)
+	copyCommand := fmt.Sprintf("cp -r %s/* %s", shellQuote(hclPath), types.WorkingVolumeMountPath)
 
-	checkoutCommand := ""
-	checkoutObject := getCheckoutObj(a.Git.Ref)
-	if checkoutObject != "" {
-		checkoutCommand = fmt.Sprintf("git checkout %s", checkoutObject)
+	var cloneCommand string
+	if a.Git.Shallow || a.Git.SparseCheckout || a.Git.Submodules {
+		cloneCommand = a.getFetchCommand()
+	} else {
+		cloneCommand = fmt.Sprintf("git clone %s %s", shellQuote(a.Git.URL), types.BackendVolumeMountPath)
+		checkoutObject := getCheckoutObj(a.Git.Ref)
+		if checkoutObject != "" {
+			cloneCommand = fmt.Sprintf("%s && git checkout %s", cloneCommand, shellQuote(checkoutObject))
+		}
 	}
-	cloneCommand := fmt.Sprintf("git clone %s %s", a.Git.URL, types.BackendVolumeMountPath)
 
 	// Check for git credentials, mount the SSH known hosts and private key, add private key into the SSH authentication agent
 	if a.GitCredential {
@@ -60,12 +65,7 @@ func (a *Assembler) getCloneCommand() []string {
 		cloneCommand = fmt.Sprintf("%s && %s", sshCommand, cloneCommand)
 	}
 
-	cmd = cloneCommand
-
-	if checkoutCommand != "" {
-		cmd = fmt.Sprintf("%s && %s", cmd, checkoutCommand)
-	}
-	cmd = fmt.Sprintf("%s && %s", cmd, copyCommand)
+	cmd = fmt.Sprintf("%s && %s", cloneCommand, copyCommand)
 
 	command := []string{
 		"sh",
@@ -75,6 +75,74 @@ func (a *Assembler) getCloneCommand() []string {
 	return command
 }
 
+// getFetchCommand fetches only the needed ref into an empty repository, so that shallow clone, sparse checkout and
+// submodules can be combined freely. Fetching a commit by its SHA needs the server to allow it, like
+// `uploadpack.allowReachableSHA1InWant`, otherwise all the branches are fetched to find the commit.
+func (a *Assembler) getFetchCommand() string {
+	commands := []string{
+		fmt.Sprintf("cd %s", types.BackendVolumeMountPath),
+		"git init -q",
+		fmt.Sprintf("git remote add origin %s", shellQuote(a.Git.URL)),
+	}
+
+	depthArgs, filterArgs := "", ""
+	if a.Git.Shallow {
+		depthArgs = " --depth 1"
+	}
+	if sparsePaths := a.getSparsePaths(); len(sparsePaths) != 0 {
+		filterArgs = " --filter=blob:none"
+		quoted := make([]string, 0, len(sparsePaths))
+		for _, p := range sparsePaths {
+			quoted = append(quoted, shellQuote(p))
+		}
+		commands = append(commands,
+			"git sparse-checkout init --cone",
+			fmt.Sprintf("git sparse-checkout set %s", strings.Join(quoted, " ")))
+	}
+
+	switch checkoutObject := getCheckoutObj(a.Git.Ref); {
+	case a.Git.Ref.Commit != "":
+		commit := shellQuote(checkoutObject)
+		commands = append(commands,
+			fmt.Sprintf("{ git fetch%s%s origin %s || git fetch%s origin; }", depthArgs, filterArgs, commit, filterArgs),
+			fmt.Sprintf("git checkout -q %s", commit))
+	case checkoutObject == "":
+		commands = append(commands,
+			fmt.Sprintf("git fetch%s%s origin HEAD", depthArgs, filterArgs),
+			"git checkout -q FETCH_HEAD")
+	default:
+		commands = append(commands,
+			fmt.Sprintf("git fetch%s%s origin %s", depthArgs, filterArgs, shellQuote(checkoutObject)),
+			"git checkout -q FETCH_HEAD")
+	}
+
+	if a.Git.Submodules {
+		submoduleCommand := "git submodule update --init --recursive"
+		if a.Git.Shallow {
+			submoduleCommand += " --depth 1"
+		}
+		commands = append(commands, submoduleCommand)
+	}
+	return strings.Join(commands, " && ")
+}
+
+// getSparsePaths returns the directories to check out, or nil if the whole repository is needed
+func (a *Assembler) getSparsePaths() []string {
+	// spec.path is the root of the repository, the whole repository is needed anyway
+	if !a.Git.SparseCheckout || filepath.Clean(a.Git.Path) == "." {
+		return nil
+	}
+	var paths []string
+	for _, p := range append([]string{a.Git.Path}, a.Git.SparsePaths...) {
+		p = strings.Trim(filepath.Clean(p), "/")
+		if p == "." || p == "" {
+			continue
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
 func getCheckoutObj(ref types.GitRef) string {
 	if ref.Commit != "" {
 		return ref.Commit
@@ -45,3 +45,96 @@ func Test_getCheckoutObj(t *testing.T) {
 		})
 	}
 }
+
+func Test_getCloneCommand(t *testing.T) {
+	tests := []struct {
+		name          string
+		git           types.Git
+		gitCredential bool
+		want          string
+	}{
+		{
+			name: "full clone",
+			git: types.Git{
+				URL:  "https://github.com/a/b.git",
+				Path: "alibaba/rds",
+				Ref:  types.GitRef{Tag: "v1.0.0"},
+			},
+			want: "git clone 'https://github.com/a/b.git' /opt/tf-backend && git checkout 'v1.0.0' && cp -r '/opt/tf-backend/alibaba/rds'/* /data",
+		},
+		{
+			name: "shallow clone without ref",
+			git: types.Git{
+				URL:     "https://github.com/a/b.git",
+				Path:    ".",
+				Shallow: true,
+			},
+			want: "cd /opt/tf-backend && git init -q && git remote add origin 'https://github.com/a/b.git' && " +
+				"git fetch --depth 1 origin HEAD && git checkout -q FETCH_HEAD && cp -r '/opt/tf-backend'/* /data",
+		},
+		{
+			name: "shallow sparse clone with submodules and git credentials",
+			git: types.Git{
+				URL:            "git@github.com:a/b.git",
+				Path:           "alibaba/rds/",
+				Ref:            types.GitRef{Branch: "main"},
+				Shallow:        true,
+				SparseCheckout: true,
+				SparsePaths:    []string{"/shared/modules"},
+				Submodules:     true,
+			},
+			gitCredential: true,
+			want: "eval `ssh-agent` && ssh-add /root/.ssh/ssh-privatekey && " +
+				"cd /opt/tf-backend && git init -q && git remote add origin 'git@github.com:a/b.git' && " +
+				"git sparse-checkout init --cone && git sparse-checkout set 'alibaba/rds' 'shared/modules' && " +
+				"git fetch --depth 1 --filter=blob:none origin 'main' && git checkout -q FETCH_HEAD && " +
+				"git submodule update --init --recursive --depth 1 && cp -r '/opt/tf-backend/alibaba/rds'/* /data",
+		},
+		{
+			name: "sparse checkout of the repository root is ignored",
+			git: types.Git{
+				URL:            "https://github.com/a/b.git",
+				Path:           ".",
+				Ref:            types.GitRef{Commit: "123456"},
+				SparseCheckout: true,
+				SparsePaths:    []string{"shared"},
+			},
+			want: "cd /opt/tf-backend && git init -q && git remote add origin 'https://github.com/a/b.git' && " +
+				"{ git fetch origin '123456' || git fetch origin; } && git checkout -q '123456' && cp -r '/opt/tf-backend'/* /data",
+		},
+		{
+			name: "shallow fetch of a commit falls back to the full fetch",
+			git: types.Git{
+				URL:     "https://github.com/a/b.git",
+				Path:    ".",
+				Ref:     types.GitRef{Commit: "123456"},
+				Shallow: true,
+			},
+			want: "cd /opt/tf-backend && git init -q && git remote add origin 'https://github.com/a/b.git' && " +
+				"{ git fetch --depth 1 origin '123456' || git fetch origin; } && git checkout -q '123456' && cp -r '/opt/tf-backend'/* /data",
+		},
+		{
+			name: "the values from the spec are quoted",
+			git: types.Git{
+				URL:            "https://github.com/a/b.git; rm -rf /",
+				Path:           "rds",
+				Ref:            types.GitRef{Branch: "main$(id)"},
+				SparseCheckout: true,
+				SparsePaths:    []string{"shared && curl x"},
+			},
+			want: "cd /opt/tf-backend && git init -q && git remote add origin 'https://github.com/a/b.git; rm -rf /' && " +
+				"git sparse-checkout init --cone && git sparse-checkout set 'rds' 'shared && curl x' && " +
+				"git fetch --filter=blob:none origin 'main$(id)' && git checkout -q FETCH_HEAD && cp -r '/opt/tf-backend/rds'/* /data",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewAssembler("abc").SetGit(tt.git)
+			a.GitCredential = tt.gitCredential
+			got := a.getCloneCommand()
+			if len(got) != 3 || got[2] != tt.want {
+				t.Errorf("getCloneCommand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
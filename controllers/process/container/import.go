@@ -0,0 +1,36 @@
+package container
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	v1 "k8s.io/api/core/v1"
+)
+
+// ImportContainer will run terraform import for the resources which are not in the Terraform state yet, it's for the
+// Terraform versions which don't support import blocks
+func (a *Assembler) ImportContainer() v1.Container {
+	commands := []string{"set -e"}
+	for _, i := range a.Imports {
+		address, id := shellQuote(i.Address), shellQuote(i.ID)
+		commands = append(commands,
+			fmt.Sprintf("terraform state show -no-color %s >/dev/null 2>&1 || terraform import -lock=false -no-color %s %s", address, address, id))
+	}
+	return v1.Container{
+		Name:            types.TerraformImportContainerName,
+		Image:           a.TerraformImage,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command: []string{
+			"sh",
+			"-c",
+			strings.Join(commands, "; "),
+		},
+		VolumeMounts: a.terraformMounts(),
+		Env:          a.Envs,
+	}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
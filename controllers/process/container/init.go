@@ -7,6 +7,22 @@ import (
 
 // InitContainer will run terraform init
 func (a *Assembler) InitContainer() v1.Container {
+	return v1.Container{
+		Name:            types.TerraformInitContainerName,
+		Image:           a.TerraformImage,
+		ImagePullPolicy: v1.PullIfNotPresent,
+		Command: []string{
+			"sh",
+			"-c",
+			"terraform init",
+		},
+		VolumeMounts: a.terraformMounts(),
+		Env:          a.Envs,
+	}
+}
+
+// terraformMounts are the volumes mounted by the init containers which run terraform
+func (a *Assembler) terraformMounts() []v1.VolumeMount {
 	mounts := []v1.VolumeMount{
 		{
 			Name:      a.Name,
@@ -37,17 +53,5 @@ func (a *Assembler) InitContainer() v1.Container {
 			})
 	}
 
-	c := v1.Container{
-		Name:            types.TerraformInitContainerName,
-		Image:           a.TerraformImage,
-		ImagePullPolicy: v1.PullIfNotPresent,
-		Command: []string{
-			"sh",
-			"-c",
-			"terraform init",
-		},
-		VolumeMounts: mounts,
-		Env:          a.Envs,
-	}
-	return c
+	return mounts
 }
@@ -22,6 +22,15 @@ func (a *Assembler) InputContainer() v1.Container {
 			MountPath: types.InputTFConfigurationVolumeMountPath,
 		},
 	}
+	cmd := fmt.Sprintf("cp %s/* %s", types.InputTFConfigurationVolumeMountPath, types.WorkingVolumeMountPath)
+	if a.TerraformVariables {
+		mounts = append(mounts,
+			v1.VolumeMount{
+				Name:      types.VariableVolumeName,
+				MountPath: types.VariableVolumeMountPath,
+			})
+		cmd = fmt.Sprintf("%s && cp %s/%s %s", cmd, types.VariableVolumeMountPath, types.TerraformVariablesFileName, types.WorkingVolumeMountPath)
+	}
 	return v1.Container{
 		Name:            InputContainerName,
 		Image:           a.BusyboxImage,
@@ -29,7 +38,7 @@ func (a *Assembler) InputContainer() v1.Container {
 		Command: []string{
 			"sh",
 			"-c",
-			fmt.Sprintf("cp %s/* %s", types.InputTFConfigurationVolumeMountPath, types.WorkingVolumeMountPath),
+			cmd,
 		},
 		VolumeMounts: mounts,
 	}
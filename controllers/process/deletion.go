@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+)
+
+// exportHint tells how to delete the Configuration whose Terraform state can't be exported
+const exportHint = "set spec.deletionPolicy to Orphan or Delete to delete the Configuration without exporting it"
+
+// orphanedLabels are the labels marking the Terraform state kept for adoption after the Configuration is deleted
+func orphanedLabels(configuration v1beta2.Configuration) map[string]string {
+	return map[string]string{
+		types.LabelOrphanedBy:        configuration.Name,
+		types.LabelOrphanedNamespace: configuration.Namespace,
+	}
+}
+
+// ReleaseState keeps the Terraform state for adoption when the Configuration is deleted by the deletion policy Orphan
+// or Export. The state is labelled where it is, and for Export, it's also copied to spec.exportStateTo. A missing
+// state is fine for Orphan, but fails Export, so that the state isn't lost silently.
+func (meta *TFConfigurationMeta) ReleaseState(ctx context.Context, k8sClient client.Client, configuration v1beta2.Configuration) error {
+	if meta.DeletionPolicy != v1beta2.DeletionPolicyOrphan && meta.DeletionPolicy != v1beta2.DeletionPolicyExport {
+		return nil
+	}
+	export := meta.DeletionPolicy == v1beta2.DeletionPolicyExport
+	if meta.Backend == nil {
+		if export {
+			return errors.New("failed to export the Terraform state: the backend of the Configuration is not available, " + exportHint)
+		}
+		return nil
+	}
+	state, err := meta.Backend.GetTFStateJSON(ctx)
+	if err != nil {
+		if export {
+			return errors.Errorf("failed to export the Terraform state: %s, %s", err.Error(), exportHint)
+		}
+		klog.InfoS("No Terraform state to keep", "Namespace", configuration.Namespace, "Name", configuration.Name, "Error", err)
+		return nil
+	}
+	labels := orphanedLabels(configuration)
+	if err := meta.Backend.Release(ctx, labels); err != nil {
+		return errors.Wrap(err, "failed to label the Terraform state")
+	}
+	if !export {
+		return nil
+	}
+	return exportState(ctx, k8sClient, configuration, meta, state, labels)
+}
+
+// exportState writes the Terraform state to the Secret of spec.exportStateTo. An existing Secret is only overwritten
+// when it's exported by the same Configuration.
+func exportState(ctx context.Context, k8sClient client.Client, configuration v1beta2.Configuration, meta *TFConfigurationMeta, state []byte, labels map[string]string) error {
+	ref := configuration.Spec.ExportStateTo
+	if ref == nil {
+		return errors.New("spec.exportStateTo should be set for the deletion policy Export")
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = configuration.Namespace
+	}
+	if !meta.isOutputNamespaceAllowed(configuration, namespace) {
+		return errors.Errorf("exporting the Terraform state to namespace %s is not allowed", namespace)
+	}
+
+	data := map[string][]byte{backend.TerraformStateNameInSecret: state}
+	var secret v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		secret = v1.Secret{Data: data}
+		secret.Name = ref.Name
+		secret.Namespace = namespace
+		secret.Labels = labels
+		klog.InfoS("Exporting the Terraform state", "Secret", client.ObjectKeyFromObject(&secret))
+		return k8sClient.Create(ctx, &secret)
+	}
+	if secret.Labels[types.LabelOrphanedBy] != configuration.Name || secret.Labels[types.LabelOrphanedNamespace] != configuration.Namespace {
+		return errors.Errorf("the Secret %s/%s to export the Terraform state to already exists", namespace, ref.Name)
+	}
+	secret.Data = data
+	return k8sClient.Update(ctx, &secret)
+}
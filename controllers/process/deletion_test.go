@@ -0,0 +1,140 @@
+package process
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+)
+
+func TestReleaseState(t *testing.T) {
+	ctx := context.Background()
+	tfStateData, _ := base64.StdEncoding.DecodeString("H4sIAAAAAAAA/0SMwa7CIBBF9/0KMutH80ArDb9ijKHDYEhqMQO4afrvBly4POfc3H0QAt7EOaYNrDj/NS7E7ELi5/1XQI3/o4beM3F0K1ihO65xI/egNsLThLPRWi6agkR/CVIppaSZJrfgbBx6//1ItbxqyWDFfnTBlFNlpKaut+EYPgEAAP//xUXpvZsAAAA=")
+	stateSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-a", Namespace: "default"},
+		Data:       map[string][]byte{backend.TerraformStateNameInSecret: tfStateData},
+	}
+	orphaned := map[string]string{types.LabelOrphanedBy: "a", types.LabelOrphanedNamespace: "default"}
+
+	testcases := map[string]struct {
+		policy    v1beta2.DeletionPolicy
+		exportTo  *crossplane.SecretReference
+		existing  *corev1.Secret
+		noState   bool
+		noBackend bool
+		wantErr   string
+		exportKey client.ObjectKey
+	}{
+		"Delete": {
+			policy: v1beta2.DeletionPolicyDelete,
+		},
+		"Orphan": {
+			policy: v1beta2.DeletionPolicyOrphan,
+		},
+		"Orphan without state": {
+			policy:  v1beta2.DeletionPolicyOrphan,
+			noState: true,
+		},
+		"Export without state": {
+			policy:   v1beta2.DeletionPolicyExport,
+			exportTo: &crossplane.SecretReference{Name: "a-state"},
+			noState:  true,
+			wantErr: "failed to export the Terraform state: terraform state file backend secret is not generated: " +
+				`secrets "tfstate-default-a" not found, ` + exportHint,
+		},
+		"Export without backend": {
+			policy:    v1beta2.DeletionPolicyExport,
+			exportTo:  &crossplane.SecretReference{Name: "a-state"},
+			noBackend: true,
+			wantErr:   "failed to export the Terraform state: the backend of the Configuration is not available, " + exportHint,
+		},
+		"Export": {
+			policy:    v1beta2.DeletionPolicyExport,
+			exportTo:  &crossplane.SecretReference{Name: "a-state"},
+			exportKey: client.ObjectKey{Name: "a-state", Namespace: "default"},
+		},
+		"Export to the Secret exported before": {
+			policy:   v1beta2.DeletionPolicyExport,
+			exportTo: &crossplane.SecretReference{Name: "a-state", Namespace: "infra"},
+			existing: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Name: "a-state", Namespace: "infra", Labels: orphaned,
+			}},
+			exportKey: client.ObjectKey{Name: "a-state", Namespace: "infra"},
+		},
+		"Export to an existing Secret": {
+			policy:   v1beta2.DeletionPolicyExport,
+			exportTo: &crossplane.SecretReference{Name: "a-state", Namespace: "infra"},
+			existing: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "a-state", Namespace: "infra"}},
+			wantErr:  "the Secret infra/a-state to export the Terraform state to already exists",
+		},
+		"Export to a namespace not allowed": {
+			policy:   v1beta2.DeletionPolicyExport,
+			exportTo: &crossplane.SecretReference{Name: "a-state", Namespace: "kube-system"},
+			wantErr:  "exporting the Terraform state to namespace kube-system is not allowed",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			builder := fake.NewClientBuilder()
+			if !tc.noState {
+				builder.WithObjects(stateSecret.DeepCopy())
+			}
+			if tc.existing != nil {
+				builder.WithObjects(tc.existing)
+			}
+			k8sClient := builder.Build()
+			meta := &TFConfigurationMeta{
+				DeletionPolicy:          tc.policy,
+				AllowedOutputNamespaces: []string{"infra"},
+				Backend:                 &backend.K8SBackend{Client: k8sClient, SecretSuffix: "a", SecretNS: "default"},
+			}
+			if tc.noBackend {
+				meta.Backend = nil
+			}
+			configuration := v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+				Spec:       v1beta2.ConfigurationSpec{DeletionPolicy: tc.policy, ExportStateTo: tc.exportTo},
+			}
+
+			err := meta.ReleaseState(ctx, k8sClient, configuration)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.Nil(t, err)
+
+			var state corev1.Secret
+			err = k8sClient.Get(ctx, client.ObjectKeyFromObject(stateSecret), &state)
+			if tc.noState {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			if tc.policy == v1beta2.DeletionPolicyDelete {
+				assert.Empty(t, state.Labels)
+			} else {
+				assert.Equal(t, orphaned, state.Labels)
+			}
+
+			if tc.exportKey.Name == "" {
+				return
+			}
+			stateJSON, err := meta.Backend.GetTFStateJSON(ctx)
+			assert.Nil(t, err)
+			var exported corev1.Secret
+			assert.Nil(t, k8sClient.Get(ctx, tc.exportKey, &exported))
+			assert.Equal(t, orphaned, exported.Labels)
+			assert.Equal(t, stateJSON, exported.Data[backend.TerraformStateNameInSecret])
+		})
+	}
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// EventRecorderOption sets the recorder which emits Events for the Configuration
+func EventRecorderOption(recorder record.EventRecorder) Option {
+	return func(configuration v1beta2.Configuration, meta *TFConfigurationMeta) {
+		meta.Recorder = recorder
+	}
+}
+
+// RecordEvent emits an Event for the object, it's a no-op if no recorder is set
+func (meta *TFConfigurationMeta) RecordEvent(object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if meta.Recorder == nil {
+		return
+	}
+	meta.Recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
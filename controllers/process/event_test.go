@@ -0,0 +1,33 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestRecordEvent(t *testing.T) {
+	configuration := v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}}
+	req := ctrl.Request{}
+	req.Name, req.Namespace = configuration.Name, configuration.Namespace
+
+	// no recorder
+	meta := New(req, configuration, nil)
+	meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonReloading, "reloading")
+
+	recorder := record.NewFakeRecorder(1)
+	meta = New(req, configuration, nil, EventRecorderOption(recorder))
+	meta.RecordEvent(&configuration, v1.EventTypeNormal, types.EventReasonJobCreated, "Created Terraform apply Job %s", "a-apply")
+	assert.Equal(t, "Normal JobCreated Created Terraform apply Job a-apply", <-recorder.Events)
+
+	// The dynamic messages are passed as an argument, as they may contain %
+	meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonApplyFailed, "%s", "Error: 100% of the quota is used")
+	assert.Equal(t, "Warning ApplyFailed Error: 100% of the quota is used", <-recorder.Events)
+}
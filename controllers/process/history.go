@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/runlog"
+)
+
+// maxRunHistory is the maximum number of the run records kept in the status of a Configuration
+const maxRunHistory = 10
+
+// LogArchiverOption sets the archiver which archives the full logs of the Terraform runs
+func LogArchiverOption(archiver runlog.Archiver) Option {
+	return func(configuration v1beta2.Configuration, meta *TFConfigurationMeta) {
+		meta.LogArchiver = archiver
+	}
+}
+
+// StartRun adds a running record of the Terraform Job to the history of the Configuration, and creates the
+// TerraformRun for the Job
+func (meta *TFConfigurationMeta) StartRun(ctx context.Context, k8sClient client.Client, executionType types.TerraformExecutionType, job *batchv1.Job, start metav1.Time) error {
+	var configuration v1beta2.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.Name, Namespace: meta.Namespace}, &configuration); err == nil {
+		record := v1beta2.RunRecord{
+			ID:                 runID(executionType, start),
+			Type:               executionType,
+			JobName:            job.Name,
+			Reason:             meta.runReason(configuration, executionType),
+			ObservedGeneration: configuration.Generation,
+			ConfigurationHash:  meta.configurationHash(),
+			StartTime:          start,
+			Outcome:            types.RunOutcomeRunning,
+		}
+		var pruned []v1beta2.RunRecord
+		configuration.Status.History, pruned = appendRun(configuration.Status.History, record)
+		if err := k8sClient.Status().Update(ctx, &configuration); err != nil {
+			return err
+		}
+		meta.pruneRuns(ctx, k8sClient, pruned)
+		return meta.createRun(ctx, k8sClient, executionType, job, start)
+	}
+	return nil
+}
+
+// FinishRun completes the running record of the Terraform Job in the history of the Configuration, and archives the
+// logs if an archiver is set. It's a no-op if the run has been completed, unless a failed Job succeeds after retries.
+func (meta *TFConfigurationMeta) FinishRun(ctx context.Context, k8sClient client.Client, executionType types.TerraformExecutionType, job *batchv1.Job, outcome, message string, getLogs func() (string, error)) error {
+	var configuration v1beta2.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.Name, Namespace: meta.Namespace}, &configuration); err != nil {
+		return nil
+	}
+
+	var (
+		history = configuration.Status.History
+		idx     = -1
+		pruned  []v1beta2.RunRecord
+	)
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Type == executionType && history[i].JobName == job.Name {
+			idx = i
+			break
+		}
+	}
+	switch {
+	case idx < 0:
+		// The Job was created before the history is recorded
+		start := job.CreationTimestamp
+		if job.Status.StartTime != nil {
+			start = *job.Status.StartTime
+		}
+		history, pruned = appendRun(history, v1beta2.RunRecord{
+			ID:                 runID(executionType, start),
+			Type:               executionType,
+			JobName:            job.Name,
+			ObservedGeneration: configuration.Generation,
+			ConfigurationHash:  meta.configurationHash(),
+			StartTime:          start,
+		})
+		idx = len(history) - 1
+	case history[idx].Outcome == outcome,
+		history[idx].Outcome == types.RunOutcomeSucceeded:
+		return nil
+	}
+
+	record := &history[idx]
+	completionTime := metav1.Now()
+	if job.Status.CompletionTime != nil {
+		completionTime = *job.Status.CompletionTime
+	}
+	record.CompletionTime = &completionTime
+	record.Outcome, record.Message = outcome, message
+	if meta.LogArchiver != nil && getLogs != nil {
+		record.LogRef = meta.archiveRunLogs(ctx, *record, getLogs)
+	}
+
+	configuration.Status.History = history
+	if err := k8sClient.Status().Update(ctx, &configuration); err != nil {
+		return err
+	}
+	meta.pruneRuns(ctx, k8sClient, pruned)
+	return nil
+}
+
+func (meta *TFConfigurationMeta) archiveRunLogs(ctx context.Context, record v1beta2.RunRecord, getLogs func() (string, error)) string {
+	logs, err := getLogs()
+	if err != nil {
+		klog.InfoS("Failed to get the logs of the Terraform run", "Name", meta.Name, "Namespace", meta.Namespace, "Job", record.JobName, "error", err)
+		return ""
+	}
+	ref, err := meta.LogArchiver.Archive(ctx, runlog.Run{
+		ID:           record.ID,
+		Namespace:    meta.Namespace,
+		Name:         meta.Name,
+		UID:          string(meta.UID),
+		JobNamespace: meta.ControllerNamespace,
+		Labels:       meta.runLogLabels(),
+	}, logs)
+	if err != nil {
+		klog.InfoS("Failed to archive the logs of the Terraform run", "Name", meta.Name, "Namespace", meta.Namespace, "Job", record.JobName, "error", err)
+		return ""
+	}
+	return ref
+}
+
+// runLogLabels are the labels of the archived logs. They differ from the labels of the sub-resources, so the logs,
+// including those of the destroy run, outlive the Configuration and are removed by the retention of the archiver.
+func (meta *TFConfigurationMeta) runLogLabels() map[string]string {
+	return map[string]string{
+		types.LabelCreatedBy:       types.CreatedByController,
+		types.LabelRunLogOf:        meta.Name,
+		types.LabelRunLogNamespace: meta.Namespace,
+	}
+}
+
+// pruneRuns deletes the TerraformRuns and the archived logs of the records pruned from the history
+func (meta *TFConfigurationMeta) pruneRuns(ctx context.Context, k8sClient client.Client, records []v1beta2.RunRecord) {
+	for _, r := range records {
+		run := &v1beta2.TerraformRun{ObjectMeta: metav1.ObjectMeta{Name: meta.Name + "-" + r.ID, Namespace: meta.Namespace}}
+		if err := k8sClient.Delete(ctx, run); client.IgnoreNotFound(err) != nil {
+			klog.InfoS("Failed to delete the TerraformRun", "Name", run.Name, "Namespace", run.Namespace, "error", err)
+		}
+		if meta.LogArchiver == nil || r.LogRef == "" {
+			continue
+		}
+		if err := meta.LogArchiver.Delete(ctx, r.LogRef); err != nil {
+			klog.InfoS("Failed to delete the archived logs of the Terraform run", "LogRef", r.LogRef, "error", err)
+		}
+	}
+}
+
+// runReason tells why the Terraform Job is created
+func (meta *TFConfigurationMeta) runReason(configuration v1beta2.Configuration, executionType types.TerraformExecutionType) string {
+	if executionType == types.TerraformDestroy {
+		return types.RunReasonDeleted
+	}
+	if configuration.Status.Apply.State == types.ConfigurationReloading {
+		switch configuration.Status.Apply.Message {
+		case types.ConfigurationReloadingAsHCLChanged:
+			return types.RunReasonHCLChanged
+		case types.ConfigurationReloadingAsVariableChanged:
+			return types.RunReasonVariableChanged
+		}
+	}
+
+	var last *v1beta2.RunRecord
+	for i := len(configuration.Status.History) - 1; i >= 0; i-- {
+		if configuration.Status.History[i].Type == types.TerraformApply {
+			last = &configuration.Status.History[i]
+			break
+		}
+	}
+	switch {
+	case last == nil:
+		return types.RunReasonCreated
+	case last.ConfigurationHash != meta.configurationHash():
+		return types.RunReasonHCLChanged
+	case last.ObservedGeneration != configuration.Generation:
+		return types.RunReasonSpecChanged
+	default:
+		return types.RunReasonJobRecreated
+	}
+}
+
+// configurationHash is the short hash of the rendered Terraform configuration
+func (meta *TFConfigurationMeta) configurationHash() string {
+	return ConfigurationHash(meta.CompleteConfiguration)
+}
+
+func runID(executionType types.TerraformExecutionType, start metav1.Time) string {
+	return fmt.Sprintf("%s-%d", executionType, start.Unix())
+}
+
+// appendRun appends the record to the history, and prunes the oldest records beyond maxRunHistory
+func appendRun(history []v1beta2.RunRecord, record v1beta2.RunRecord) ([]v1beta2.RunRecord, []v1beta2.RunRecord) {
+	history = append(history, record)
+	if len(history) <= maxRunHistory {
+		return history, nil
+	}
+	pruned := append([]v1beta2.RunRecord(nil), history[:len(history)-maxRunHistory]...)
+	return history[len(history)-maxRunHistory:], pruned
+}
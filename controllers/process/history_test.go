@@ -0,0 +1,177 @@
+package process
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/runlog"
+)
+
+type fakeArchiver struct {
+	labels   map[string]string
+	archived []string
+	deleted  []string
+}
+
+func (a *fakeArchiver) Archive(_ context.Context, run runlog.Run, logs string) (string, error) {
+	a.labels = run.Labels
+	a.archived = append(a.archived, logs)
+	return fmt.Sprintf("fake://%s/%s/%s", run.Namespace, run.Name, run.ID), nil
+}
+
+func (a *fakeArchiver) Delete(_ context.Context, ref string) error {
+	a.deleted = append(a.deleted, ref)
+	return nil
+}
+
+func TestRunHistory(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	assert.Nil(t, v1beta2.AddToScheme(scheme))
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Generation: 1},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configuration).WithStatusSubresource(configuration, &v1beta2.TerraformRun{}).Build()
+	archiver := &fakeArchiver{}
+	meta := &TFConfigurationMeta{Name: "a", Namespace: "default", CompleteConfiguration: "hcl", LogArchiver: archiver}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system"}}
+	getLogs := func() (string, error) { return "Apply complete!", nil }
+
+	get := func() v1beta2.Configuration {
+		var c v1beta2.Configuration
+		assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configuration), &c))
+		return c
+	}
+
+	assert.Nil(t, meta.StartRun(ctx, k8sClient, types.TerraformApply, job, metav1.Unix(100, 0)))
+	history := get().Status.History
+	assert.Len(t, history, 1)
+	assert.Equal(t, types.RunReasonCreated, history[0].Reason)
+	assert.Equal(t, types.RunOutcomeRunning, history[0].Outcome)
+	assert.Equal(t, int64(1), history[0].ObservedGeneration)
+	assert.Len(t, history[0].ConfigurationHash, 16)
+	assert.Equal(t, "apply-100", history[0].ID)
+	var run v1beta2.TerraformRun
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "a-apply-100", Namespace: "default"}, &run))
+	assert.Equal(t, types.RunOutcomeRunning, run.Status.Phase)
+	assert.Equal(t, "a-apply", run.Status.JobName)
+	assert.Equal(t, history[0].ConfigurationHash, run.Status.ConfigurationHash)
+	assert.Equal(t, types.CreatedByController, run.Labels[types.LabelCreatedBy])
+
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeFailed, "Error: quota", getLogs))
+	history = get().Status.History
+	assert.Equal(t, types.RunOutcomeFailed, history[0].Outcome)
+	assert.Equal(t, "Error: quota", history[0].Message)
+	assert.Equal(t, "fake://default/a/"+history[0].ID, history[0].LogRef)
+	// The archived logs aren't deleted with the sub-resources of the Configuration
+	assert.Equal(t, "a", archiver.labels[types.LabelRunLogOf])
+	assert.Empty(t, archiver.labels[types.LabelOwnedBy])
+	assert.NotNil(t, history[0].CompletionTime)
+
+	// The failed run is recorded only once, but the Job may succeed after retries
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeFailed, "Error: quota", getLogs))
+	assert.Len(t, archiver.archived, 1)
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeSucceeded, types.MessageCloudResourceDeployed, getLogs))
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeSucceeded, types.MessageCloudResourceDeployed, getLogs))
+	assert.Len(t, archiver.archived, 2)
+	history = get().Status.History
+	assert.Len(t, history, 1)
+	assert.Equal(t, types.RunOutcomeSucceeded, history[0].Outcome)
+
+	// The HCL changed
+	meta.CompleteConfiguration = "new hcl"
+	assert.Nil(t, meta.StartRun(ctx, k8sClient, types.TerraformApply, job, metav1.Unix(200, 0)))
+	history = get().Status.History
+	assert.Len(t, history, 2)
+	assert.Equal(t, types.RunReasonHCLChanged, history[1].Reason)
+
+	// The logs can't be got
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeFailed, "failed", func() (string, error) {
+		return "", errors.New("pods are gone")
+	}))
+	history = get().Status.History
+	assert.Equal(t, types.RunOutcomeFailed, history[1].Outcome)
+	assert.Empty(t, history[1].LogRef)
+
+	// The Job created before the history is recorded
+	destroyJob := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "uid-destroy", Namespace: "vela-system"}}
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformDestroy, destroyJob, types.RunOutcomeFailed, "failed", nil))
+	history = get().Status.History
+	assert.Len(t, history, 3)
+	assert.Equal(t, types.TerraformDestroy, history[2].Type)
+	assert.Equal(t, types.RunOutcomeFailed, history[2].Outcome)
+
+	// The oldest records are pruned along with their logs
+	for i := 0; i < maxRunHistory; i++ {
+		assert.Nil(t, meta.StartRun(ctx, k8sClient, types.TerraformDestroy, destroyJob, metav1.Unix(int64(300+i), 0)))
+	}
+	history = get().Status.History
+	assert.Len(t, history, maxRunHistory)
+	assert.Equal(t, types.RunReasonDeleted, history[0].Reason)
+	assert.Len(t, archiver.deleted, 1)
+	var runs v1beta2.TerraformRunList
+	assert.Nil(t, k8sClient.List(ctx, &runs))
+	assert.Len(t, runs.Items, maxRunHistory)
+	assert.True(t, kerrors.IsNotFound(k8sClient.Get(ctx, client.ObjectKey{Name: "a-apply-100", Namespace: "default"}, &run)))
+
+	// The Configuration is gone
+	meta.Name = "b"
+	assert.Nil(t, meta.StartRun(ctx, k8sClient, types.TerraformApply, job, metav1.Now()))
+	assert.Nil(t, meta.FinishRun(ctx, k8sClient, types.TerraformApply, job, types.RunOutcomeFailed, "failed", getLogs))
+}
+
+func TestRunReason(t *testing.T) {
+	meta := &TFConfigurationMeta{CompleteConfiguration: "hcl"}
+	lastApply := v1beta2.RunRecord{Type: types.TerraformApply, ObservedGeneration: 1, ConfigurationHash: meta.configurationHash()}
+
+	testcases := map[string]struct {
+		configuration v1beta2.Configuration
+		executionType types.TerraformExecutionType
+		want          string
+	}{
+		"destroy": {
+			executionType: types.TerraformDestroy,
+			want:          types.RunReasonDeleted,
+		},
+		"variable changed": {
+			configuration: v1beta2.Configuration{Status: v1beta2.ConfigurationStatus{
+				Apply: v1beta2.ConfigurationApplyStatus{State: types.ConfigurationReloading, Message: types.ConfigurationReloadingAsVariableChanged},
+			}},
+			executionType: types.TerraformApply,
+			want:          types.RunReasonVariableChanged,
+		},
+		"spec changed": {
+			configuration: v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status:     v1beta2.ConfigurationStatus{History: []v1beta2.RunRecord{lastApply}},
+			},
+			executionType: types.TerraformApply,
+			want:          types.RunReasonSpecChanged,
+		},
+		"job recreated": {
+			configuration: v1beta2.Configuration{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status:     v1beta2.ConfigurationStatus{History: []v1beta2.RunRecord{lastApply, {Type: types.TerraformDestroy}}},
+			},
+			executionType: types.TerraformApply,
+			want:          types.RunReasonJobRecreated,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, meta.runReason(tc.configuration, tc.executionType))
+		})
+	}
+}
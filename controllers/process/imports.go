@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/terraform"
+)
+
+// ImportBlocksSupported tells whether the Terraform of the image supports import blocks, which is 1.5+. It's judged by
+// the tag of the image, and the images with an unknown version run `terraform import` instead.
+func ImportBlocksSupported(image string) bool {
+	return imageVersionAtLeast(image, 1, 5, 0)
+}
+
+// renderImportBlocks renders the imports as Terraform import blocks
+func renderImportBlocks(imports []v1beta2.TerraformRunImport) string {
+	var b strings.Builder
+	for _, i := range imports {
+		fmt.Fprintf(&b, "import {\n  to = %s\n  id = %s\n}\n", i.Address, quoteHCLString(i.ID))
+	}
+	return b.String()
+}
+
+// quoteHCLString quotes the string as an HCL string literal, where the template sequences are escaped
+func quoteHCLString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.ReplaceAll(quoted, "${", "$${")
+	return strings.ReplaceAll(quoted, "%{", "%%{")
+}
+
+// tfStateResources are the resources in the Terraform state
+type tfStateResources struct {
+	Resources []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey interface{} `json:"index_key"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// addresses are the addresses of the resource instances in the Terraform state
+func (s tfStateResources) addresses() map[string]bool {
+	addresses := make(map[string]bool)
+	for _, r := range s.Resources {
+		address := fmt.Sprintf("%s.%s", r.Type, r.Name)
+		if r.Mode == "data" {
+			address = "data." + address
+		}
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		for _, instance := range r.Instances {
+			switch key := instance.IndexKey.(type) {
+			case float64:
+				addresses[fmt.Sprintf("%s[%d]", address, int64(key))] = true
+			case string:
+				addresses[fmt.Sprintf("%s[%q]", address, key)] = true
+			default:
+				addresses[address] = true
+			}
+		}
+	}
+	return addresses
+}
+
+// importStatuses are the outcomes of importing the resources of spec.imports when the apply state is updated. The
+// imports are succeeded if they're in the Terraform state once the Configuration is available, and the pending
+// imports are failed if the apply fails in any way.
+func (meta *TFConfigurationMeta) importStatuses(ctx context.Context, configuration v1beta2.Configuration, state types.ConfigurationState, message string) []v1beta2.ImportStatus {
+	if len(configuration.Spec.Imports) == 0 {
+		return nil
+	}
+	previous := make(map[string]v1beta2.ImportStatus, len(configuration.Status.Imports))
+	for _, s := range configuration.Status.Imports {
+		previous[s.Address] = s
+	}
+
+	var inState map[string]bool
+	if state == types.Available && meta.Backend != nil {
+		var resources tfStateResources
+		stateJSON, err := meta.Backend.GetTFStateJSON(ctx)
+		if err == nil {
+			err = json.Unmarshal(stateJSON, &resources)
+		}
+		if err != nil {
+			klog.InfoS("Failed to get the resources in the Terraform state", "Namespace", configuration.Namespace, "Name", configuration.Name, "Error", err)
+		} else {
+			inState = resources.addresses()
+		}
+	}
+
+	statuses := make([]v1beta2.ImportStatus, 0, len(configuration.Spec.Imports))
+	for _, i := range configuration.Spec.Imports {
+		status, ok := previous[i.Address]
+		if !ok || status.ID != i.ID {
+			status = v1beta2.ImportStatus{Address: i.Address, ID: i.ID, Phase: types.RunOutcomePending}
+		}
+		switch {
+		case inState != nil && inState[i.Address]:
+			status.Phase, status.Message = types.RunOutcomeSucceeded, ""
+		case inState != nil:
+			status.Phase, status.Message = types.RunOutcomeFailed, "The resource is not found in the Terraform state"
+		case isApplyFailedState(state) && status.Phase == types.RunOutcomePending:
+			status.Phase, status.Message = types.RunOutcomeFailed, message
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// isApplyFailedState tells whether the apply Job has failed, including the classified cloud errors and the timeout
+func isApplyFailedState(state types.ConfigurationState) bool {
+	switch state {
+	case types.ConfigurationApplyFailed, types.TerraformInitError, types.TimedOut:
+		return true
+	}
+	return terraform.IsCloudErrorState(state)
+}
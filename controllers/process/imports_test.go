@@ -0,0 +1,132 @@
+package process
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+)
+
+func TestImportBlocksSupported(t *testing.T) {
+	testcases := map[string]bool{
+		"oamdev/docker-terraform:1.1.2":       false,
+		"oamdev/docker-terraform:1.5.7":       true,
+		"hashicorp/terraform:v1.10.0-alpha":   true,
+		"registry:5000/docker-terraform:2.0":  true,
+		"registry:5000/docker-terraform":      false,
+		"oamdev/docker-terraform:latest":      false,
+		"oamdev/docker-terraform:0.15.5-beta": false,
+	}
+	for image, want := range testcases {
+		assert.Equal(t, want, ImportBlocksSupported(image), image)
+	}
+}
+
+func TestRenderConfigurationWithImports(t *testing.T) {
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			HCL: "resource \"alicloud_oss_bucket\" \"b\" {}",
+			Imports: []v1beta2.TerraformRunImport{
+				{Address: "alicloud_oss_bucket.b", ID: "bucket-${x}"},
+			},
+		},
+	}
+	importBlock := `import {
+  to = alicloud_oss_bucket.b
+  id = "bucket-$${x}"
+}
+`
+	meta := &TFConfigurationMeta{K8sClient: fake.NewClientBuilder().Build(), TerraformImage: "oamdev/docker-terraform:1.5.7"}
+	cfg, _, err := meta.RenderConfiguration(configuration, types.ConfigurationHCL)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(cfg, importBlock))
+	job := meta.assembleTerraformJob(types.TerraformApply)
+	for _, c := range job.Spec.Template.Spec.InitContainers {
+		assert.NotEqual(t, types.TerraformImportContainerName, c.Name)
+	}
+
+	// The older versions import the resources by an init container
+	meta = &TFConfigurationMeta{K8sClient: fake.NewClientBuilder().Build(), TerraformImage: "oamdev/docker-terraform:1.1.2",
+		Imports: configuration.Spec.Imports}
+	cfg, _, err = meta.RenderConfiguration(configuration, types.ConfigurationHCL)
+	assert.Nil(t, err)
+	assert.NotContains(t, cfg, "import {")
+	job = meta.assembleTerraformJob(types.TerraformApply)
+	initContainers := job.Spec.Template.Spec.InitContainers
+	importContainer := initContainers[len(initContainers)-1]
+	assert.Equal(t, types.TerraformImportContainerName, importContainer.Name)
+	assert.Equal(t, "set -e; terraform state show -no-color 'alicloud_oss_bucket.b' >/dev/null 2>&1 || "+
+		"terraform import -lock=false -no-color 'alicloud_oss_bucket.b' 'bucket-${x}'", importContainer.Command[2])
+	job = meta.assembleTerraformJob(types.TerraformDestroy)
+	initContainers = job.Spec.Template.Spec.InitContainers
+	assert.Equal(t, types.TerraformInitContainerName, initContainers[len(initContainers)-1].Name)
+}
+
+func TestImportStatuses(t *testing.T) {
+	ctx := context.Background()
+	var state bytes.Buffer
+	w := gzip.NewWriter(&state)
+	_, _ = w.Write([]byte(`{"resources": [
+		{"mode": "managed", "type": "alicloud_oss_bucket", "name": "b", "instances": [{}]},
+		{"module": "module.m", "mode": "managed", "type": "alicloud_vpc", "name": "v", "instances": [{"index_key": 0}]},
+		{"mode": "managed", "type": "alicloud_eip", "name": "e", "instances": [{"index_key": "k"}]}
+	]}`))
+	assert.Nil(t, w.Close())
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-a", Namespace: "default"},
+		Data:       map[string][]byte{backend.TerraformStateNameInSecret: state.Bytes()},
+	}).Build()
+	meta := &TFConfigurationMeta{Backend: &backend.K8SBackend{Client: k8sClient, SecretSuffix: "a", SecretNS: "default"}}
+
+	configuration := v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{Imports: []v1beta2.TerraformRunImport{
+			{Address: "alicloud_oss_bucket.b", ID: "b"},
+			{Address: "module.m.alicloud_vpc.v[0]", ID: "v"},
+			{Address: `alicloud_eip.e["k"]`, ID: "e"},
+			{Address: "alicloud_eip.missing", ID: "m"},
+		}},
+	}
+
+	statuses := meta.importStatuses(ctx, configuration, types.ConfigurationProvisioningAndChecking, "")
+	for _, s := range statuses {
+		assert.Equal(t, types.RunOutcomePending, s.Phase)
+	}
+
+	// The pending imports fail with the apply, whatever the failure is
+	for _, state := range []types.ConfigurationState{types.AuthenticationFailed, types.QuotaExceeded, types.ResourceConflict, types.OperationTimeout, types.InvalidRegion, types.TimedOut} {
+		for _, s := range meta.importStatuses(ctx, configuration, state, "failed") {
+			assert.Equal(t, types.RunOutcomeFailed, s.Phase, state)
+		}
+	}
+
+	configuration.Status.Imports = statuses
+	statuses = meta.importStatuses(ctx, configuration, types.ConfigurationApplyFailed, "apply failed")
+	for _, s := range statuses {
+		assert.Equal(t, types.RunOutcomeFailed, s.Phase)
+		assert.Equal(t, "apply failed", s.Message)
+	}
+
+	configuration.Status.Imports = statuses
+	statuses = meta.importStatuses(ctx, configuration, types.Available, "")
+	assert.Equal(t, []v1beta2.ImportStatus{
+		{Address: "alicloud_oss_bucket.b", ID: "b", Phase: types.RunOutcomeSucceeded},
+		{Address: "module.m.alicloud_vpc.v[0]", ID: "v", Phase: types.RunOutcomeSucceeded},
+		{Address: `alicloud_eip.e["k"]`, ID: "e", Phase: types.RunOutcomeSucceeded},
+		{Address: "alicloud_eip.missing", ID: "m", Phase: types.RunOutcomeFailed, Message: "The resource is not found in the Terraform state"},
+	}, statuses)
+
+	configuration.Spec.Imports = nil
+	assert.Nil(t, meta.importStatuses(ctx, configuration, types.Available, ""))
+}
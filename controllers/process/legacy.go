@@ -0,0 +1,73 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+// NewLegacyJobLabeler returns the Runnable which labels the Terraform Jobs created before the Jobs were labeled, as
+// only the labeled Jobs are cached. The Jobs are listed from the API server by the reader, and the Terraform Jobs are
+// told by their Terraform container. The Jobs in all namespaces are labeled if the namespace is empty.
+func NewLegacyJobLabeler(k8sClient client.Client, reader client.Reader, namespace string) manager.Runnable {
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		if err := LabelLegacyJobs(ctx, k8sClient, reader, namespace); err != nil {
+			klog.ErrorS(err, "Failed to label the legacy Terraform Jobs")
+		}
+		return nil
+	})
+}
+
+// LabelLegacyJobs labels the Terraform Jobs in the namespace without the label types.LabelCreatedBy
+func LabelLegacyJobs(ctx context.Context, k8sClient client.Client, reader client.Reader, namespace string) error {
+	var jobs batchv1.JobList
+	if err := reader.List(ctx, &jobs, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if _, ok := job.Labels[types.LabelCreatedBy]; ok || !isTerraformJob(job) {
+			continue
+		}
+		patch := client.MergeFrom(job.DeepCopy())
+		if job.Labels == nil {
+			job.Labels = map[string]string{}
+		}
+		job.Labels[types.LabelCreatedBy] = types.CreatedByController
+		klog.InfoS("Labeling the legacy Terraform Job", "Namespace", job.Namespace, "Name", job.Name)
+		if err := k8sClient.Patch(ctx, job, patch); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isTerraformJob(job *batchv1.Job) bool {
+	for _, c := range job.Spec.Template.Spec.Containers {
+		if c.Name == types.TerraformContainerName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package process
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestLabelLegacyJobs(t *testing.T) {
+	newJob := func(name, container string, labels map[string]string) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: labels},
+			Spec: batchv1.JobSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+				Containers: []v1.Container{{Name: container}},
+			}}},
+		}
+	}
+	legacy := newJob("a-apply", types.TerraformContainerName, nil)
+	labeled := newJob("b-apply", types.TerraformContainerName, map[string]string{types.LabelCreatedBy: types.CreatedByController, "k": "v"})
+	other := newJob("backup", "backup", map[string]string{"app": "backup"})
+	k8sClient := fake.NewClientBuilder().WithObjects(legacy, labeled, other).Build()
+
+	assert.Nil(t, LabelLegacyJobs(context.Background(), k8sClient, k8sClient, ""))
+	for _, tc := range []struct {
+		job  *batchv1.Job
+		want map[string]string
+	}{
+		{job: legacy, want: map[string]string{types.LabelCreatedBy: types.CreatedByController}},
+		{job: labeled, want: labeled.Labels},
+		{job: other, want: other.Labels},
+	} {
+		var got batchv1.Job
+		assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tc.job), &got))
+		assert.Equal(t, tc.want, got.Labels, tc.job.Name)
+	}
+}
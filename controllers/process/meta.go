@@ -1,13 +1,19 @@
 package process
 
 import (
+	"time"
+
 	"github.com/oam-dev/terraform-controller/api/types"
 	crossplane "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
 	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
+	"github.com/oam-dev/terraform-controller/controllers/runlog"
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -26,6 +32,7 @@ type LegacySubResources struct {
 type TFConfigurationMeta struct {
 	Name                                         string
 	Namespace                                    string
+	UID                                          k8stypes.UID
 	ControllerNamespace                          string
 	ConfigurationType                            types.ConfigurationType
 	CompleteConfiguration                        string
@@ -43,6 +50,9 @@ type TFConfigurationMeta struct {
 	Region                                       string
 	Credentials                                  map[string]string
 	JobEnv                                       map[string]interface{}
+	ReferencedVariables                          map[string]string
+	TFVariables                                  map[string]interface{}
+	OutputVariables                              map[string]interface{}
 	GitCredentialsSecretReference                *v1.SecretReference
 	TerraformCredentialsSecretReference          *v1.SecretReference
 	TerraformRCConfigMapReference                *v1.SecretReference
@@ -59,6 +69,13 @@ type TFConfigurationMeta struct {
 
 	// BackoffLimit specifies the number of retries to mark the Job as failed
 	BackoffLimit int32
+	// ActiveDeadlineSeconds is the duration the Job runs before it's terminated, there is no deadline if it's nil
+	ActiveDeadlineSeconds *int64
+	// PendingTimeout is the duration the pods of the Job stay Pending before the Job is considered stuck
+	PendingTimeout time.Duration
+	// RetryToken is the value of the annotation types.AnnotationRetry of the Configuration, which is also set to the
+	// Jobs. A timed-out Job is recreated if its token is different.
+	RetryToken string
 
 	// ResourceQuota series Variables are for Setting Compute Resources required by this container
 	ResourceQuota types.ResourceQuota
@@ -66,6 +83,45 @@ type TFConfigurationMeta struct {
 	LegacySubResources    LegacySubResources
 	ControllerNSSpecified bool
 
+	// AllowedOutputNamespaces are the namespaces, besides the namespace of the Configuration, which the outputs can be
+	// published to
+	AllowedOutputNamespaces []string
+
+	// AllowedDependencyNamespaces are the namespaces, besides the namespace of the Configuration, which the
+	// Configurations depended on can be in
+	AllowedDependencyNamespaces []string
+
+	// Recorder emits Events for the Configuration, it's optional
+	Recorder record.EventRecorder
+
+	// JobLimiter queues the Terraform apply Jobs when too many Terraform Jobs are running, it's optional
+	JobLimiter *limiter.JobLimiter
+
+	// LogArchiver archives the full logs of the Terraform runs, it's optional
+	LogArchiver runlog.Archiver
+
+	// Diagnostics are the errors and warnings reported by Terraform in the failed run, which are kept in the status
+	Diagnostics []v1beta2.Diagnostic
+
+	// DeletionPolicy is what happens to the cloud resources when the Configuration is deleted, DeleteResource is true
+	// only if it's Delete
+	DeletionPolicy v1beta2.DeletionPolicy
+
+	// Imports are the existing cloud resources to import into the Terraform state before they're applied
+	Imports []v1beta2.TerraformRunImport
+
+	// Suspended stops creating or deleting the Terraform Jobs, and storing the changes of the Configuration which are
+	// applied once it's resumed
+	Suspended bool
+
+	// ApplyWindowClosed holds the changes and the apply Jobs to be created of the Configuration until NextApplyWindow,
+	// as they're out of its apply window
+	ApplyWindowClosed bool
+	NextApplyWindow   time.Time
+	// DestroyWindowClosed holds destroying the cloud resources until NextDestroyWindow
+	DestroyWindowClosed bool
+	NextDestroyWindow   time.Time
+
 	K8sClient client.Client
 }
 
@@ -76,11 +132,13 @@ type TFState struct {
 
 // TfStateProperty is the tf state property for an output
 type TfStateProperty struct {
-	Value interface{} `json:"value,omitempty"`
-	Type  interface{} `json:"type,omitempty"`
+	Value     interface{} `json:"value,omitempty"`
+	Type      interface{} `json:"type,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty"`
 }
 
-// ToProperty converts TfStateProperty type to Property
+// ToProperty converts TfStateProperty type to Property. The value of a sensitive output is kept, use Redact before
+// exposing it.
 func (tp *TfStateProperty) ToProperty() (v1beta2.Property, error) {
 	var (
 		property v1beta2.Property
@@ -91,7 +149,22 @@ func (tp *TfStateProperty) ToProperty() (v1beta2.Property, error) {
 		return property, errors.Wrapf(err, "failed to convert value %s of terraform state outputs to string", tp.Value)
 	}
 	property = v1beta2.Property{
-		Value: sv,
+		Value:     sv,
+		Sensitive: tp.Sensitive,
+	}
+	if tp.Type != nil {
+		// Primitive types are plain strings like `string`, and structured types are lists like `["list","string"]`
+		if property.Type, err = tfcfg.Interface2String(tp.Type); err != nil {
+			return property, errors.Wrapf(err, "failed to convert type %v of terraform state outputs to string", tp.Type)
+		}
 	}
 	return property, err
 }
+
+// Redact clears the value of a sensitive output
+func Redact(property v1beta2.Property) v1beta2.Property {
+	if property.Sensitive {
+		property.Value = ""
+	}
+	return property
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+const (
+	outputTargetKindSecret    = "Secret"
+	outputTargetKindConfigMap = "ConfigMap"
+)
+
+// AllowedOutputNamespacesOption sets the namespaces, besides the namespace of the Configuration, which the outputs can
+// be published to. `*` allows all namespaces.
+func AllowedOutputNamespacesOption(namespaces []string) Option {
+	return func(configuration v1beta2.Configuration, meta *TFConfigurationMeta) {
+		meta.AllowedOutputNamespaces = namespaces
+	}
+}
+
+func (meta *TFConfigurationMeta) isOutputNamespaceAllowed(configuration v1beta2.Configuration, namespace string) bool {
+	if namespace == configuration.Namespace {
+		return true
+	}
+	for _, ns := range meta.AllowedOutputNamespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// outputTargetLabels are the labels marking the owner of an output target
+func outputTargetLabels(configuration v1beta2.Configuration) map[string]string {
+	return map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        configuration.Name,
+		types.LabelOwnedNamespace: configuration.Namespace,
+		types.LabelOutputTarget:   "true",
+	}
+}
+
+// renderOutputTargetData selects the outputs published to the target
+func renderOutputTargetData(outputs map[string]v1beta2.Property, target v1beta2.OutputTarget) (map[string]string, error) {
+	data := make(map[string]string)
+	if len(target.Outputs) == 0 {
+		for k, v := range outputs {
+			if v.Sensitive && target.Kind == outputTargetKindConfigMap {
+				continue
+			}
+			data[k] = v.Value
+		}
+		return data, nil
+	}
+	for _, o := range target.Outputs {
+		v, ok := outputs[o.Name]
+		if !ok {
+			return nil, errors.Errorf("output %q is not found", o.Name)
+		}
+		if v.Sensitive && target.Kind == outputTargetKindConfigMap {
+			return nil, errors.Errorf("output %q is sensitive, and it could not be published to a ConfigMap", o.Name)
+		}
+		key := o.Key
+		if key == "" {
+			key = o.Name
+		}
+		data[key] = v.Value
+	}
+	return data, nil
+}
+
+// publishOutputTargets writes the outputs to spec.outputTargets, and deletes the targets no longer in the list
+func (meta *TFConfigurationMeta) publishOutputTargets(ctx context.Context, k8sClient client.Client, configuration v1beta2.Configuration, outputs map[string]v1beta2.Property) error {
+	published := make(map[string]bool, len(configuration.Spec.OutputTargets))
+	for _, target := range configuration.Spec.OutputTargets {
+		if target.Namespace == "" {
+			target.Namespace = configuration.Namespace
+		}
+		if !meta.isOutputNamespaceAllowed(configuration, target.Namespace) {
+			return errors.Errorf("publishing outputs to namespace %s is not allowed", target.Namespace)
+		}
+		data, err := renderOutputTargetData(outputs, target)
+		if err != nil {
+			return errors.Wrapf(err, "failed to publish outputs to %s %s/%s", target.Kind, target.Namespace, target.Name)
+		}
+		if err := publishOutputTarget(ctx, k8sClient, configuration, target, data); err != nil {
+			return err
+		}
+		published[outputTargetKey(target.Kind, target.Namespace, target.Name)] = true
+	}
+	return DeleteOutputTargets(ctx, k8sClient, configuration, published)
+}
+
+func outputTargetKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+func publishOutputTarget(ctx context.Context, k8sClient client.Client, configuration v1beta2.Configuration, target v1beta2.OutputTarget, data map[string]string) error {
+	var obj client.Object
+	switch target.Kind {
+	case outputTargetKindSecret:
+		obj = &v1.Secret{Data: toSecretData(data)}
+	case outputTargetKindConfigMap:
+		obj = &v1.ConfigMap{Data: data}
+	default:
+		return errors.Errorf("unsupported kind of output target: %s", target.Kind)
+	}
+
+	key := client.ObjectKey{Name: target.Name, Namespace: target.Namespace}
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := k8sClient.Get(ctx, key, existing); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		obj.SetName(target.Name)
+		obj.SetNamespace(target.Namespace)
+		obj.SetLabels(outputTargetLabels(configuration))
+		setOwnership(obj, configuration.Name, configuration.Namespace, configuration.UID)
+		return k8sClient.Create(ctx, obj)
+	}
+
+	labels := existing.GetLabels()
+	ownerName := labels[types.LabelOwnedBy]
+	ownerNamespace := labels[types.LabelOwnedNamespace]
+	if ownerName != configuration.Name || ownerNamespace != configuration.Namespace {
+		return errors.Errorf("configuration(namespace: %s ; name: %s) cannot update %s(namespace: %s ; name: %s) which is not created by it",
+			configuration.Namespace, configuration.Name, target.Kind, target.Namespace, target.Name)
+	}
+	setOwnership(existing, configuration.Name, configuration.Namespace, configuration.UID)
+	switch o := existing.(type) {
+	case *v1.Secret:
+		o.Data = toSecretData(data)
+	case *v1.ConfigMap:
+		o.Data = data
+	}
+	return k8sClient.Update(ctx, existing)
+}
+
+func toSecretData(data map[string]string) map[string][]byte {
+	secretData := make(map[string][]byte, len(data))
+	for k, v := range data {
+		secretData[k] = []byte(v)
+	}
+	return secretData
+}
+
+// DeleteOutputTargets deletes the Secrets and ConfigMaps published for spec.outputTargets of the Configuration, except
+// the ones in `keep`, which are keyed by `kind/namespace/name`
+func DeleteOutputTargets(ctx context.Context, k8sClient client.Client, configuration v1beta2.Configuration, keep map[string]bool) error {
+	selector := client.MatchingLabels(outputTargetLabels(configuration))
+
+	var secrets v1.SecretList
+	if err := k8sClient.List(ctx, &secrets, selector); err != nil {
+		return errors.Wrap(err, "failed to list the Secrets of output targets")
+	}
+	var configMaps v1.ConfigMapList
+	if err := k8sClient.List(ctx, &configMaps, selector); err != nil {
+		return errors.Wrap(err, "failed to list the ConfigMaps of output targets")
+	}
+
+	var stale []client.Object
+	for i := range secrets.Items {
+		s := &secrets.Items[i]
+		if !keep[outputTargetKey(outputTargetKindSecret, s.Namespace, s.Name)] {
+			stale = append(stale, s)
+		}
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if !keep[outputTargetKey(outputTargetKindConfigMap, cm.Namespace, cm.Name)] {
+			stale = append(stale, cm)
+		}
+	}
+	for _, obj := range stale {
+		klog.InfoS("Deleting the output target", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		if err := k8sClient.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
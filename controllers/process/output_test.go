@@ -0,0 +1,70 @@
+package process
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestPublishOutputTargets(t *testing.T) {
+	ctx := context.Background()
+	outputs := map[string]v1beta2.Property{
+		"vpc_id":   {Value: "vpc-123", Type: "string"},
+		"password": {Value: "p@ss", Type: "string", Sensitive: true},
+	}
+	configuration := v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "vpc", Namespace: "infra"},
+		Spec: v1beta2.ConfigurationSpec{
+			OutputTargets: []v1beta2.OutputTarget{
+				{Kind: "ConfigMap", Name: "vpc-outputs"},
+				{Kind: "Secret", Name: "vpc-password", Namespace: "app", Outputs: []v1beta2.ConnectionSecretOutput{{Name: "password", Key: "DB_PASSWORD"}}},
+			},
+		},
+	}
+	notOwned := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "not-owned", Namespace: "infra"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(notOwned).Build()
+	meta := &TFConfigurationMeta{}
+
+	err := meta.publishOutputTargets(ctx, k8sClient, configuration, outputs)
+	assert.EqualError(t, err, "publishing outputs to namespace app is not allowed")
+
+	meta.AllowedOutputNamespaces = []string{"app"}
+	assert.Nil(t, meta.publishOutputTargets(ctx, k8sClient, configuration, outputs))
+	var cm corev1.ConfigMap
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-outputs", Namespace: "infra"}, &cm))
+	assert.Equal(t, map[string]string{"vpc_id": "vpc-123"}, cm.Data, "sensitive outputs are not published to ConfigMaps")
+	assert.Equal(t, "vpc", cm.Labels["terraform.core.oam.dev/owned-by"])
+	var secret corev1.Secret
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-password", Namespace: "app"}, &secret))
+	assert.Equal(t, map[string][]byte{"DB_PASSWORD": []byte("p@ss")}, secret.Data)
+
+	// The target removed from the list is deleted
+	configuration.Spec.OutputTargets = configuration.Spec.OutputTargets[:1]
+	assert.Nil(t, meta.publishOutputTargets(ctx, k8sClient, configuration, outputs))
+	err = k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-password", Namespace: "app"}, &secret)
+	assert.True(t, kerrors.IsNotFound(err))
+
+	configuration.Spec.OutputTargets = []v1beta2.OutputTarget{
+		{Kind: "ConfigMap", Name: "vpc-outputs", Outputs: []v1beta2.ConnectionSecretOutput{{Name: "password"}}},
+	}
+	err = meta.publishOutputTargets(ctx, k8sClient, configuration, outputs)
+	assert.EqualError(t, err, `failed to publish outputs to ConfigMap infra/vpc-outputs: output "password" is sensitive, and it could not be published to a ConfigMap`)
+
+	configuration.Spec.OutputTargets = []v1beta2.OutputTarget{{Kind: "ConfigMap", Name: "not-owned"}}
+	err = meta.publishOutputTargets(ctx, k8sClient, configuration, outputs)
+	assert.EqualError(t, err, "configuration(namespace: infra ; name: vpc) cannot update ConfigMap(namespace: infra ; name: not-owned) which is not created by it")
+
+	// All the targets are deleted along with the Configuration
+	assert.Nil(t, DeleteOutputTargets(ctx, k8sClient, configuration, nil))
+	err = k8sClient.Get(ctx, client.ObjectKey{Name: "vpc-outputs", Namespace: "infra"}, &cm)
+	assert.True(t, kerrors.IsNotFound(err))
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "not-owned", Namespace: "infra"}, &cm))
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// SubResourceLabels are the labels of the sub-resources of the Configuration, like the Jobs, the ConfigMap of the
+// HCL and the Secrets of the variables and the outputs
+func (meta *TFConfigurationMeta) SubResourceLabels() map[string]string {
+	return subResourceLabels(meta.Name, meta.Namespace, meta.UID)
+}
+
+// SetOwnership labels the sub-resource with the Configuration. The Configuration is also set as an owner of the
+// sub-resource if they are in the same namespace, as owner references can't cross namespaces.
+func (meta *TFConfigurationMeta) SetOwnership(obj metav1.Object) {
+	setOwnership(obj, meta.Name, meta.Namespace, meta.UID)
+}
+
+func subResourceLabels(name, namespace string, uid k8stypes.UID) map[string]string {
+	labels := map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        name,
+		types.LabelOwnedNamespace: namespace,
+	}
+	if uid != "" {
+		labels[types.LabelOwnedUID] = string(uid)
+	}
+	return labels
+}
+
+func setOwnership(obj metav1.Object, name, namespace string, uid k8stypes.UID) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range subResourceLabels(name, namespace, uid) {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+
+	if uid == "" || obj.GetNamespace() != namespace {
+		return
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.UID == uid {
+			return
+		}
+	}
+	obj.SetOwnerReferences(append(obj.GetOwnerReferences(), metav1.OwnerReference{
+		APIVersion: v1beta2.GroupVersion.String(),
+		Kind:       "Configuration",
+		Name:       name,
+		UID:        uid,
+	}))
+}
+
+// DeleteSubResources deletes the Jobs, Secrets and ConfigMaps labeled with the Configuration in all namespaces, so
+// the sub-resources are found even if the controller namespace has changed since they were created
+func (meta *TFConfigurationMeta) DeleteSubResources(ctx context.Context, k8sClient client.Client) error {
+	selector := client.MatchingLabels{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        meta.Name,
+		types.LabelOwnedNamespace: meta.Namespace,
+	}
+
+	var jobs batchv1.JobList
+	if err := k8sClient.List(ctx, &jobs, selector); err != nil {
+		return errors.Wrap(err, "failed to list the Jobs of the Configuration")
+	}
+	var secrets v1.SecretList
+	if err := k8sClient.List(ctx, &secrets, selector); err != nil {
+		return errors.Wrap(err, "failed to list the Secrets of the Configuration")
+	}
+	var configMaps v1.ConfigMapList
+	if err := k8sClient.List(ctx, &configMaps, selector); err != nil {
+		return errors.Wrap(err, "failed to list the ConfigMaps of the Configuration")
+	}
+
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		klog.InfoS("Deleting the job", "Namespace", job.Namespace, "Name", job.Name)
+		if err := k8sClient.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	var objects []client.Object
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	for _, obj := range objects {
+		klog.InfoS("Deleting the sub-resource", "Namespace", obj.GetNamespace(), "Name", obj.GetName())
+		if err := k8sClient.Delete(ctx, obj); err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
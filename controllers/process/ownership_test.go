@@ -0,0 +1,73 @@
+package process
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestSetOwnership(t *testing.T) {
+	meta := &TFConfigurationMeta{Name: "a", Namespace: "default", UID: "uid-a"}
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-a", Namespace: "default", Labels: map[string]string{"app": "a"}}}
+	meta.SetOwnership(cm)
+	meta.SetOwnership(cm)
+	assert.Equal(t, map[string]string{
+		"app":                     "a",
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "a",
+		types.LabelOwnedNamespace: "default",
+		types.LabelOwnedUID:       "uid-a",
+	}, cm.Labels)
+	assert.Equal(t, []metav1.OwnerReference{{
+		APIVersion: "terraform.core.oam.dev/v1beta2",
+		Kind:       "Configuration",
+		Name:       "a",
+		UID:        "uid-a",
+	}}, cm.OwnerReferences)
+
+	// Owner references can't cross namespaces
+	job := meta.assembleTerraformJob(types.TerraformApply)
+	job.Namespace = "vela-system"
+	job.OwnerReferences = nil
+	meta.SetOwnership(job)
+	assert.Empty(t, job.OwnerReferences)
+	assert.Equal(t, "a", job.Labels[types.LabelOwnedBy])
+	assert.Equal(t, "a", job.Spec.Template.Labels[types.LabelOwnedBy])
+}
+
+func TestDeleteSubResources(t *testing.T) {
+	ctx := context.Background()
+	meta := &TFConfigurationMeta{Name: "a", Namespace: "default", UID: "uid-a"}
+	labels := map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "a",
+		types.LabelOwnedNamespace: "default",
+	}
+	// The sub-resources were created in the former controller namespace
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "old-ns", Labels: labels}}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "variable-a", Namespace: "old-ns", Labels: labels}}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-a", Namespace: "default", Labels: labels}}
+	others := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tf-b", Namespace: "default", Labels: map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "b",
+		types.LabelOwnedNamespace: "default",
+	}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(job, secret, cm, others).Build()
+
+	assert.Nil(t, meta.DeleteSubResources(ctx, k8sClient))
+	for _, obj := range []client.Object{job, secret, cm} {
+		err := k8sClient.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		assert.True(t, kerrors.IsNotFound(err), obj.GetName())
+	}
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(others), others))
+}
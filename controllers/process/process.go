@@ -1,11 +1,15 @@
 package process
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/oam-dev/terraform-controller/controllers/process/container"
 
@@ -24,6 +28,7 @@ import (
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
 	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
 	"github.com/oam-dev/terraform-controller/controllers/provider"
 	"github.com/oam-dev/terraform-controller/controllers/util"
 )
@@ -49,12 +54,20 @@ func ControllerNamespaceOption(controllerNamespace string) Option {
 	}
 }
 
+// JobLimiterOption sets the limiter which queues the Terraform apply Jobs when too many Terraform Jobs are running
+func JobLimiterOption(jobLimiter *limiter.JobLimiter) Option {
+	return func(configuration v1beta2.Configuration, meta *TFConfigurationMeta) {
+		meta.JobLimiter = jobLimiter
+	}
+}
+
 // New will create a new TFConfigurationMeta to process the configuration
 func New(req ctrl.Request, configuration v1beta2.Configuration, k8sClient client.Client, option ...Option) *TFConfigurationMeta {
 	var meta = &TFConfigurationMeta{
 		ControllerNamespace: req.Namespace,
 		Namespace:           req.Namespace,
 		Name:                req.Name,
+		UID:                 configuration.UID,
 		ConfigurationCMName: fmt.Sprintf(types.TFInputConfigMapName, req.Name),
 		VariableSecretName:  fmt.Sprintf(types.TFVariableSecret, req.Name),
 		ApplyJobName:        req.Name + "-" + string(types.TerraformApply),
@@ -82,11 +95,30 @@ func New(req ctrl.Request, configuration v1beta2.Configuration, k8sClient client
 	} else {
 		meta.Git.Path = configuration.Spec.Path
 	}
-	if configuration.Spec.DeleteResource != nil {
-		meta.DeleteResource = *configuration.Spec.DeleteResource
-	} else {
-		meta.DeleteResource = true
+	if opts := configuration.Spec.GitOptions; opts != nil {
+		meta.Git.Shallow = opts.Shallow
+		meta.Git.SparseCheckout = opts.SparseCheckout
+		meta.Git.SparsePaths = opts.SparsePaths
+		meta.Git.Submodules = opts.Submodules
+	}
+	meta.Imports = configuration.Spec.Imports
+	meta.PendingTimeout = DefaultPendingTimeout
+	meta.RetryToken = configuration.Annotations[types.AnnotationRetry]
+	if policy := configuration.Spec.JobPolicy; policy != nil {
+		meta.ActiveDeadlineSeconds = policy.ActiveDeadlineSeconds
+		if policy.PendingTimeoutSeconds != nil {
+			meta.PendingTimeout = time.Duration(*policy.PendingTimeoutSeconds) * time.Second
+		}
 	}
+	switch {
+	case configuration.Spec.DeletionPolicy != "":
+		meta.DeletionPolicy = configuration.Spec.DeletionPolicy
+	case configuration.Spec.DeleteResource != nil && !*configuration.Spec.DeleteResource:
+		meta.DeletionPolicy = v1beta2.DeletionPolicyOrphan
+	default:
+		meta.DeletionPolicy = v1beta2.DeletionPolicyDelete
+	}
+	meta.DeleteResource = meta.DeletionPolicy == v1beta2.DeletionPolicyDelete
 
 	if !configuration.Spec.InlineCredentials {
 		meta.ProviderReference = tfcfg.GetProviderNamespacedName(configuration)
@@ -191,9 +223,10 @@ func (meta *TFConfigurationMeta) UpdateApplyStatus(ctx context.Context, k8sClien
 	var configuration v1beta2.Configuration
 	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.Name, Namespace: meta.Namespace}, &configuration); err == nil {
 		configuration.Status.Apply = v1beta2.ConfigurationApplyStatus{
-			State:   state,
-			Message: message,
-			Region:  meta.Region,
+			State:       state,
+			Message:     message,
+			Region:      meta.Region,
+			Diagnostics: meta.Diagnostics,
 		}
 		configuration.Status.ObservedGeneration = configuration.Generation
 		if state == types.Available {
@@ -208,8 +241,13 @@ func (meta *TFConfigurationMeta) UpdateApplyStatus(ctx context.Context, k8sClien
 				configuration.Status.Apply.Outputs = outputs
 			}
 		}
+		tfcfg.SetConditions(&configuration, configuration.Status.Apply.State, configuration.Status.Apply.Message)
+		configuration.Status.Imports = meta.importStatuses(ctx, configuration, configuration.Status.Apply.State, configuration.Status.Apply.Message)
 
-		return k8sClient.Status().Update(ctx, &configuration)
+		if err := k8sClient.Status().Update(ctx, &configuration); err != nil {
+			return err
+		}
+		return nil
 	}
 	return nil
 }
@@ -218,14 +256,34 @@ func (meta *TFConfigurationMeta) UpdateDestroyStatus(ctx context.Context, k8sCli
 	var configuration v1beta2.Configuration
 	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.Name, Namespace: meta.Namespace}, &configuration); err == nil {
 		configuration.Status.Destroy = v1beta2.ConfigurationDestroyStatus{
-			State:   state,
-			Message: message,
+			State:       state,
+			Message:     message,
+			Diagnostics: meta.Diagnostics,
 		}
+		tfcfg.SetConditions(&configuration, state, message)
 		return k8sClient.Status().Update(ctx, &configuration)
 	}
 	return nil
 }
 
+// UpdateSuspendedCondition updates the Suspended condition of the Configuration, the reason is types.ReasonResumed if
+// it isn't suspended
+func (meta *TFConfigurationMeta) UpdateSuspendedCondition(ctx context.Context, k8sClient client.Client, reason, message string) error {
+	var configuration v1beta2.Configuration
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: meta.Name, Namespace: meta.Namespace}, &configuration); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !tfcfg.SetSuspendedCondition(&configuration, reason, message) {
+		return nil
+	}
+	return k8sClient.Status().Update(ctx, &configuration)
+}
+
+// ChangesHeld tells whether the changes of the Configuration are held back, as it's suspended or out of its apply window
+func (meta *TFConfigurationMeta) ChangesHeld() bool {
+	return meta.Suspended || meta.ApplyWindowClosed
+}
+
 func (meta *TFConfigurationMeta) AssembleAndTriggerJob(ctx context.Context, k8sClient client.Client, executionType types.TerraformExecutionType) error {
 	// apply rbac
 	if err := createTerraformExecutorServiceAccount(ctx, k8sClient, meta.ControllerNamespace, types.ServiceAccountName); err != nil {
@@ -236,8 +294,31 @@ func (meta *TFConfigurationMeta) AssembleAndTriggerJob(ctx context.Context, k8sC
 	}
 
 	job := meta.assembleTerraformJob(executionType)
+	start := metav1.Now()
+	setRunLabel(job, RunName(meta.Name, executionType, start))
+	// Destroying is never queued, so that the cloud resources can always be cleaned up
+	if executionType == types.TerraformDestroy {
+		if err := k8sClient.Create(ctx, job); err != nil {
+			return err
+		}
+	} else {
+		queued, err := meta.JobLimiter.Create(ctx, k8sClient, job)
+		if err != nil {
+			return err
+		}
+		if queued != "" {
+			klog.InfoS("Terraform Job is queued", "Name", job.Name, "Reason", queued)
+			if err := meta.UpdateApplyStatus(ctx, k8sClient, types.Queued, queued); err != nil {
+				return err
+			}
+			return errors.New(types.MessageJobQueued)
+		}
+	}
 
-	return k8sClient.Create(ctx, job)
+	if err := meta.StartRun(ctx, k8sClient, executionType, job, start); err != nil {
+		klog.InfoS("Failed to record the Terraform run", "Name", meta.Name, "Namespace", meta.Namespace, "Job", job.Name, "error", err)
+	}
+	return nil
 }
 
 // UpdateTerraformJobIfNeeded will set deletion finalizer to the Terraform job if its envs are changed, which will result in
@@ -280,13 +361,20 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType types.Terraf
 		SetBusyboxImage(meta.BusyboxImage).
 		SetTerraformImage(meta.TerraformImage).
 		SetGitImage(meta.GitImage).
-		SetEnvs(meta.Envs)
+		SetTerraformVariables(meta.hasTerraformVariables()).
+		SetJSONOutput(JSONOutputSupported(meta.TerraformImage)).
+		SetEnvs(meta.Envs).
+		SetImports(meta.Imports)
 
 	initContainers = append(initContainers, assembler.InputContainer())
 	if meta.Git.URL != "" {
 		initContainers = append(initContainers, assembler.GitContainer())
 	}
 	initContainers = append(initContainers, assembler.InitContainer())
+	// The Terraform versions which support import blocks import the resources by the blocks in the configuration
+	if executionType == types.TerraformApply && len(meta.Imports) > 0 && !ImportBlocksSupported(meta.TerraformImage) {
+		initContainers = append(initContainers, assembler.ImportContainer())
+	}
 
 	applyContainer := assembler.ApplyContainer(executionType, meta.ResourceQuota)
 
@@ -295,7 +383,7 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType types.Terraf
 		name = meta.DestroyJobName
 	}
 
-	return &batchv1.Job{
+	job := &batchv1.Job{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Job",
 			APIVersion: "batch/v1",
@@ -308,8 +396,12 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType types.Terraf
 			Parallelism:  &parallelism,
 			Completions:  &completions,
 			BackoffLimit: &meta.BackoffLimit,
+			// The deadline covers the retries, then the Job is failed with the reason DeadlineExceeded
+			ActiveDeadlineSeconds: meta.ActiveDeadlineSeconds,
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
+					// The labels are also set to the pods, so that the controller can watch both of them
+					Labels: meta.SubResourceLabels(),
 					Annotations: map[string]string{
 						// This annotation will prevent istio-proxy sidecar injection in the pods
 						// as having the sidecar would have kept the Job in `Running` state and would
@@ -326,12 +418,23 @@ func (meta *TFConfigurationMeta) assembleTerraformJob(executionType types.Terraf
 					Containers:         []v1.Container{applyContainer},
 					ServiceAccountName: types.ServiceAccountName,
 					Volumes:            executorVolumes,
-					RestartPolicy:      v1.RestartPolicyOnFailure,
-					NodeSelector:       meta.JobNodeSelector,
+					// A failed pod is kept and retried by a new pod, so that its logs are still there after the
+					// retries are exhausted
+					RestartPolicy: v1.RestartPolicyNever,
+					NodeSelector:  meta.JobNodeSelector,
 				},
 			},
 		},
 	}
+	meta.SetOwnership(job)
+	if meta.RetryToken != "" {
+		job.Annotations = map[string]string{types.AnnotationRetry: meta.RetryToken}
+	}
+	if meta.ProviderReference != nil {
+		job.Labels[types.LabelProviderName] = meta.ProviderReference.Name
+		job.Labels[types.LabelProviderNamespace] = meta.ProviderReference.Namespace
+	}
+	return job
 }
 
 func (meta *TFConfigurationMeta) assembleExecutorVolumes() []v1.Volume {
@@ -371,9 +474,29 @@ func (meta *TFConfigurationMeta) assembleExecutorVolumes() []v1.Volume {
 			executorVolumes = append(executorVolumes, meta.createSecretOrConfigMapVolume(ref.isSecret, ref.ref.Name, ref.volumeName))
 		}
 	}
+	if meta.hasTerraformVariables() {
+		executorVolumes = append(executorVolumes, meta.createTFVariablesVolume())
+	}
 	return executorVolumes
 }
 
+func (meta *TFConfigurationMeta) hasTerraformVariables() bool {
+	_, ok := meta.VariableSecretData[types.TerraformVariablesFileName]
+	return ok
+}
+
+// createTFVariablesVolume only projects the variables file of the variable Secret, credentials in it are still
+// consumed as environment variables
+func (meta *TFConfigurationMeta) createTFVariablesVolume() v1.Volume {
+	volumeSource := v1.SecretVolumeSource{
+		SecretName: meta.VariableSecretName,
+		Items: []v1.KeyToPath{
+			{Key: types.TerraformVariablesFileName, Path: types.TerraformVariablesFileName},
+		},
+	}
+	return v1.Volume{Name: types.VariableVolumeName, VolumeSource: v1.VolumeSource{Secret: &volumeSource}}
+}
+
 func (meta *TFConfigurationMeta) createConfigurationVolume() v1.Volume {
 	inputCMVolumeSource := v1.ConfigMapVolumeSource{}
 	inputCMVolumeSource.Name = meta.ConfigurationCMName
@@ -431,13 +554,18 @@ func (meta *TFConfigurationMeta) RenderConfiguration(configuration *v1beta2.Conf
 		return "", nil, errors.Wrap(err, "failed to prepare Terraform backend configuration")
 	}
 
+	var imports string
+	if len(configuration.Spec.Imports) > 0 && ImportBlocksSupported(meta.TerraformImage) {
+		imports = "\n" + renderImportBlocks(configuration.Spec.Imports)
+	}
+
 	switch configurationType {
 	case types.ConfigurationHCL:
 		completedConfiguration := configuration.Spec.HCL
 		completedConfiguration += "\n" + backendInterface.HCL()
-		return completedConfiguration, backendInterface, nil
+		return completedConfiguration + imports, backendInterface, nil
 	case types.ConfigurationRemote:
-		return backendInterface.HCL(), backendInterface, nil
+		return backendInterface.HCL() + imports, backendInterface, nil
 	default:
 		return "", nil, errors.New("Unsupported Configuration Type")
 	}
@@ -472,13 +600,21 @@ func (meta *TFConfigurationMeta) getTFOutputs(ctx context.Context, k8sClient cli
 	for k, v := range tfState.Outputs {
 		property, err := v.ToProperty()
 		if err != nil {
-			return outputs, err
+			return nil, err
 		}
 		outputs[k] = property
 	}
+	// Sensitive values are only written to the connection Secret, and redacted from the status
+	redacted := make(map[string]v1beta2.Property, len(outputs))
+	for k, v := range outputs {
+		redacted[k] = Redact(v)
+	}
+	if err := meta.publishOutputTargets(ctx, k8sClient, configuration, outputs); err != nil {
+		return nil, err
+	}
 	writeConnectionSecretToReference := configuration.Spec.WriteConnectionSecretToReference
 	if writeConnectionSecretToReference == nil || writeConnectionSecretToReference.Name == "" {
-		return outputs, nil
+		return redacted, nil
 	}
 
 	name := writeConnectionSecretToReference.Name
@@ -486,9 +622,14 @@ func (meta *TFConfigurationMeta) getTFOutputs(ctx context.Context, k8sClient cli
 	if ns == "" {
 		ns = types.DefaultNamespace
 	}
-	data := make(map[string][]byte)
-	for k, v := range outputs {
-		data[k] = []byte(v.Value)
+	connectionSecret := configuration.Spec.ConnectionSecret
+	data, err := tfcfg.RenderConnectionSecretData(outputs, connectionSecret)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render the connection secret")
+	}
+	var secretType v1.SecretType
+	if connectionSecret != nil {
+		secretType = connectionSecret.Type
 	}
 	var gotSecret v1.Secret
 	configurationName := configuration.ObjectMeta.Name
@@ -498,15 +639,13 @@ func (meta *TFConfigurationMeta) getTFOutputs(ctx context.Context, k8sClient cli
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      name,
 					Namespace: ns,
-					Labels: map[string]string{
-						"terraform.core.oam.dev/created-by":      "terraform-controller",
-						"terraform.core.oam.dev/owned-by":        configurationName,
-						"terraform.core.oam.dev/owned-namespace": configuration.Namespace,
-					},
 				},
 				TypeMeta: metav1.TypeMeta{Kind: "Secret"},
+				Type:     secretType,
 				Data:     data,
 			}
+			setOwnership(&secret, configurationName, configuration.Namespace, configuration.UID)
+			setConnectionSecretMetadata(&secret, connectionSecret)
 			err = k8sClient.Create(ctx, &secret)
 			if kerrors.IsAlreadyExists(err) {
 				return nil, fmt.Errorf("secret(%s) already exists", name)
@@ -517,8 +656,8 @@ func (meta *TFConfigurationMeta) getTFOutputs(ctx context.Context, k8sClient cli
 	} else {
 		// check the owner of this secret
 		labels := gotSecret.ObjectMeta.Labels
-		ownerName := labels["terraform.core.oam.dev/owned-by"]
-		ownerNamespace := labels["terraform.core.oam.dev/owned-namespace"]
+		ownerName := labels[types.LabelOwnedBy]
+		ownerNamespace := labels[types.LabelOwnedNamespace]
 		if (ownerName != "" && ownerName != configurationName) ||
 			(ownerNamespace != "" && ownerNamespace != configuration.Namespace) {
 			errMsg := fmt.Sprintf(
@@ -528,14 +667,82 @@ func (meta *TFConfigurationMeta) getTFOutputs(ctx context.Context, k8sClient cli
 				ownerNamespace, ownerName,
 			)
 			klog.ErrorS(err, "fail to update backend secret")
+			meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonConnectionSecretConflict, "%s", errMsg)
 			return nil, errors.New(errMsg)
 		}
 		gotSecret.Data = data
-		if err := k8sClient.Update(ctx, &gotSecret); err != nil {
+		setOwnership(&gotSecret, configurationName, configuration.Namespace, configuration.UID)
+		setConnectionSecretMetadata(&gotSecret, connectionSecret)
+		if secretType != "" && gotSecret.Type != secretType {
+			// The type of a Secret is immutable, so it has to be recreated. The data is validated for the new type
+			// by a dry run first, as the old Secret would be gone if the new one is rejected.
+			gotSecret.ResourceVersion = ""
+			gotSecret.Type = secretType
+			candidate := gotSecret.DeepCopy()
+			candidate.Name = ""
+			candidate.GenerateName = name + "-"
+			if err := k8sClient.Create(ctx, candidate, client.DryRunAll); err != nil {
+				msg := fmt.Sprintf("the connection secret(%s) could not be changed to type %s: %s", name, secretType, err.Error())
+				meta.RecordEvent(&configuration, v1.EventTypeWarning, types.EventReasonInvalidConnectionSecret, "%s", msg)
+				return nil, errors.New(msg)
+			}
+			if err := k8sClient.Delete(ctx, &gotSecret); err != nil && !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+			if err := k8sClient.Create(ctx, &gotSecret); err != nil {
+				return nil, err
+			}
+		} else if err := k8sClient.Update(ctx, &gotSecret); err != nil {
 			return nil, err
 		}
 	}
-	return outputs, nil
+	return redacted, nil
+}
+
+// setConnectionSecretMetadata adds the labels and annotations in spec.connectionSecret to the Secret. The labels added
+// before are recorded in AnnotationConnectionSecretLabels, so that the ones removed from spec.connectionSecret are
+// removed from the Secret as well.
+func setConnectionSecretMetadata(secret *v1.Secret, connectionSecret *v1beta2.ConnectionSecret) {
+	var labels map[string]string
+	if connectionSecret != nil {
+		labels = connectionSecret.Labels
+	}
+	if previous := secret.Annotations[types.AnnotationConnectionSecretLabels]; previous != "" {
+		for _, k := range strings.Split(previous, ",") {
+			if _, ok := labels[k]; !ok && !strings.HasPrefix(k, "terraform.core.oam.dev/") {
+				delete(secret.Labels, k)
+			}
+		}
+		delete(secret.Annotations, types.AnnotationConnectionSecretLabels)
+	}
+	if connectionSecret == nil {
+		return
+	}
+	var added []string
+	for k, v := range labels {
+		if secret.Labels == nil {
+			secret.Labels = make(map[string]string)
+		}
+		// The labels marking the owner can't be overridden
+		if _, ok := secret.Labels[k]; ok && strings.HasPrefix(k, "terraform.core.oam.dev/") {
+			continue
+		}
+		secret.Labels[k] = v
+		added = append(added, k)
+	}
+	if len(added) != 0 {
+		sort.Strings(added)
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[types.AnnotationConnectionSecretLabels] = strings.Join(added, ",")
+	}
+	for k, v := range connectionSecret.Annotations {
+		if secret.Annotations == nil {
+			secret.Annotations = make(map[string]string)
+		}
+		secret.Annotations[k] = v
+	}
 }
 
 func (meta *TFConfigurationMeta) PrepareTFVariables(configuration *v1beta2.Configuration) error {
@@ -551,16 +758,22 @@ func (meta *TFConfigurationMeta) PrepareTFVariables(configuration *v1beta2.Confi
 		return errors.New("The referenced provider could not be retrieved")
 	}
 
-	tfVariable, err := getTerraformJSONVariable(configuration.Spec.Variable)
+	// Variables are rendered to terraform.tfvars.json to keep their types, while credentials and JobEnv are still
+	// passed as environment variables
+	tfVariable, err := getTerraformJSONVariable(meta.ReferencedVariables, configuration.Spec.Variable)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("failed to get Terraform JSON variables from Configuration Variables %v", configuration.Spec.Variable))
 	}
-	for k, v := range tfVariable {
-		envValue, err := tfcfg.Interface2String(v)
+	for k, v := range meta.OutputVariables {
+		tfVariable[k] = v
+	}
+	meta.TFVariables = tfVariable
+	if len(tfVariable) != 0 {
+		tfVarsJSON, err := json.Marshal(tfVariable)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "failed to render Terraform variables file")
 		}
-		data[k] = []byte(envValue)
+		data[types.TerraformVariablesFileName] = tfVarsJSON
 	}
 
 	if !configuration.Spec.InlineCredentials && meta.Credentials == nil {
@@ -577,6 +790,9 @@ func (meta *TFConfigurationMeta) PrepareTFVariables(configuration *v1beta2.Confi
 		data[k] = []byte(envValue)
 	}
 	for k := range data {
+		if k == types.TerraformVariablesFileName {
+			continue
+		}
 		valueFrom := &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{Key: k}}
 		valueFrom.SecretKeyRef.Name = meta.VariableSecretName
 		envs = append(envs, v1.EnvVar{Name: k, ValueFrom: valueFrom})
@@ -587,6 +803,295 @@ func (meta *TFConfigurationMeta) PrepareTFVariables(configuration *v1beta2.Confi
 	return nil
 }
 
+// MigrateLegacyVariableSecret rewrites the variable Secret rendered before the variables were passed by
+// terraform.tfvars.json, when every variable was passed by a TF_VAR_ environment. If the legacy Secret holds the same
+// variables, it's rewritten in place, so that upgrading the controller doesn't re-apply the Configuration. Otherwise, the
+// changed variables re-apply the Configuration as usual.
+func (meta *TFConfigurationMeta) MigrateLegacyVariableSecret(ctx context.Context, k8sClient client.Client, secret *v1.Secret) error {
+	if _, ok := secret.Data[types.TerraformVariablesFileName]; ok || len(meta.TFVariables) == 0 {
+		return nil
+	}
+	legacy := map[string][]byte{}
+	for k, v := range meta.TFVariables {
+		value, err := tfcfg.Interface2String(v)
+		if err != nil {
+			return err
+		}
+		legacy["TF_VAR_"+k] = []byte(value)
+	}
+	// credentials and JobEnv took precedence over the variables
+	for k, v := range meta.VariableSecretData {
+		if k != types.TerraformVariablesFileName {
+			legacy[k] = v
+		}
+	}
+	if len(legacy) != len(secret.Data) {
+		return nil
+	}
+	for k, v := range legacy {
+		if val, ok := secret.Data[k]; !ok || !bytes.Equal(v, val) {
+			return nil
+		}
+	}
+
+	klog.InfoS("Rewriting the legacy variable Secret", "Name", secret.Name, "Namespace", secret.Namespace)
+	secret.Data = meta.VariableSecretData
+	return k8sClient.Update(ctx, secret)
+}
+
+// ValidateVariables will check the variables against the `variable` blocks of the inline HCL. The module of a remote
+// git repository is only available in the Job, so it's not checked. The undeclared variables are only reported by a
+// Warning Event, as Terraform ignores them.
+func (meta *TFConfigurationMeta) ValidateVariables(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration) error {
+	if meta.ConfigurationType != types.ConfigurationHCL {
+		return nil
+	}
+	// variables could also be set by environments like TF_VAR_xxx in JobEnv or credentials
+	var envVariables []string
+	for k := range meta.VariableSecretData {
+		if strings.HasPrefix(k, "TF_VAR_") {
+			envVariables = append(envVariables, strings.TrimPrefix(k, "TF_VAR_"))
+		}
+	}
+	undeclared, err := tfcfg.ValidateVariables(configuration.Spec.HCL, meta.TFVariables, envVariables)
+	if len(undeclared) != 0 {
+		meta.RecordEvent(configuration, v1.EventTypeWarning, types.EventReasonUndeclaredVariables,
+			"Variables %s are not declared in the module, they're ignored by Terraform", strings.Join(undeclared, ", "))
+	}
+	if err != nil {
+		msg := fmt.Sprintf("spec.variable is not valid: %s", err.Error())
+		if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+			return errors.Wrap(updateStatusErr, msg)
+		}
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// AllowedDependencyNamespacesOption sets the namespaces, besides the namespace of the Configuration, which the
+// Configurations depended on can be in. `*` allows all namespaces.
+func AllowedDependencyNamespacesOption(namespaces []string) Option {
+	return func(configuration v1beta2.Configuration, meta *TFConfigurationMeta) {
+		meta.AllowedDependencyNamespaces = namespaces
+	}
+}
+
+func (meta *TFConfigurationMeta) isDependencyNamespaceAllowed(configuration *v1beta2.Configuration, namespace string) bool {
+	if namespace == configuration.Namespace {
+		return true
+	}
+	for _, ns := range meta.AllowedDependencyNamespaces {
+		if ns == "*" || ns == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveDependencies will check whether the Configurations depended on are Available, and resolve the variables which
+// reference their outputs. When the Configuration is being deleted, it doesn't wait for the dependencies, and resolves
+// the outputs which are still available, so that the destroy Job gets the same variables as the apply one.
+func (meta *TFConfigurationMeta) ResolveDependencies(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration) error {
+	isDeleting := !configuration.DeletionTimestamp.IsZero()
+	dependencies, err := tfcfg.GetDependencies(configuration)
+	if err != nil {
+		msg := fmt.Sprintf("spec.dependsOn or spec.variable is not valid: %s", err.Error())
+		if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+			return errors.Wrap(updateStatusErr, msg)
+		}
+		return errors.New(msg)
+	}
+	// The outputs and the connection Secrets of the Configurations in other namespaces could only be read if allowed
+	for _, dep := range dependencies {
+		if meta.isDependencyNamespaceAllowed(configuration, dep.Namespace) {
+			continue
+		}
+		msg := fmt.Sprintf("depending on Configuration %s is not allowed, as namespace %s is not allowed by the controller",
+			tfcfg.DependencyKey(dep), dep.Namespace)
+		if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+			return errors.Wrap(updateStatusErr, msg)
+		}
+		return errors.New(msg)
+	}
+
+	// A circular dependency would wait for itself forever, so it's reported instead. It doesn't block the deletion.
+	if !isDeleting && len(dependencies) != 0 {
+		cycle, err := tfcfg.FindDependencyCycle(ctx, k8sClient, configuration)
+		if err != nil {
+			return err
+		}
+		if cycle != "" {
+			msg := fmt.Sprintf("there is a circular dependency among Configurations: %s", cycle)
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+				return errors.Wrap(updateStatusErr, msg)
+			}
+			return errors.New(msg)
+		}
+	}
+
+	var (
+		notReady []string
+		resolved = make(map[v1beta2.ConfigurationReference]*v1beta2.Configuration)
+	)
+	for _, dep := range dependencies {
+		var c v1beta2.Configuration
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: dep.Name, Namespace: dep.Namespace}, &c); err != nil {
+			if !kerrors.IsNotFound(err) {
+				return err
+			}
+			notReady = append(notReady, fmt.Sprintf("%s (not found)", tfcfg.DependencyKey(dep)))
+			continue
+		}
+		resolved[dep] = &c
+		if c.Status.Apply.State != types.Available || !c.DeletionTimestamp.IsZero() {
+			notReady = append(notReady, fmt.Sprintf("%s (%s)", tfcfg.DependencyKey(dep), c.Status.Apply.State))
+		}
+	}
+	if len(notReady) != 0 && !isDeleting {
+		msg := fmt.Sprintf("%s: %s", types.MessageWaitingForDependencies, strings.Join(notReady, ", "))
+		if err := meta.UpdateApplyStatus(ctx, k8sClient, types.WaitingForDependencies, msg); err != nil {
+			return err
+		}
+		return errors.New(types.MessageWaitingForDependencies)
+	}
+
+	references, err := tfcfg.GetOutputReferences(configuration)
+	if err != nil {
+		return err
+	}
+	variables := make(map[string]interface{}, len(references))
+	for name, ref := range references {
+		var (
+			property v1beta2.Property
+			ok       bool
+		)
+		if c := resolved[ref.ConfigurationReference]; c != nil {
+			property, ok = c.Status.Apply.Outputs[ref.Key]
+		}
+		if !ok {
+			if isDeleting {
+				continue
+			}
+			msg := fmt.Sprintf("output %q is not found in Configuration %s, which is referenced by variable %q",
+				ref.Key, tfcfg.DependencyKey(ref.ConfigurationReference), name)
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+				return errors.Wrap(updateStatusErr, msg)
+			}
+			return errors.New(msg)
+		}
+		value := property.Value
+		if property.Sensitive {
+			if value, err = getSensitiveOutput(ctx, k8sClient, resolved[ref.ConfigurationReference], ref.Key); err != nil {
+				if isDeleting {
+					continue
+				}
+				msg := fmt.Sprintf("sensitive output %q of Configuration %s, which is referenced by variable %q, could not be read: %s",
+					ref.Key, tfcfg.DependencyKey(ref.ConfigurationReference), name, err.Error())
+				if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.ConfigurationStaticCheckFailed, msg); updateStatusErr != nil {
+					return errors.Wrap(updateStatusErr, msg)
+				}
+				return errors.New(msg)
+			}
+		}
+		variables[name] = decodeVariableValue(value)
+	}
+	meta.OutputVariables = variables
+	return nil
+}
+
+// getSensitiveOutput reads a sensitive output from the connection Secret of the Configuration, as it's redacted from
+// the status. The key of the output in the Secret follows spec.connectionSecret.outputs.
+func getSensitiveOutput(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration, output string) (string, error) {
+	ref := configuration.Spec.WriteConnectionSecretToReference
+	if ref == nil || ref.Name == "" {
+		return "", errors.New("spec.writeConnectionSecretToRef of the Configuration is not set")
+	}
+	key, ok := tfcfg.ConnectionSecretKey(output, configuration.Spec.ConnectionSecret)
+	if !ok {
+		return "", errors.Errorf("output %q is not written to the connection Secret by spec.connectionSecret.outputs", output)
+	}
+	ns := ref.Namespace
+	if ns == "" {
+		ns = types.DefaultNamespace
+	}
+	var secret v1.Secret
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ns}, &secret); err != nil {
+		return "", err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", errors.Errorf("key %q is not found in Secret %s/%s", key, ns, ref.Name)
+	}
+	return string(value), nil
+}
+
+// GetReferencedVariables will resolve the variables from the Secrets and ConfigMaps in spec.variableFrom
+func (meta *TFConfigurationMeta) GetReferencedVariables(ctx context.Context, k8sClient client.Client, configuration *v1beta2.Configuration) error {
+	variables := make(map[string]string)
+	for _, ref := range configuration.Spec.VariableFrom {
+		data, err := getVariableFromData(ctx, k8sClient, configuration.Namespace, ref)
+		if err != nil {
+			if kerrors.IsNotFound(err) && ref.Optional {
+				continue
+			}
+			msg := fmt.Sprintf("failed to get variables from %s %s/%s: %s", ref.Kind, configuration.Namespace, ref.Name, err.Error())
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.InvalidVariableFromReference, msg); updateStatusErr != nil {
+				return errors.Wrap(updateStatusErr, msg)
+			}
+			return errors.New(msg)
+		}
+		if ref.Key == "" {
+			for k, v := range data {
+				variables[k] = v
+			}
+			continue
+		}
+		v, ok := data[ref.Key]
+		if !ok {
+			if ref.Optional {
+				continue
+			}
+			msg := fmt.Sprintf("key '%s' is not found in %s %s/%s", ref.Key, ref.Kind, configuration.Namespace, ref.Name)
+			if updateStatusErr := meta.UpdateApplyStatus(ctx, k8sClient, types.InvalidVariableFromReference, msg); updateStatusErr != nil {
+				return errors.Wrap(updateStatusErr, msg)
+			}
+			return errors.New(msg)
+		}
+		name := ref.VariableName
+		if name == "" {
+			name = ref.Key
+		}
+		variables[name] = v
+	}
+	meta.ReferencedVariables = variables
+	return nil
+}
+
+func getVariableFromData(ctx context.Context, k8sClient client.Client, namespace string, ref v1beta2.VariableFromSource) (map[string]string, error) {
+	data := make(map[string]string)
+	switch ref.Kind {
+	case "Secret":
+		var secret v1.Secret
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &secret); err != nil {
+			return nil, err
+		}
+		for k, v := range secret.Data {
+			data[k] = string(v)
+		}
+	case "ConfigMap":
+		var cm v1.ConfigMap
+		if err := k8sClient.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: namespace}, &cm); err != nil {
+			return nil, err
+		}
+		for k, v := range cm.Data {
+			data[k] = v
+		}
+	default:
+		return nil, errors.Errorf("unsupported kind %s, only Secret or ConfigMap is supported", ref.Kind)
+	}
+	return data, nil
+}
+
 // GetCredentials will get credentials from secret of the Provider
 func (meta *TFConfigurationMeta) GetCredentials(ctx context.Context, k8sClient client.Client, providerObj *v1beta1.Provider) error {
 	region, err := tfcfg.SetRegion(ctx, k8sClient, meta.Namespace, meta.Name, providerObj)
@@ -619,6 +1124,7 @@ func (meta *TFConfigurationMeta) createOrUpdateConfigMap(ctx context.Context, k8
 			},
 			Data: data,
 		}
+		meta.SetOwnership(&cm)
 
 		if err := k8sClient.Create(ctx, &cm); err != nil {
 			return errors.Wrap(err, "failed to create TF configuration ConfigMap")
@@ -627,7 +1133,11 @@ func (meta *TFConfigurationMeta) createOrUpdateConfigMap(ctx context.Context, k8
 		return nil
 	}
 
-	if !reflect.DeepEqual(gotCM.Data, data) {
+	// The ConfigMaps created by former versions are not labeled
+	labeled := gotCM.DeepCopy()
+	meta.SetOwnership(labeled)
+	if !reflect.DeepEqual(gotCM.Data, data) || !reflect.DeepEqual(gotCM.ObjectMeta, labeled.ObjectMeta) {
+		gotCM = *labeled
 		gotCM.Data = data
 
 		return errors.Wrap(k8sClient.Update(ctx, &gotCM), "failed to update TF configuration ConfigMap")
@@ -743,15 +1253,45 @@ func createTerraformExecutorServiceAccount(ctx context.Context, k8sClient client
 	return nil
 }
 
-func getTerraformJSONVariable(tfVariables *runtime.RawExtension) (map[string]interface{}, error) {
-	variables, err := tfcfg.RawExtension2Map(tfVariables)
+// getTerraformJSONVariable merges the variables referenced by spec.variableFrom and spec.variable, the latter takes
+// precedence. A referenced value which is a JSON object or array is decoded, so it keeps its type in terraform.tfvars.json
+func getTerraformJSONVariable(referenced map[string]string, tfVariables *runtime.RawExtension) (map[string]interface{}, error) {
+	var variables = make(map[string]interface{})
+	for k, v := range referenced {
+		variables[k] = decodeVariableValue(v)
+	}
+
+	if tfVariables == nil {
+		return variables, nil
+	}
+	raw, err := tfVariables.MarshalJSON()
 	if err != nil {
 		return nil, err
 	}
-	var environments = make(map[string]interface{})
+	var inline map[string]interface{}
+	if err := decodeJSON(raw, &inline); err != nil {
+		return nil, err
+	}
+	for k, v := range inline {
+		variables[k] = v
+	}
+	return variables, nil
+}
 
-	for k, v := range variables {
-		environments[fmt.Sprintf("TF_VAR_%s", k)] = v
+// decodeVariableValue decodes a string which is a JSON object or array, or returns the string as it is
+func decodeVariableValue(v string) interface{} {
+	if trimmed := strings.TrimSpace(v); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var value interface{}
+		if err := decodeJSON([]byte(trimmed), &value); err == nil {
+			return value
+		}
 	}
-	return environments, nil
+	return v
+}
+
+// decodeJSON decodes numbers as json.Number, so large integers and decimals are not rounded through float64
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
 }
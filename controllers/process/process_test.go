@@ -1,6 +1,8 @@
 package process
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -16,21 +18,26 @@ import (
 	"github.com/oam-dev/terraform-controller/api/v1beta1"
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	"github.com/oam-dev/terraform-controller/controllers/configuration/backend"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 var k8sClient client.Client
@@ -155,6 +162,7 @@ func TestInitTFConfigurationMetaWithDeleteResource(t *testing.T) {
 			},
 			meta: &TFConfigurationMeta{
 				DeleteResource: false,
+				DeletionPolicy: v1beta2.DeletionPolicyOrphan,
 			},
 		},
 		{
@@ -169,12 +177,32 @@ func TestInitTFConfigurationMetaWithDeleteResource(t *testing.T) {
 			},
 			meta: &TFConfigurationMeta{
 				DeleteResource: true,
+				DeletionPolicy: v1beta2.DeletionPolicyDelete,
+			},
+		},
+		{
+			name: "DeletionPolicy takes precedence over DeleteResource",
+			configuration: v1beta2.Configuration{
+				ObjectMeta: v1.ObjectMeta{
+					Name: "abc",
+				},
+				Spec: v1beta2.ConfigurationSpec{
+					DeleteResource: pointer.Bool(true),
+					DeletionPolicy: v1beta2.DeletionPolicyExport,
+				},
+			},
+			meta: &TFConfigurationMeta{
+				DeleteResource: false,
+				DeletionPolicy: v1beta2.DeletionPolicyExport,
 			},
 		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			meta := New(req, tc.configuration, nil)
+			if tc.meta.DeletionPolicy != "" && meta.DeletionPolicy != tc.meta.DeletionPolicy {
+				t.Errorf("initTFConfigurationMeta = %v, want %v", meta, tc.meta)
+			}
 			if !reflect.DeepEqual(meta.DeleteResource, tc.meta.DeleteResource) {
 				t.Errorf("initTFConfigurationMeta = %v, want %v", meta, tc.meta)
 			}
@@ -505,8 +533,11 @@ func TestPrepareTFVariables(t *testing.T) {
 	}
 	err := meta.PrepareTFVariables(&configuration)
 	assert.Nil(t, err)
-	rTestKey := fmt.Sprintf("TF_VAR_%s", testKey)
-	wantVarSecretData := map[string]string{prjID: prjIDValue, rTestKey: testValue, credentialKey: credentialValue}
+	wantVarSecretData := map[string]string{
+		prjID:                            prjIDValue,
+		types.TerraformVariablesFileName: fmt.Sprintf(`{"%s":"%s"}`, testKey, testValue),
+		credentialKey:                    credentialValue,
+	}
 	for k, v := range wantVarSecretData {
 		actualV, ok := meta.VariableSecretData[k]
 		assert.Equal(t, ok, true)
@@ -517,20 +548,272 @@ func TestPrepareTFVariables(t *testing.T) {
 		switch e.Name {
 		case prjID:
 			existMap[prjID] = true
-		case rTestKey:
-			existMap[rTestKey] = true
 		case credentialKey:
 			existMap[credentialKey] = true
 		default:
 			t.Fatalf("unexpected %s", e.Name)
 		}
 	}
-	assert.Equal(t, len(existMap), 3)
+	assert.Equal(t, len(existMap), 2)
 	for _, v := range existMap {
 		assert.Equal(t, v, true)
 	}
 }
 
+func TestPrepareTFVariablesWithReferencedVariables(t *testing.T) {
+	variable, _ := json.Marshal(map[string]interface{}{
+		"password": "inline",
+	})
+	configuration := v1beta2.Configuration{
+		Spec: v1beta2.ConfigurationSpec{
+			InlineCredentials: true,
+			Variable:          &runtime.RawExtension{Raw: variable},
+		},
+	}
+	meta := &TFConfigurationMeta{
+		VariableSecretName: "variable-abc",
+		ReferencedVariables: map[string]string{
+			"password": "referenced",
+			"username": "admin",
+		},
+	}
+	assert.Nil(t, meta.PrepareTFVariables(&configuration))
+	assert.Equal(t, map[string][]byte{
+		types.TerraformVariablesFileName: []byte(`{"password":"inline","username":"admin"}`),
+	}, meta.VariableSecretData)
+	assert.Empty(t, meta.Envs)
+}
+
+func TestMigrateLegacyVariableSecret(t *testing.T) {
+	variable, _ := json.Marshal(map[string]interface{}{"name": "a", "count": 2, "tags": map[string]string{"env": "prod"}})
+	configuration := v1beta2.Configuration{
+		Spec: v1beta2.ConfigurationSpec{
+			InlineCredentials: true,
+			Variable:          &runtime.RawExtension{Raw: variable},
+		},
+	}
+	meta := &TFConfigurationMeta{
+		VariableSecretName:  "variable-abc",
+		ControllerNamespace: "default",
+		Credentials:         map[string]string{"TF_VAR_name": "credential"},
+	}
+	assert.Nil(t, meta.PrepareTFVariables(&configuration))
+	legacyData := map[string][]byte{
+		"TF_VAR_name":  []byte("credential"),
+		"TF_VAR_count": []byte("2"),
+		"TF_VAR_tags":  []byte(`{"env":"prod"}`),
+	}
+
+	testcases := map[string]struct {
+		data    map[string][]byte
+		migrate bool
+	}{
+		"legacy Secret with the same variables": {
+			data:    legacyData,
+			migrate: true,
+		},
+		"legacy Secret with a changed variable": {
+			data: map[string][]byte{
+				"TF_VAR_name":  []byte("credential"),
+				"TF_VAR_count": []byte("3"),
+				"TF_VAR_tags":  []byte(`{"env":"prod"}`),
+			},
+		},
+		"legacy Secret with a removed variable": {
+			data: map[string][]byte{
+				"TF_VAR_name":  []byte("credential"),
+				"TF_VAR_count": []byte("2"),
+				"TF_VAR_tags":  []byte(`{"env":"prod"}`),
+				"TF_VAR_zone":  []byte("a"),
+			},
+		},
+		"Secret of terraform.tfvars.json": {
+			data: map[string][]byte{types.TerraformVariablesFileName: []byte(`{}`)},
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "variable-abc", Namespace: "default"}, Data: tc.data}
+			k8sClient := fake.NewClientBuilder().WithObjects(secret.DeepCopy()).Build()
+			assert.Nil(t, meta.MigrateLegacyVariableSecret(context.Background(), k8sClient, secret))
+
+			var got corev1.Secret
+			assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(secret), &got))
+			if tc.migrate {
+				assert.Equal(t, meta.VariableSecretData, got.Data)
+				assert.Equal(t, meta.VariableSecretData, secret.Data)
+			} else {
+				assert.Equal(t, tc.data, got.Data)
+			}
+		})
+	}
+}
+
+func TestGetTerraformJSONVariable(t *testing.T) {
+	variable := []byte(`{"count":12345678901234567890,"ratio":0.1,"tags":{"env":"prod"},"subnets":[{"cidr":"10.0.0.0/24"}],"name":"inline"}`)
+	got, err := getTerraformJSONVariable(map[string]string{
+		"name":  "referenced",
+		"zones": `["a", "b"]`,
+		"raw":   "[not json",
+	}, &runtime.RawExtension{Raw: variable})
+	assert.Nil(t, err)
+	rendered, err := json.Marshal(got)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"count":12345678901234567890,"ratio":0.1,"tags":{"env":"prod"},"subnets":[{"cidr":"10.0.0.0/24"}],`+
+		`"name":"inline","zones":["a","b"],"raw":"[not json"}`, string(rendered))
+	assert.Contains(t, string(rendered), "12345678901234567890")
+}
+
+func TestAssembleTerraformJobWithTerraformVariables(t *testing.T) {
+	meta := &TFConfigurationMeta{
+		Name:                "a",
+		ConfigurationCMName: "b",
+		VariableSecretName:  "variable-a",
+		BusyboxImage:        "c",
+		GitImage:            "d",
+		Namespace:           "e",
+		TerraformImage:      "f",
+		VariableSecretData: map[string][]byte{
+			types.TerraformVariablesFileName: []byte(`{"name":"abc"}`),
+		},
+	}
+
+	job := meta.assembleTerraformJob(types.TerraformApply)
+	spec := job.Spec.Template.Spec
+
+	var volume *corev1.Volume
+	for i := range spec.Volumes {
+		if spec.Volumes[i].Name == types.VariableVolumeName {
+			volume = &spec.Volumes[i]
+		}
+	}
+	assert.NotNil(t, volume)
+	assert.Equal(t, "variable-a", volume.Secret.SecretName)
+	assert.Equal(t, []corev1.KeyToPath{{Key: types.TerraformVariablesFileName, Path: types.TerraformVariablesFileName}}, volume.Secret.Items)
+
+	inputContainer := spec.InitContainers[0]
+	assert.Contains(t, inputContainer.VolumeMounts, corev1.VolumeMount{Name: types.VariableVolumeName, MountPath: types.VariableVolumeMountPath})
+	assert.Equal(t, "cp /opt/tf-configuration/* /data && cp /opt/tf-variables/terraform.tfvars.json /data", inputContainer.Command[2])
+}
+
+func TestValidateVariables(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	v1beta2.AddToScheme(scheme)
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: v1.ObjectMeta{Name: "abc", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			HCL: `
+variable "name" {
+  type = string
+}
+variable "password" {
+  type = string
+}`,
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(configuration).WithStatusSubresource(configuration).Build()
+
+	meta := &TFConfigurationMeta{
+		Name:              "abc",
+		Namespace:         "default",
+		ConfigurationType: types.ConfigurationHCL,
+		TFVariables:       map[string]interface{}{"nmae": "abc"},
+		VariableSecretData: map[string][]byte{
+			"TF_VAR_password": []byte("p"),
+		},
+		Recorder: record.NewFakeRecorder(10),
+	}
+	err := meta.ValidateVariables(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, `spec.variable is not valid: required variable "name" is not set`)
+	assert.Equal(t, `Warning UndeclaredVariables Variables nmae are not declared in the module, they're ignored by Terraform`,
+		<-meta.Recorder.(*record.FakeRecorder).Events)
+
+	var got v1beta2.Configuration
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.ConfigurationStaticCheckFailed, got.Status.Apply.State)
+	assert.Equal(t, err.Error(), got.Status.Apply.Message)
+
+	// The undeclared variables don't fail the Configuration
+	meta.TFVariables = map[string]interface{}{"name": "abc", "unused": "a"}
+	assert.Nil(t, meta.ValidateVariables(ctx, k8sClient, configuration))
+
+	meta.ConfigurationType = types.ConfigurationRemote
+	meta.TFVariables = map[string]interface{}{"nmae": "abc"}
+	assert.Nil(t, meta.ValidateVariables(ctx, k8sClient, configuration))
+}
+
+func TestGetReferencedVariables(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	v1beta2.AddToScheme(scheme)
+	corev1.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "db", Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("p1"), "username": []byte("u1")},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "settings", Namespace: "default"},
+		Data:       map[string]string{"region": "cn-beijing"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret, cm).Build()
+
+	testcases := []struct {
+		name         string
+		variableFrom []v1beta2.VariableFromSource
+		want         map[string]string
+		errMsg       string
+	}{
+		{
+			name: "whole Secret and one key of ConfigMap",
+			variableFrom: []v1beta2.VariableFromSource{
+				{Kind: "Secret", Name: "db"},
+				{Kind: "ConfigMap", Name: "settings", Key: "region", VariableName: "zone"},
+			},
+			want: map[string]string{"password": "p1", "username": "u1", "zone": "cn-beijing"},
+		},
+		{
+			name: "optional references are ignored when missing",
+			variableFrom: []v1beta2.VariableFromSource{
+				{Kind: "Secret", Name: "not-exist", Optional: true},
+				{Kind: "Secret", Name: "db", Key: "token", Optional: true},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "Secret is missing",
+			variableFrom: []v1beta2.VariableFromSource{
+				{Kind: "Secret", Name: "not-exist"},
+			},
+			errMsg: "failed to get variables from Secret default/not-exist",
+		},
+		{
+			name: "key is missing",
+			variableFrom: []v1beta2.VariableFromSource{
+				{Kind: "ConfigMap", Name: "settings", Key: "zone"},
+			},
+			errMsg: "key 'zone' is not found in ConfigMap default/settings",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			meta := &TFConfigurationMeta{Name: "abc", Namespace: "default"}
+			configuration := &v1beta2.Configuration{
+				ObjectMeta: v1.ObjectMeta{Name: "abc", Namespace: "default"},
+				Spec:       v1beta2.ConfigurationSpec{VariableFrom: tc.variableFrom},
+			}
+			err := meta.GetReferencedVariables(ctx, k8sClient, configuration)
+			if tc.errMsg != "" {
+				assert.Contains(t, err.Error(), tc.errMsg)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, meta.ReferencedVariables)
+		})
+	}
+}
+
 func TestCheckProvider(t *testing.T) {
 	ctx := context.Background()
 	scheme := runtime.NewScheme()
@@ -1033,9 +1316,11 @@ func TestGetTFOutputs(t *testing.T) {
 	tfStateOutputs := map[string]v1beta2.Property{
 		"container_id": {
 			Value: "e5fff27c62e26dc9504d21980543f21161225ab483a1e534a98311a677b9453a",
+			Type:  "string",
 		},
 		"image_id": {
 			Value: "sha256:d1a364dc548d5357f0da3268c888e1971bbdb957ee3f028fe7194f1d61c6fdeenginx:latest",
+			Type:  "string",
 		},
 	}
 
@@ -1379,6 +1664,157 @@ func TestGetTFOutputs(t *testing.T) {
 
 }
 
+func newTFStateSecret(t *testing.T, suffix, tfState string) *corev1.Secret {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(tfState))
+	assert.Nil(t, err)
+	assert.Nil(t, gz.Close())
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tfstate-default-" + suffix, Namespace: "default"},
+		Data:       map[string][]byte{"tfstate": buf.Bytes()},
+	}
+}
+
+func TestGetTFOutputsWithSensitiveOutputs(t *testing.T) {
+	ctx := context.Background()
+	stateSecret := newTFStateSecret(t, "s", `{"outputs":{
+"password":{"value":"p@ss","type":"string","sensitive":true},
+"zones":{"value":["a","b"],"type":["list","string"]},
+"port":{"value":3306,"type":"number"}}}`)
+	k8sClient := fake.NewClientBuilder().WithObjects(stateSecret).Build()
+	meta := &TFConfigurationMeta{
+		Backend: &backend.K8SBackend{Client: k8sClient, SecretSuffix: "s", SecretNS: "default"},
+	}
+	configuration := v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			WriteConnectionSecretToReference: &crossplane.SecretReference{Name: "db-conn", Namespace: "default"},
+		},
+	}
+
+	outputs, err := meta.getTFOutputs(ctx, k8sClient, configuration)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]v1beta2.Property{
+		"password": {Type: "string", Sensitive: true},
+		"zones":    {Value: `["a","b"]`, Type: `["list","string"]`},
+		"port":     {Value: "3306", Type: "number"},
+	}, outputs)
+
+	var connectionSecret corev1.Secret
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &connectionSecret))
+	assert.Equal(t, "p@ss", string(connectionSecret.Data["password"]))
+	assert.Equal(t, `["a","b"]`, string(connectionSecret.Data["zones"]))
+}
+
+func TestGetTFOutputsWithConnectionSecret(t *testing.T) {
+	ctx := context.Background()
+	stateSecret := newTFStateSecret(t, "c", `{"outputs":{
+"username":{"value":"admin","type":"string"},
+"password":{"value":"p@ss","type":"string","sensitive":true},
+"host":{"value":"db.local","type":"string"}}}`)
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-conn",
+			Namespace: "default",
+			Labels:    map[string]string{"terraform.core.oam.dev/owned-by": "db"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"stale": []byte("stale")},
+	}
+	k8sClient := fake.NewClientBuilder().WithObjects(stateSecret, existing).Build()
+	meta := &TFConfigurationMeta{
+		Backend: &backend.K8SBackend{Client: k8sClient, SecretSuffix: "c", SecretNS: "default"},
+	}
+	configuration := v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			WriteConnectionSecretToReference: &crossplane.SecretReference{Name: "db-conn", Namespace: "default"},
+			ConnectionSecret: &v1beta2.ConnectionSecret{
+				Outputs:     []v1beta2.ConnectionSecretOutput{{Name: "host", Key: "DB_HOST"}},
+				Templates:   map[string]string{"DSN": "postgres://{{.username}}:{{.password}}@{{.host}}"},
+				Type:        corev1.SecretTypeBasicAuth,
+				Labels:      map[string]string{"app": "web", "terraform.core.oam.dev/owned-by": "other"},
+				Annotations: map[string]string{"note": "managed"},
+			},
+		},
+	}
+
+	_, err := meta.getTFOutputs(ctx, k8sClient, configuration)
+	assert.Nil(t, err)
+
+	var got corev1.Secret
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &got))
+	assert.Equal(t, map[string][]byte{
+		"DB_HOST": []byte("db.local"),
+		"DSN":     []byte("postgres://admin:p@ss@db.local"),
+	}, got.Data)
+	assert.Equal(t, corev1.SecretTypeBasicAuth, got.Type)
+	assert.Equal(t, map[string]string{
+		"terraform.core.oam.dev/created-by":      "terraform-controller",
+		"terraform.core.oam.dev/owned-by":        "db",
+		"terraform.core.oam.dev/owned-namespace": "default",
+		"app":                                    "web",
+	}, got.Labels)
+	assert.Equal(t, map[string]string{"note": "managed", types.AnnotationConnectionSecretLabels: "app"}, got.Annotations)
+
+	// The labels removed from spec.connectionSecret.labels are removed from the Secret
+	configuration.Spec.ConnectionSecret.Labels = map[string]string{"tier": "db"}
+	_, err = meta.getTFOutputs(ctx, k8sClient, configuration)
+	assert.Nil(t, err)
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &got))
+	assert.Equal(t, "db", got.Labels["tier"])
+	assert.NotContains(t, got.Labels, "app")
+	assert.Equal(t, "tier", got.Annotations[types.AnnotationConnectionSecretLabels])
+	configuration.Spec.ConnectionSecret.Labels = nil
+	_, err = meta.getTFOutputs(ctx, k8sClient, configuration)
+	assert.Nil(t, err)
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &got))
+	assert.NotContains(t, got.Labels, "tier")
+	assert.NotContains(t, got.Annotations, types.AnnotationConnectionSecretLabels)
+}
+
+func TestGetTFOutputsWithInvalidConnectionSecretType(t *testing.T) {
+	ctx := context.Background()
+	stateSecret := newTFStateSecret(t, "c", `{"outputs":{"host":{"value":"db.local","type":"string"}}}`)
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "db-conn",
+			Namespace: "default",
+			Labels:    map[string]string{"terraform.core.oam.dev/owned-by": "db"},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{"host": []byte("db.local")},
+	}
+	// The API server rejects a kubernetes.io/tls Secret without tls.crt and tls.key
+	k8sClient := fake.NewClientBuilder().WithObjects(stateSecret, existing).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if secret, ok := obj.(*corev1.Secret); ok && secret.Type == corev1.SecretTypeTLS {
+				return errors.New(`data[tls.crt]: Required value`)
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	meta := &TFConfigurationMeta{
+		Backend: &backend.K8SBackend{Client: k8sClient, SecretSuffix: "c", SecretNS: "default"},
+	}
+	configuration := v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			WriteConnectionSecretToReference: &crossplane.SecretReference{Name: "db-conn", Namespace: "default"},
+			ConnectionSecret:                 &v1beta2.ConnectionSecret{Type: corev1.SecretTypeTLS},
+		},
+	}
+
+	_, err := meta.getTFOutputs(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, "the connection secret(db-conn) could not be changed to type kubernetes.io/tls: data[tls.crt]: Required value")
+
+	// The existing Secret is kept
+	var got corev1.Secret
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "db-conn", Namespace: "default"}, &got))
+	assert.Equal(t, corev1.SecretTypeOpaque, got.Type)
+}
+
 func TestUpdateApplyStatus(t *testing.T) {
 	type args struct {
 		k8sClient client.Client
@@ -2285,3 +2721,142 @@ func TestTerraformCredentialsHelperConfigMap(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveDependencies(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	v1beta2.AddToScheme(scheme)
+	corev1.AddToScheme(scheme)
+	vpc := &v1beta2.Configuration{
+		ObjectMeta: v1.ObjectMeta{Name: "vpc", Namespace: "default"},
+		Status: v1beta2.ConfigurationStatus{
+			Apply: v1beta2.ConfigurationApplyStatus{
+				State: types.ConfigurationProvisioningAndChecking,
+				Outputs: map[string]v1beta2.Property{
+					"id":    {Value: "vpc-123"},
+					"zones": {Value: `["a","b"]`},
+				},
+			},
+		},
+	}
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: v1.ObjectMeta{Name: "abc", Namespace: "default"},
+		Spec: v1beta2.ConfigurationSpec{
+			Variable: &runtime.RawExtension{
+				Raw: []byte(`{"vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"id"}}},"zones":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"zones"}}}}`),
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vpc, configuration).WithStatusSubresource(vpc, configuration).Build()
+	meta := &TFConfigurationMeta{Name: "abc", Namespace: "default"}
+
+	// The Configurations in other namespaces can't be depended on unless allowed
+	configuration.Spec.DependsOn = []v1beta2.ConfigurationReference{{Name: "vpc", Namespace: "infra"}}
+	err := meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, "depending on Configuration infra/vpc is not allowed, as namespace infra is not allowed by the controller")
+	meta.AllowedDependencyNamespaces = []string{"infra"}
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, types.MessageWaitingForDependencies)
+	meta.AllowedDependencyNamespaces = nil
+	configuration.Spec.DependsOn = nil
+
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, types.MessageWaitingForDependencies)
+	var got v1beta2.Configuration
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.WaitingForDependencies, got.Status.Apply.State)
+	assert.Contains(t, got.Status.Apply.Message, "default/vpc (ProvisioningAndChecking)")
+	assert.True(t, apimeta.IsStatusConditionTrue(got.Status.Conditions, types.ConditionReconciling))
+	assert.True(t, apimeta.IsStatusConditionFalse(got.Status.Conditions, types.ConditionReady))
+
+	vpc.Status.Apply.State = types.Available
+	assert.Nil(t, k8sClient.Status().Update(ctx, vpc))
+	assert.Nil(t, meta.ResolveDependencies(ctx, k8sClient, configuration))
+	assert.Equal(t, map[string]interface{}{"vpc_id": "vpc-123", "zones": []interface{}{"a", "b"}}, meta.OutputVariables)
+
+	configuration.Spec.Variable = &runtime.RawExtension{
+		Raw: []byte(`{"subnet_id":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"subnet_id"}}}}`),
+	}
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, `output "subnet_id" is not found in Configuration default/vpc, which is referenced by variable "subnet_id"`)
+
+	configuration.Spec.Variable = &runtime.RawExtension{
+		Raw: []byte(`{"token":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"token"}}}}`),
+	}
+	vpc.Status.Apply.Outputs["token"] = v1beta2.Property{Type: "string", Sensitive: true}
+	assert.Nil(t, k8sClient.Status().Update(ctx, vpc))
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, `sensitive output "token" of Configuration default/vpc, which is referenced by variable "token", could not be read: spec.writeConnectionSecretToRef of the Configuration is not set`)
+
+	vpc.Spec.WriteConnectionSecretToReference = &crossplane.SecretReference{Name: "vpc-conn", Namespace: "default"}
+	assert.Nil(t, k8sClient.Update(ctx, vpc))
+	assert.Nil(t, k8sClient.Create(ctx, &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "vpc-conn", Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte("secret-token")},
+	}))
+	assert.Nil(t, meta.ResolveDependencies(ctx, k8sClient, configuration))
+	assert.Equal(t, map[string]interface{}{"token": "secret-token"}, meta.OutputVariables)
+
+	// The key of the sensitive output follows spec.connectionSecret.outputs
+	vpc.Spec.ConnectionSecret = &v1beta2.ConnectionSecret{Outputs: []v1beta2.ConnectionSecretOutput{{Name: "token", Key: "TOKEN"}}}
+	assert.Nil(t, k8sClient.Update(ctx, vpc))
+	assert.Nil(t, k8sClient.Update(ctx, &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: "vpc-conn", Namespace: "default"},
+		Data:       map[string][]byte{"TOKEN": []byte("renamed-token")},
+	}))
+	assert.Nil(t, meta.ResolveDependencies(ctx, k8sClient, configuration))
+	assert.Equal(t, map[string]interface{}{"token": "renamed-token"}, meta.OutputVariables)
+	vpc.Spec.ConnectionSecret.Outputs = []v1beta2.ConnectionSecretOutput{{Name: "id"}}
+	assert.Nil(t, k8sClient.Update(ctx, vpc))
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, `sensitive output "token" of Configuration default/vpc, which is referenced by variable "token", could not be read: output "token" is not written to the connection Secret by spec.connectionSecret.outputs`)
+
+	// A circular dependency is reported instead of waiting forever
+	vpc.Spec.DependsOn = []v1beta2.ConfigurationReference{{Name: "abc"}}
+	assert.Nil(t, k8sClient.Update(ctx, vpc))
+	err = meta.ResolveDependencies(ctx, k8sClient, configuration)
+	assert.EqualError(t, err, "there is a circular dependency among Configurations: default/abc -> default/vpc -> default/abc")
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.ConfigurationStaticCheckFailed, got.Status.Apply.State)
+
+	now := v1.Now()
+	configuration.DeletionTimestamp = &now
+	configuration.Spec.DependsOn = []v1beta2.ConfigurationReference{{Name: "not-exist"}}
+	assert.Nil(t, meta.ResolveDependencies(ctx, k8sClient, configuration))
+}
+
+func TestAssembleAndTriggerJobQueued(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	clientgoscheme.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}}
+	running := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "default", Labels: map[string]string{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        "a",
+		types.LabelOwnedNamespace: "default",
+	}}}
+	k8sClient := fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, running).
+		WithStatusSubresource(configuration).Build()
+	meta := &TFConfigurationMeta{
+		Name:                "b",
+		Namespace:           "default",
+		ControllerNamespace: "default",
+		ApplyJobName:        "b-apply",
+		DestroyJobName:      "b-destroy",
+		JobLimiter:          limiter.NewJobLimiter(limiter.Limits{Global: 1}),
+	}
+
+	err := meta.AssembleAndTriggerJob(ctx, k8sClient, types.TerraformApply)
+	assert.EqualError(t, err, types.MessageJobQueued)
+	var got v1beta2.Configuration
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configuration), &got))
+	assert.Equal(t, types.Queued, got.Status.Apply.State)
+	assert.Equal(t, types.MessageJobQueued+": 1 Jobs are running", got.Status.Apply.Message)
+
+	// Destroying is never queued
+	k8sClient = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, running).Build()
+	assert.Nil(t, meta.AssembleAndTriggerJob(ctx, k8sClient, types.TerraformDestroy))
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "b-destroy", Namespace: "default"}, &batchv1.Job{}))
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+// runJobLabels are the labels set by Kubernetes to the pod template of a Job, which can't be copied to another Job
+var runJobLabels = []string{"controller-uid", "job-name", batchv1.ControllerUidLabel, batchv1.JobNameLabel}
+
+// RunName is the name of the TerraformRun created by the controller for a Terraform Job
+func RunName(configurationName string, executionType types.TerraformExecutionType, start metav1.Time) string {
+	return fmt.Sprintf("%s-%s", configurationName, runID(executionType, start))
+}
+
+// setRunLabel labels the Job and its pods with the TerraformRun. A name too long to be a label value is skipped, and
+// then the TerraformRun is synced with the Job only periodically.
+func setRunLabel(job *batchv1.Job, runName string) {
+	if len(validation.IsValidLabelValue(runName)) != 0 {
+		return
+	}
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[types.LabelRun] = runName
+	if job.Spec.Template.Labels == nil {
+		job.Spec.Template.Labels = map[string]string{}
+	}
+	job.Spec.Template.Labels[types.LabelRun] = runName
+}
+
+// WaitForRuns tells whether the Job of a TerraformRun created by users is running for the Configuration, then the apply
+// or the destroy Job isn't created or replaced, as the Terraform Jobs run with -lock=false and only one of them could
+// write the state at a time. The state of the Configuration is set to Queued meanwhile.
+func (meta *TFConfigurationMeta) WaitForRuns(ctx context.Context, k8sClient client.Client, executionType types.TerraformExecutionType) error {
+	var jobs batchv1.JobList
+	if err := k8sClient.List(ctx, &jobs, client.MatchingLabels{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        meta.Name,
+		types.LabelOwnedNamespace: meta.Namespace,
+	}, client.HasLabels{types.LabelRun}); err != nil {
+		return err
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		// The apply and the destroy Jobs are labeled with the TerraformRuns created by the controller
+		if job.Name == meta.ApplyJobName || job.Name == meta.DestroyJobName {
+			continue
+		}
+		if phase, _ := RunPhase(job); phase != types.RunOutcomeRunning {
+			continue
+		}
+		msg := fmt.Sprintf("%s: TerraformRun %s/%s is running Job %s/%s", types.MessageWaitingForRun, meta.Namespace, job.Labels[types.LabelRun], job.Namespace, job.Name)
+		klog.InfoS(msg, "Namespace", meta.Namespace, "Name", meta.Name)
+		update := meta.UpdateApplyStatus
+		if executionType == types.TerraformDestroy {
+			update = meta.UpdateDestroyStatus
+		}
+		if err := update(ctx, k8sClient, types.Queued, msg); err != nil {
+			return err
+		}
+		return errors.New(types.MessageWaitingForRun)
+	}
+	return nil
+}
+
+// createRun creates the TerraformRun for the Terraform Job created by the controller. The Job isn't owned by the
+// TerraformRun, otherwise deleting the TerraformRun would delete the apply Job and trigger another apply.
+func (meta *TFConfigurationMeta) createRun(ctx context.Context, k8sClient client.Client, executionType types.TerraformExecutionType, job *batchv1.Job, start metav1.Time) error {
+	run := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RunName(meta.Name, executionType, start),
+			Namespace: meta.Namespace,
+		},
+		Spec: v1beta2.TerraformRunSpec{
+			ConfigurationName: meta.Name,
+			Type:              executionType,
+		},
+	}
+	meta.SetOwnership(run)
+	if err := k8sClient.Create(ctx, run); err != nil && !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	run.Status = v1beta2.TerraformRunStatus{
+		Phase:             types.RunOutcomeRunning,
+		JobName:           job.Name,
+		JobNamespace:      job.Namespace,
+		ConfigurationHash: meta.configurationHash(),
+		VariableHash:      VariableHash(meta.VariableSecretData),
+		Image:             meta.TerraformImage,
+		ProviderReference: meta.ProviderReference,
+		StartTime:         &start,
+	}
+	return k8sClient.Status().Update(ctx, run)
+}
+
+// AssembleRunJob assembles the Job of a TerraformRun created by users from the apply Job of the Configuration, so
+// that the run shares the rendered configuration, the variables and the images with the latest apply
+func AssembleRunJob(run *v1beta2.TerraformRun, applyJob *batchv1.Job) (*batchv1.Job, error) {
+	// The Job is labeled with the run, so that the Jobs of the runs are found and serialized
+	if len(validation.IsValidLabelValue(run.Name)) != 0 {
+		return nil, fmt.Errorf("the name of TerraformRun %s is invalid, it must be a valid label value of at most %d characters", run.Name, validation.LabelValueMaxLength)
+	}
+	template := applyJob.Spec.Template.DeepCopy()
+	for _, k := range append(runJobLabels, types.LabelRun) {
+		delete(template.Labels, k)
+	}
+	found := false
+	for i, c := range template.Spec.Containers {
+		if c.Name == types.TerraformContainerName {
+			command, err := runCommand(run.Spec, JSONOutputSupported(c.Image))
+			if err != nil {
+				return nil, err
+			}
+			template.Spec.Containers[i].Command = command
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("the container %s is not found in the Job %s/%s", types.TerraformContainerName, applyJob.Namespace, applyJob.Name)
+	}
+
+	labels := map[string]string{}
+	for k, v := range applyJob.Labels {
+		labels[k] = v
+	}
+	delete(labels, types.LabelRun)
+
+	var (
+		parallelism  int32 = 1
+		completions  int32 = 1
+		backoffLimit int32
+	)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      runJobName(run),
+			Namespace: applyJob.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			Parallelism:           &parallelism,
+			Completions:           &completions,
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: applyJob.Spec.ActiveDeadlineSeconds,
+			Template:              *template,
+		},
+	}
+	setRunLabel(job, run.Name)
+	// Owner references can't cross namespaces, the Job is deleted by the finalizer of the TerraformRun then
+	if run.UID != "" && job.Namespace == run.Namespace {
+		controller := true
+		job.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: v1beta2.GroupVersion.String(),
+			Kind:       "TerraformRun",
+			Name:       run.Name,
+			UID:        run.UID,
+			Controller: &controller,
+		}}
+	}
+	return job, nil
+}
+
+// runJobName is the name of the Job of a TerraformRun, which is unique by the hash of the run. The name of the run is
+// truncated, as the name of a Job has to be a valid label value of its pods.
+func runJobName(run *v1beta2.TerraformRun) string {
+	hash := hashString(run.Namespace + "/" + run.Name)[:8]
+	name := run.Name
+	if max := validation.LabelValueMaxLength - len(hash) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-.")
+	}
+	return fmt.Sprintf("%s-%s", name, hash)
+}
+
+// runCommand is the command of the Terraform container to execute the TerraformRun. The machine-readable output is
+// only enabled if the Terraform of the image supports it.
+func runCommand(spec v1beta2.TerraformRunSpec, jsonOutput bool) ([]string, error) {
+	var command []string
+	switch spec.Type {
+	case types.TerraformApply, types.TerraformDestroy:
+		command = []string{"terraform", string(spec.Type), "-lock=false", "-auto-approve"}
+	case types.TerraformPlan:
+		command = []string{"terraform", "plan", "-lock=false"}
+	case types.TerraformRefresh:
+		command = []string{"terraform", "apply", "-refresh-only", "-lock=false", "-auto-approve"}
+	case types.TerraformImport:
+		if spec.Import == nil || spec.Import.Address == "" || spec.Import.ID == "" {
+			return nil, errors.New("spec.import.address and spec.import.id are required to import a resource")
+		}
+		return []string{"terraform", "import", "-lock=false", spec.Import.Address, spec.Import.ID}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s of TerraformRun", spec.Type)
+	}
+	if jsonOutput {
+		command = append(command, "-json")
+	}
+	for _, target := range spec.Targets {
+		command = append(command, "-target="+target)
+	}
+	return command, nil
+}
+
+// RunPhase tells the phase of a TerraformRun from its Job
+func RunPhase(job *batchv1.Job) (string, string) {
+	if job.Status.Succeeded > 0 {
+		return types.RunOutcomeSucceeded, ""
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == v1.ConditionTrue {
+			return types.RunOutcomeFailed, c.Message
+		}
+	}
+	return types.RunOutcomeRunning, ""
+}
+
+// VariableHash is the short hash of the data of the variable Secret
+func VariableHash(data map[string][]byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, data[k])
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// ConfigurationHash is the short hash of the rendered Terraform configuration
+func ConfigurationHash(configuration string) string {
+	if configuration == "" {
+		return ""
+	}
+	return hashString(configuration)[:16]
+}
+
+func hashString(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
@@ -0,0 +1,183 @@
+package process
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestRunCommand(t *testing.T) {
+	testcases := map[string]struct {
+		spec       v1beta2.TerraformRunSpec
+		jsonOutput bool
+		want       []string
+		wantErr    bool
+	}{
+		"apply with targets": {
+			spec:       v1beta2.TerraformRunSpec{Type: types.TerraformApply, Targets: []string{"a.b", "c.d"}},
+			jsonOutput: true,
+			want:       []string{"terraform", "apply", "-lock=false", "-auto-approve", "-json", "-target=a.b", "-target=c.d"},
+		},
+		"destroy": {
+			spec:       v1beta2.TerraformRunSpec{Type: types.TerraformDestroy},
+			jsonOutput: true,
+			want:       []string{"terraform", "destroy", "-lock=false", "-auto-approve", "-json"},
+		},
+		"destroy without the machine-readable output": {
+			spec: v1beta2.TerraformRunSpec{Type: types.TerraformDestroy},
+			want: []string{"terraform", "destroy", "-lock=false", "-auto-approve"},
+		},
+		"plan": {
+			spec:       v1beta2.TerraformRunSpec{Type: types.TerraformPlan},
+			jsonOutput: true,
+			want:       []string{"terraform", "plan", "-lock=false", "-json"},
+		},
+		"refresh": {
+			spec:       v1beta2.TerraformRunSpec{Type: types.TerraformRefresh},
+			jsonOutput: true,
+			want:       []string{"terraform", "apply", "-refresh-only", "-lock=false", "-auto-approve", "-json"},
+		},
+		"import": {
+			spec: v1beta2.TerraformRunSpec{Type: types.TerraformImport, Import: &v1beta2.TerraformRunImport{Address: "a.b", ID: "id-1"}},
+			want: []string{"terraform", "import", "-lock=false", "a.b", "id-1"},
+		},
+		"import without id": {
+			spec:    v1beta2.TerraformRunSpec{Type: types.TerraformImport, Import: &v1beta2.TerraformRunImport{Address: "a.b"}},
+			wantErr: true,
+		},
+		"unsupported type": {
+			spec:    v1beta2.TerraformRunSpec{Type: "output"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			got, err := runCommand(tc.spec, tc.jsonOutput)
+			if tc.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAssembleRunJob(t *testing.T) {
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-apply",
+			Namespace: "vela-system",
+			Labels:    map[string]string{types.LabelOwnedBy: "a", types.LabelRun: "a-apply-100"},
+		},
+		Spec: batchv1.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{
+					types.LabelOwnedBy:         "a",
+					types.LabelRun:             "a-apply-100",
+					batchv1.JobNameLabel:       "a-apply",
+					batchv1.ControllerUidLabel: "uid",
+				}},
+				Spec: v1.PodSpec{Containers: []v1.Container{{
+					Name:    types.TerraformContainerName,
+					Image:   "terraform:1.0",
+					Command: []string{"terraform", "apply"},
+				}}},
+			},
+		},
+	}
+	run := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan-1", Namespace: "default", UID: "run-uid"},
+		Spec:       v1beta2.TerraformRunSpec{ConfigurationName: "a", Type: types.TerraformPlan},
+	}
+
+	job, err := AssembleRunJob(run, applyJob)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(job.Name, "plan-1-"))
+	assert.Equal(t, "vela-system", job.Namespace)
+	assert.Equal(t, map[string]string{types.LabelOwnedBy: "a", types.LabelRun: "plan-1"}, job.Labels)
+	assert.Equal(t, map[string]string{types.LabelOwnedBy: "a", types.LabelRun: "plan-1"}, job.Spec.Template.Labels)
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit)
+	assert.Equal(t, []string{"terraform", "plan", "-lock=false", "-json"}, job.Spec.Template.Spec.Containers[0].Command)
+	assert.Equal(t, []string{"terraform", "apply"}, applyJob.Spec.Template.Spec.Containers[0].Command)
+	// the Job isn't in the namespace of the TerraformRun
+	assert.Empty(t, job.OwnerReferences)
+
+	applyJob.Namespace = "default"
+	job, err = AssembleRunJob(run, applyJob)
+	assert.Nil(t, err)
+	assert.Len(t, job.OwnerReferences, 1)
+	assert.Equal(t, run.UID, job.OwnerReferences[0].UID)
+
+	// The name of the Job fits in the job-name label of its pods
+	run.Name = strings.Repeat("a", 60)
+	job, err = AssembleRunJob(run, applyJob)
+	assert.Nil(t, err)
+	assert.Len(t, job.Name, 63)
+	assert.True(t, strings.HasPrefix(job.Name, strings.Repeat("a", 54)+"-"))
+	run.Name = strings.Repeat("a", 64)
+	_, err = AssembleRunJob(run, applyJob)
+	assert.ErrorContains(t, err, "must be a valid label value of at most 63 characters")
+	run.Name = "plan-1"
+
+	applyJob.Spec.Template.Spec.Containers[0].Name = "other"
+	_, err = AssembleRunJob(run, applyJob)
+	assert.NotNil(t, err)
+}
+
+func TestSetRunLabel(t *testing.T) {
+	job := &batchv1.Job{}
+	setRunLabel(job, "a-apply-100")
+	assert.Equal(t, "a-apply-100", job.Labels[types.LabelRun])
+	assert.Equal(t, "a-apply-100", job.Spec.Template.Labels[types.LabelRun])
+
+	job = &batchv1.Job{}
+	setRunLabel(job, strings.Repeat("a", 64))
+	assert.Empty(t, job.Labels)
+}
+
+func TestRunPhase(t *testing.T) {
+	testcases := map[string]struct {
+		status  batchv1.JobStatus
+		phase   string
+		message string
+	}{
+		"running": {
+			status: batchv1.JobStatus{Active: 1},
+			phase:  types.RunOutcomeRunning,
+		},
+		"succeeded": {
+			status: batchv1.JobStatus{Succeeded: 1},
+			phase:  types.RunOutcomeSucceeded,
+		},
+		"failed": {
+			status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+				Type: batchv1.JobFailed, Status: v1.ConditionTrue, Message: "BackoffLimitExceeded",
+			}}},
+			phase:   types.RunOutcomeFailed,
+			message: "BackoffLimitExceeded",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			phase, message := RunPhase(&batchv1.Job{Status: tc.status})
+			assert.Equal(t, tc.phase, phase)
+			assert.Equal(t, tc.message, message)
+		})
+	}
+}
+
+func TestVariableHash(t *testing.T) {
+	assert.Equal(t, "", VariableHash(nil))
+	h := VariableHash(map[string][]byte{"a": []byte("1"), "b": []byte("2")})
+	assert.Len(t, h, 16)
+	assert.Equal(t, h, VariableHash(map[string][]byte{"b": []byte("2"), "a": []byte("1")}))
+	assert.NotEqual(t, h, VariableHash(map[string][]byte{"a": []byte("1"), "b": []byte("3")}))
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+// DefaultPendingTimeout is the duration the pods of a Terraform Job stay Pending before the Job is considered stuck
+const DefaultPendingTimeout = 10 * time.Minute
+
+// CheckJobTimeout tells why the Terraform Job is timed out, which is empty if it isn't. A Job is timed out when it
+// exceeds its deadline, or when its pod stays Pending longer than meta.PendingTimeout, like being unschedulable or
+// in ImagePullBackOff. The Job whose pod is stuck is suspended, so that its pods are deleted and not recreated, until
+// the Job is retried by RetryTimedOutJob.
+func (meta *TFConfigurationMeta) CheckJobTimeout(ctx context.Context, k8sClient client.Client, job *batchv1.Job) (string, error) {
+	if msg, ok := job.Annotations[types.AnnotationTimedOut]; ok && job.Spec.Suspend != nil && *job.Spec.Suspend {
+		return msg, nil
+	}
+	for _, c := range job.Status.Conditions {
+		if c.Type != batchv1.JobFailed || c.Status != v1.ConditionTrue {
+			continue
+		}
+		if c.Reason == batchv1.JobReasonDeadlineExceeded && job.Spec.ActiveDeadlineSeconds != nil {
+			return fmt.Sprintf("The Terraform Job %s/%s is not completed in %d seconds", job.Namespace, job.Name, *job.Spec.ActiveDeadlineSeconds), nil
+		}
+		if c.Reason == batchv1.JobReasonDeadlineExceeded {
+			return fmt.Sprintf("The Terraform Job %s/%s is not completed in time: %s", job.Namespace, job.Name, c.Message), nil
+		}
+		// The other failures are analyzed from the logs
+		return "", nil
+	}
+	if job.Status.Succeeded > 0 || meta.PendingTimeout <= 0 {
+		return "", nil
+	}
+
+	var pods v1.PodList
+	if err := k8sClient.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabels{"job-name": job.Name}); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		since, pending := pendingSince(pod)
+		if !pending || time.Since(since) < meta.PendingTimeout {
+			continue
+		}
+		msg := fmt.Sprintf("The pod %s/%s of the Terraform Job is stuck in Pending for more than %s", pod.Namespace, pod.Name, meta.PendingTimeout)
+		if reason := pendingReason(pod); reason != "" {
+			msg += ": " + reason
+		}
+		return msg, suspendJob(ctx, k8sClient, job, msg)
+	}
+	return "", nil
+}
+
+// suspendJob suspends the Job and records why it's timed out
+func suspendJob(ctx context.Context, k8sClient client.Client, job *batchv1.Job, msg string) error {
+	patch := client.MergeFrom(job.DeepCopy())
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	job.Annotations[types.AnnotationTimedOut] = msg
+	job.Spec.Suspend = pointer.Bool(true)
+	return k8sClient.Patch(ctx, job, patch)
+}
+
+// RetryTimedOutJob deletes the timed-out Job if the retry token of the Configuration is changed, so that a new Job is
+// created. It tells whether the Job is deleted.
+func (meta *TFConfigurationMeta) RetryTimedOutJob(ctx context.Context, k8sClient client.Client, job *batchv1.Job) (bool, error) {
+	if meta.RetryToken == "" || job.Annotations[types.AnnotationRetry] == meta.RetryToken {
+		return false, nil
+	}
+	klog.InfoS("Retrying the timed-out Terraform Job", "Namespace", job.Namespace, "Name", job.Name)
+	if err := k8sClient.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !kerrors.IsNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// pendingSince tells whether none of the containers of the pod is running, and since when. The init containers run
+// while the pod is Pending, so the time is counted from the latest one which is completed.
+func pendingSince(pod v1.Pod) (time.Time, bool) {
+	if pod.Status.Phase != v1.PodPending {
+		return time.Time{}, false
+	}
+	since := pod.CreationTimestamp.Time
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, c := range statuses {
+			if c.State.Running != nil {
+				return time.Time{}, false
+			}
+			if c.State.Terminated != nil && c.State.Terminated.FinishedAt.After(since) {
+				since = c.State.Terminated.FinishedAt.Time
+			}
+		}
+	}
+	return since, true
+}
+
+// pendingReason is the reason why the pod is Pending, like ImagePullBackOff of a container or Unschedulable
+func pendingReason(pod v1.Pod) string {
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, c := range statuses {
+			if w := c.State.Waiting; w != nil && w.Reason != "" && w.Reason != "PodInitializing" && w.Reason != "ContainerCreating" {
+				return joinReason(w.Reason, w.Message)
+			}
+		}
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse {
+			return joinReason(c.Reason, c.Message)
+		}
+	}
+	return ""
+}
+
+func joinReason(reason, message string) string {
+	if message == "" {
+		return reason
+	}
+	return fmt.Sprintf("%s: %s", reason, message)
+}
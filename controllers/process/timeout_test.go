@@ -0,0 +1,171 @@
+package process
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func TestCheckJobTimeout(t *testing.T) {
+	s := runtime.NewScheme()
+	v1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system"}}
+	deadlineExceeded := job.DeepCopy()
+	deadlineExceeded.Spec.ActiveDeadlineSeconds = pointer.Int64(600)
+	deadlineExceeded.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: batchv1.JobReasonDeadlineExceeded,
+	}}
+	suspended := job.DeepCopy()
+	suspended.Annotations = map[string]string{types.AnnotationTimedOut: "The pod vela-system/a-apply-xyz of the Terraform Job is stuck in Pending for more than 10m0s"}
+	suspended.Spec.Suspend = pointer.Bool(true)
+	backoffLimitExceeded := job.DeepCopy()
+	backoffLimitExceeded.Status.Conditions = []batchv1.JobCondition{{
+		Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: batchv1.JobReasonBackoffLimitExceeded,
+	}}
+
+	newPod := func(age time.Duration, status v1.PodStatus) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "a-apply-xyz",
+				Namespace:         "vela-system",
+				Labels:            map[string]string{"job-name": "a-apply"},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+			Status: status,
+		}
+	}
+	waiting := func(reason, message string) []v1.ContainerStatus {
+		return []v1.ContainerStatus{{State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: reason, Message: message}}}}
+	}
+	unschedulable := []v1.PodCondition{{
+		Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available",
+	}}
+
+	testcases := map[string]struct {
+		job  *batchv1.Job
+		pod  *v1.Pod
+		want string
+	}{
+		"deadline exceeded": {
+			job:  deadlineExceeded,
+			want: "The Terraform Job vela-system/a-apply is not completed in 600 seconds",
+		},
+		"failed with retries exhausted": {
+			job: backoffLimitExceeded,
+		},
+		"image pull back-off": {
+			job:  job,
+			pod:  newPod(time.Hour, v1.PodStatus{Phase: v1.PodPending, InitContainerStatuses: waiting("ImagePullBackOff", "Back-off pulling image")}),
+			want: "The pod vela-system/a-apply-xyz of the Terraform Job is stuck in Pending for more than 10m0s: ImagePullBackOff: Back-off pulling image",
+		},
+		"unschedulable": {
+			job:  job,
+			pod:  newPod(time.Hour, v1.PodStatus{Phase: v1.PodPending, Conditions: unschedulable}),
+			want: "The pod vela-system/a-apply-xyz of the Terraform Job is stuck in Pending for more than 10m0s: Unschedulable: 0/3 nodes are available",
+		},
+		"pending for a while": {
+			job: job,
+			pod: newPod(time.Minute, v1.PodStatus{Phase: v1.PodPending, Conditions: unschedulable}),
+		},
+		"init container running": {
+			job: job,
+			pod: newPod(time.Hour, v1.PodStatus{
+				Phase:                 v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{Running: &v1.ContainerStateRunning{}}}},
+			}),
+		},
+		"init container just completed": {
+			job: job,
+			pod: newPod(time.Hour, v1.PodStatus{
+				Phase: v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{{State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+					FinishedAt: metav1.NewTime(time.Now().Add(-time.Second)),
+				}}}},
+				ContainerStatuses: waiting("PodInitializing", ""),
+			}),
+		},
+		"running": {
+			job: job,
+			pod: newPod(time.Hour, v1.PodStatus{Phase: v1.PodRunning}),
+		},
+		"suspended as the pod was stuck": {
+			job:  suspended,
+			want: "The pod vela-system/a-apply-xyz of the Terraform Job is stuck in Pending for more than 10m0s",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			objects := []client.Object{tc.job}
+			if tc.pod != nil {
+				objects = append(objects, tc.pod)
+			}
+			k8sClient := fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build()
+			meta := &TFConfigurationMeta{PendingTimeout: DefaultPendingTimeout}
+			got, err := meta.CheckJobTimeout(context.Background(), k8sClient, tc.job.DeepCopy())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.want, got)
+
+			// The Job whose pod is stuck is suspended
+			var j batchv1.Job
+			assert.Nil(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tc.job), &j))
+			if tc.pod != nil && tc.want != "" {
+				assert.True(t, *j.Spec.Suspend)
+				assert.Equal(t, tc.want, j.Annotations[types.AnnotationTimedOut])
+			} else {
+				assert.Equal(t, tc.job.Spec.Suspend, j.Spec.Suspend)
+			}
+		})
+	}
+}
+
+func TestRetryTimedOutJob(t *testing.T) {
+	testcases := map[string]struct {
+		token       string
+		jobToken    string
+		wantRetried bool
+	}{
+		"no token": {},
+		"same token": {
+			token:    "1",
+			jobToken: "1",
+		},
+		"new token": {
+			token:       "2",
+			jobToken:    "1",
+			wantRetried: true,
+		},
+		"token of the Job created before the annotation": {
+			token:       "1",
+			wantRetried: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "a-apply", Namespace: "vela-system"}}
+			if tc.jobToken != "" {
+				job.Annotations = map[string]string{types.AnnotationRetry: tc.jobToken}
+			}
+			k8sClient := fake.NewClientBuilder().WithObjects(job).Build()
+			meta := &TFConfigurationMeta{RetryToken: tc.token}
+			retried, err := meta.RetryTimedOutJob(context.Background(), k8sClient, job)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.wantRetried, retried)
+			err = k8sClient.Get(context.Background(), client.ObjectKeyFromObject(job), &batchv1.Job{})
+			assert.Equal(t, tc.wantRetried, kerrors.IsNotFound(err))
+		})
+	}
+}
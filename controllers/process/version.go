@@ -0,0 +1,55 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package process
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var imageVersionRegexp = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// JSONOutputSupported tells whether the Terraform of the image supports the machine-readable output of
+// `terraform apply/destroy -json`, which is 0.15.3+. The images with an unknown version print the colored logs instead,
+// from which the errors are still analyzed, but without the diagnostics.
+func JSONOutputSupported(image string) bool {
+	return imageVersionAtLeast(image, 0, 15, 3)
+}
+
+// imageVersionAtLeast tells whether the version in the tag of the image is at least major.minor.patch
+func imageVersionAtLeast(image string, major, minor, patch int) bool {
+	tag := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(tag, ":")
+	if i < 0 {
+		return false
+	}
+	matches := imageVersionRegexp.FindStringSubmatch(tag[i+1:])
+	if matches == nil {
+		return false
+	}
+	version := make([]int, 3)
+	for j, m := range matches[1:] {
+		version[j], _ = strconv.Atoi(m)
+	}
+	for j, want := range []int{major, minor, patch} {
+		if version[j] != want {
+			return version[j] > want
+		}
+	}
+	return true
+}
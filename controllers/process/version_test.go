@@ -0,0 +1,23 @@
+package process
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONOutputSupported(t *testing.T) {
+	testcases := map[string]bool{
+		"oamdev/docker-terraform:1.1.5":       true,
+		"hashicorp/terraform:0.15.3":          true,
+		"hashicorp/terraform:0.15.2":          false,
+		"hashicorp/terraform:v0.15":           false,
+		"oamdev/docker-terraform:0.14.11":     false,
+		"registry:5000/docker-terraform":      false,
+		"oamdev/docker-terraform:latest":      false,
+		"oamdev/docker-terraform:1.10.0-beta": true,
+	}
+	for image, want := range testcases {
+		assert.Equal(t, want, JSONOutputSupported(image), image)
+	}
+}
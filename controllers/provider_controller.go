@@ -23,14 +23,20 @@ import (
 	"github.com/go-logr/logr"
 	crossplanetypes "github.com/oam-dev/terraform-controller/api/types/crossplane-runtime"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 
 	"github.com/oam-dev/terraform-controller/api/types"
 	terraformv1beta1 "github.com/oam-dev/terraform-controller/api/v1beta1"
+	"github.com/oam-dev/terraform-controller/controllers/metrics"
 	providercred "github.com/oam-dev/terraform-controller/controllers/provider"
 )
 
@@ -42,12 +48,16 @@ const (
 // ProviderReconciler reconciles a Provider object
 type ProviderReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// MaxConcurrentReconciles is the maximum number of Providers reconciled at the same time
+	MaxConcurrentReconciles int
 }
 
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=providers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=providers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile will reconcile periodically
 func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -75,14 +85,30 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 		return nil
 	}()
+	previousState := provider.Status.State
+	ready := metav1.Condition{Type: types.ConditionReady, ObservedGeneration: provider.Generation}
 	if err != nil {
 		klog.ErrorS(err, errGetCredentials, "Provider", req.NamespacedName)
+		metrics.ProviderValidationFailuresTotal.WithLabelValues(provider.Spec.Provider).Inc()
 
+		provider.Status.State = types.ProviderIsNotReady
 		provider.Status.Message = fmt.Sprintf("%s: %s", errGetCredentials, err.Error())
-		provider.Status = terraformv1beta1.ProviderStatus{State: types.ProviderIsNotReady}
+		ready.Status = metav1.ConditionFalse
+		ready.Reason = string(types.ProviderIsNotReady)
 	} else {
+		provider.Status.State = types.ProviderIsReady
 		provider.Status.Message = "Provider ready"
-		provider.Status = terraformv1beta1.ProviderStatus{State: types.ProviderIsReady}
+		ready.Status = metav1.ConditionTrue
+		ready.Reason = types.ReasonProviderReady
+	}
+	ready.Message = provider.Status.Message
+	apimeta.SetStatusCondition(&provider.Status.Conditions, ready)
+	if provider.Status.State != previousState && r.Recorder != nil {
+		if provider.Status.State == types.ProviderIsReady {
+			r.Recorder.Event(&provider, corev1.EventTypeNormal, types.EventReasonProviderReady, provider.Status.Message)
+		} else {
+			r.Recorder.Event(&provider, corev1.EventTypeWarning, types.EventReasonProviderNotReady, provider.Status.Message)
+		}
 	}
 
 	if updateErr := r.Status().Update(ctx, &provider); updateErr != nil {
@@ -96,7 +122,11 @@ func (r *ProviderReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 
 // SetupWithManager setups with a manager
 func (r *ProviderReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("terraform-controller")
+	}
 	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		For(&terraformv1beta1.Provider{}).
 		Complete(r)
 }
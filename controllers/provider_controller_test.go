@@ -8,12 +8,15 @@ import (
 
 	. "github.com/agiledragon/gomonkey/v2"
 	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 	v1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -419,3 +422,77 @@ func apiutilGVKForObject(obj runtime.Object, scheme *runtime.Scheme) (schema.Gro
 	}
 	return gvks[0], nil
 }
+
+func TestReconcileProviderConditions(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta1.AddToScheme(s)
+	v1.AddToScheme(s)
+
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default", Generation: 2},
+		Spec: v1beta1.ProviderSpec{
+			Credentials: v1beta1.ProviderCredentials{Source: "InjectedIdentity"},
+			Provider:    "aws",
+		},
+	}
+	r := &ProviderReconciler{}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(provider).WithStatusSubresource(&v1beta1.Provider{}).Build()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "aws", Namespace: "default"}}
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	var got v1beta1.Provider
+	assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+	assert.Equal(t, "Provider ready", got.Status.Message)
+	ready := apimeta.FindStatusCondition(got.Status.Conditions, "Ready")
+	assert.NotNil(t, ready)
+	assert.Equal(t, metav1.ConditionTrue, ready.Status)
+	assert.Equal(t, int64(2), ready.ObservedGeneration)
+
+	got.Spec.Credentials.Source = "Invalid"
+	assert.Nil(t, r.Update(ctx, &got))
+	_, err = r.Reconcile(ctx, req)
+	assert.EqualError(t, err, "unsupported credentials source: Invalid")
+	assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+	assert.Equal(t, "failed to get credentials from the cloud provider: unsupported credentials source: Invalid", got.Status.Message)
+	ready = apimeta.FindStatusCondition(got.Status.Conditions, "Ready")
+	assert.Equal(t, metav1.ConditionFalse, ready.Status)
+	assert.Equal(t, "ProviderNotReady", ready.Reason)
+}
+
+func TestReconcileProviderEvents(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta1.AddToScheme(s)
+	v1.AddToScheme(s)
+
+	provider := &v1beta1.Provider{
+		ObjectMeta: metav1.ObjectMeta{Name: "aws", Namespace: "default"},
+		Spec: v1beta1.ProviderSpec{
+			Credentials: v1beta1.ProviderCredentials{Source: "InjectedIdentity"},
+			Provider:    "aws",
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := &ProviderReconciler{Recorder: recorder}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(provider).WithStatusSubresource(&v1beta1.Provider{}).Build()
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: "aws", Namespace: "default"}}
+
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Normal ProviderReady Provider ready", <-recorder.Events)
+
+	// No Event is emitted if the readiness doesn't change
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.Events)
+
+	var got v1beta1.Provider
+	assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+	got.Spec.Credentials.Source = "Invalid"
+	assert.Nil(t, r.Update(ctx, &got))
+	_, err = r.Reconcile(ctx, req)
+	assert.NotNil(t, err)
+	assert.Equal(t, "Warning ProviderNotReady failed to get credentials from the cloud provider: unsupported credentials source: Invalid", <-recorder.Events)
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// SinkConfigMap archives the logs in ConfigMaps
+	SinkConfigMap = "configmap"
+	// SinkS3 archives the logs in an S3 bucket
+	SinkS3 = "s3"
+)
+
+// Run identifies the Terraform run whose logs are archived
+type Run struct {
+	// ID identifies the run among the runs of the Configuration
+	ID string
+	// Namespace, Name and UID are of the Configuration
+	Namespace string
+	Name      string
+	UID       string
+	// JobNamespace is the namespace of the Terraform Job
+	JobNamespace string
+	// Labels are set on the archived logs if the sink supports labels
+	Labels map[string]string
+}
+
+// Archiver archives the full logs of the Terraform runs
+type Archiver interface {
+	// Archive stores the logs of the run, and returns the reference to find them
+	Archive(ctx context.Context, run Run, logs string) (string, error)
+	// Delete removes the logs which the reference points to
+	Delete(ctx context.Context, ref string) error
+}
+
+// Pruner is implemented by the Archivers which remove the expired logs by themselves. The logs are kept after their
+// Configuration is deleted, so they are only removed by the retention.
+type Pruner interface {
+	// Prune removes the logs archived before the time
+	Prune(ctx context.Context, before time.Time) error
+}
+
+// pruneInterval is how often the expired logs are pruned
+const pruneInterval = time.Hour
+
+// NewRetention returns the Runnable which prunes the logs older than the retention periodically, it returns nil if
+// the Archiver doesn't prune the logs or the retention is not set
+func NewRetention(archiver Archiver, retention time.Duration) manager.Runnable {
+	pruner, ok := archiver.(Pruner)
+	if !ok || retention <= 0 {
+		return nil
+	}
+	return manager.RunnableFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for {
+			if err := pruner.Prune(ctx, time.Now().Add(-retention)); err != nil {
+				klog.ErrorS(err, "Failed to prune the archived logs of the Terraform runs")
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Options are the options to build an Archiver
+type Options struct {
+	// Sink is where the logs are archived, SinkConfigMap or SinkS3, empty means the logs are not archived
+	Sink string
+	// S3Bucket, S3Prefix and S3Region are the location of the logs for SinkS3
+	S3Bucket string
+	S3Prefix string
+	S3Region string
+	// Retention is how long the logs are kept in the sinks which support pruning, zero means the logs are kept
+	// forever. The logs in S3 are expired by the lifecycle rules of the bucket instead.
+	Retention time.Duration
+}
+
+// NewArchiver builds the Archiver of the sink, it returns nil if no sink is set
+func NewArchiver(opts Options, k8sClient client.Client) (Archiver, error) {
+	switch opts.Sink {
+	case "":
+		return nil, nil
+	case SinkConfigMap:
+		return &ConfigMapArchiver{Client: k8sClient}, nil
+	case SinkS3:
+		if opts.S3Bucket == "" {
+			return nil, errors.New("the bucket is required to archive the logs in S3")
+		}
+		return NewS3Archiver(opts.S3Bucket, opts.S3Prefix, opts.S3Region)
+	default:
+		return nil, fmt.Errorf("unsupported sink %s for the logs, only %s or %s is supported", opts.Sink, SinkConfigMap, SinkS3)
+	}
+}
+
+func compress(logs string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(logs)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
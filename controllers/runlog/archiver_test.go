@@ -0,0 +1,155 @@
+package runlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+func decompress(t *testing.T, data []byte) string {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	assert.Nil(t, err)
+	logs, err := io.ReadAll(r)
+	assert.Nil(t, err)
+	return string(logs)
+}
+
+func TestNewArchiver(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+
+	archiver, err := NewArchiver(Options{}, k8sClient)
+	assert.Nil(t, err)
+	assert.Nil(t, archiver)
+
+	archiver, err = NewArchiver(Options{Sink: SinkConfigMap}, k8sClient)
+	assert.Nil(t, err)
+	assert.IsType(t, &ConfigMapArchiver{}, archiver)
+
+	_, err = NewArchiver(Options{Sink: SinkS3}, k8sClient)
+	assert.EqualError(t, err, "the bucket is required to archive the logs in S3")
+
+	_, err = NewArchiver(Options{Sink: "oss"}, k8sClient)
+	assert.EqualError(t, err, "unsupported sink oss for the logs, only configmap or s3 is supported")
+}
+
+func TestConfigMapArchiver(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().Build()
+	archiver := &ConfigMapArchiver{Client: k8sClient}
+	labels := map[string]string{"terraform.core.oam.dev/owned-by": "a"}
+
+	ref, err := archiver.Archive(ctx, Run{ID: "apply-1", Namespace: "default", Name: "a", UID: "uid-a", JobNamespace: "vela-system", Labels: labels}, "Apply complete!")
+	assert.Nil(t, err)
+	assert.Equal(t, "configmap://vela-system/run-log-uid-a-apply-1", ref)
+	var cm v1.ConfigMap
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "run-log-uid-a-apply-1-0", Namespace: "vela-system"}, &cm))
+	assert.Equal(t, labels, cm.Labels)
+	assert.Equal(t, "Apply complete!", decompress(t, cm.BinaryData[logsKey]))
+
+	// The logs which can't be compressed well are split into chunks
+	random := make([]byte, maxChunkSize*2)
+	_, err = rand.Read(random)
+	assert.Nil(t, err)
+	logs := base64.StdEncoding.EncodeToString(random)
+	ref, err = archiver.Archive(ctx, Run{ID: "apply-2", Namespace: "default", Name: "a", JobNamespace: "vela-system"}, logs)
+	assert.Nil(t, err)
+	assert.Equal(t, "configmap://vela-system/run-log-default-a-apply-2", ref)
+	var cms v1.ConfigMapList
+	assert.Nil(t, k8sClient.List(ctx, &cms))
+	assert.Len(t, cms.Items, 4)
+	var data []byte
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("run-log-default-a-apply-2-%d", i), Namespace: "vela-system"}, &cm))
+		data = append(data, cm.BinaryData[logsKey]...)
+	}
+	assert.Equal(t, logs, decompress(t, data))
+
+	// The logs archived again overwrite the chunks, and the stale chunks are removed
+	run := Run{ID: "apply-2", Namespace: "default", Name: "a", JobNamespace: "vela-system", Labels: labels}
+	ref, err = archiver.Archive(ctx, run, "Apply complete!")
+	assert.Nil(t, err)
+	assert.Equal(t, "configmap://vela-system/run-log-default-a-apply-2", ref)
+	assert.Nil(t, k8sClient.List(ctx, &cms))
+	assert.Len(t, cms.Items, 2)
+	assert.Nil(t, k8sClient.Get(ctx, client.ObjectKey{Name: "run-log-default-a-apply-2-0", Namespace: "vela-system"}, &cm))
+	assert.Equal(t, labels, cm.Labels)
+	assert.Equal(t, "Apply complete!", decompress(t, cm.BinaryData[logsKey]))
+
+	assert.Nil(t, archiver.Delete(ctx, ref))
+	assert.Nil(t, k8sClient.List(ctx, &cms))
+	assert.Len(t, cms.Items, 1)
+
+	assert.Nil(t, archiver.Delete(ctx, "s3://bucket/key"))
+	assert.EqualError(t, archiver.Delete(ctx, "configmap://invalid"), "invalid reference of the logs: configmap://invalid")
+}
+
+func TestConfigMapArchiverPrune(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	logCM := func(name string, created time.Time, labels map[string]string) *v1.ConfigMap {
+		return &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vela-system", Labels: labels, CreationTimestamp: metav1.NewTime(created)}}
+	}
+	runLog := map[string]string{types.LabelRunLogOf: "a"}
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		logCM("run-log-a-apply-1-0", now.Add(-48*time.Hour), runLog),
+		logCM("run-log-a-apply-2-0", now.Add(-time.Hour), runLog),
+		logCM("other", now.Add(-48*time.Hour), nil),
+	).Build()
+	archiver := &ConfigMapArchiver{Client: k8sClient}
+
+	assert.Nil(t, archiver.Prune(ctx, now.Add(-24*time.Hour)))
+	var cms v1.ConfigMapList
+	assert.Nil(t, k8sClient.List(ctx, &cms))
+	var names []string
+	for _, cm := range cms.Items {
+		names = append(names, cm.Name)
+	}
+	assert.ElementsMatch(t, []string{"run-log-a-apply-2-0", "other"}, names)
+
+	assert.NotNil(t, NewRetention(archiver, 24*time.Hour))
+	assert.Nil(t, NewRetention(archiver, 0))
+	assert.Nil(t, NewRetention(&S3Archiver{}, 24*time.Hour))
+}
+
+type mockS3Client struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (m *mockS3Client) PutObjectWithContext(_ context.Context, input *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.objects[*input.Bucket+"/"+*input.Key] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3Archiver(t *testing.T) {
+	ctx := context.Background()
+	mock := &mockS3Client{objects: map[string][]byte{}}
+	archiver := &S3Archiver{client: mock, Bucket: "logs", Prefix: "terraform"}
+
+	ref, err := archiver.Archive(ctx, Run{ID: "destroy-1", Namespace: "default", Name: "a"}, "Destroy complete!")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3://logs/terraform/default/a/destroy-1.log.gz", ref)
+	assert.Equal(t, "Destroy complete!", decompress(t, mock.objects["logs/terraform/default/a/destroy-1.log.gz"]))
+	assert.Nil(t, archiver.Delete(ctx, ref))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runlog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+)
+
+const (
+	configMapRefPrefix = "configmap://"
+	// logsKey is the key of the gzipped logs in the binary data of the ConfigMaps
+	logsKey = "logs.gz"
+	// maxChunkSize keeps every ConfigMap below the 1 MiB limit of the objects in etcd
+	maxChunkSize = 900 * 1024
+)
+
+// ConfigMapArchiver archives the gzipped logs in the ConfigMaps in the namespace of the Terraform Job. The logs
+// which are larger than a ConfigMap can hold are split into chunks named <name>-0, <name>-1 and so on.
+type ConfigMapArchiver struct {
+	Client client.Client
+}
+
+// Archive stores the logs of the run in ConfigMaps
+func (a *ConfigMapArchiver) Archive(ctx context.Context, run Run, logs string) (string, error) {
+	data, err := compress(logs)
+	if err != nil {
+		return "", err
+	}
+	owner := run.UID
+	if owner == "" {
+		owner = fmt.Sprintf("%s-%s", run.Namespace, run.Name)
+	}
+	name := fmt.Sprintf("run-log-%s-%s", owner, run.ID)
+	i := 0
+	for ; i == 0 || len(data) > 0; i++ {
+		size := len(data)
+		if size > maxChunkSize {
+			size = maxChunkSize
+		}
+		cm := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("%s-%d", name, i),
+				Namespace: run.JobNamespace,
+				Labels:    run.Labels,
+			},
+			BinaryData: map[string][]byte{logsKey: data[:size]},
+		}
+		if err := a.writeChunk(ctx, cm); err != nil {
+			return "", err
+		}
+		data = data[size:]
+	}
+	// The logs archived again may be shorter, so the chunks beyond them are stale
+	if err := a.deleteChunks(ctx, run.JobNamespace, name, i); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s/%s", configMapRefPrefix, run.JobNamespace, name), nil
+}
+
+// writeChunk creates the chunk of the logs, or overwrites the existing one if the logs of the run are archived again
+func (a *ConfigMapArchiver) writeChunk(ctx context.Context, cm *v1.ConfigMap) error {
+	err := a.Client.Create(ctx, cm)
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	var existing v1.ConfigMap
+	if err := a.Client.Get(ctx, client.ObjectKeyFromObject(cm), &existing); err != nil {
+		return err
+	}
+	existing.Labels = cm.Labels
+	existing.Data = nil
+	existing.BinaryData = cm.BinaryData
+	return a.Client.Update(ctx, &existing)
+}
+
+// deleteChunks removes the chunks of the logs from the index on
+func (a *ConfigMapArchiver) deleteChunks(ctx context.Context, namespace, name string, from int) error {
+	for i := from; ; i++ {
+		var cm v1.ConfigMap
+		if err := a.Client.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s-%d", name, i), Namespace: namespace}, &cm); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if err := a.Client.Delete(ctx, &cm); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+}
+
+// Delete removes all the chunks of the logs
+func (a *ConfigMapArchiver) Delete(ctx context.Context, ref string) error {
+	if !strings.HasPrefix(ref, configMapRefPrefix) {
+		return nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(ref, configMapRefPrefix), "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid reference of the logs: %s", ref)
+	}
+	return a.deleteChunks(ctx, parts[0], parts[1], 0)
+}
+
+// Prune removes the chunks of the logs created before the time in all namespaces, so the logs are found even if the
+// controller namespace has changed since they were archived
+func (a *ConfigMapArchiver) Prune(ctx context.Context, before time.Time) error {
+	var cms v1.ConfigMapList
+	if err := a.Client.List(ctx, &cms, client.HasLabels{types.LabelRunLogOf}); err != nil {
+		return err
+	}
+	for i := range cms.Items {
+		cm := &cms.Items[i]
+		if !cm.CreationTimestamp.Time.Before(before) {
+			continue
+		}
+		if err := a.Client.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
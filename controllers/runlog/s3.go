@@ -0,0 +1,76 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package runlog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// S3Archiver archives the gzipped logs in an S3 bucket, the objects are named
+// <prefix>/<namespace>/<name>/<run ID>.log.gz
+type S3Archiver struct {
+	client s3iface.S3API
+	Bucket string
+	Prefix string
+}
+
+// NewS3Archiver builds an S3Archiver, the credentials are read from the environment of the controller, like
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or the IAM role of its service account
+func NewS3Archiver(bucket, prefix, region string) (*S3Archiver, error) {
+	sessionOpts := session.Options{SharedConfigState: session.SharedConfigEnable}
+	if region != "" {
+		sessionOpts.Config.Region = aws.String(region)
+	}
+	sess, err := session.NewSessionWithOptions(sessionOpts)
+	if err != nil {
+		return nil, fmt.Errorf("fail to build the s3 client to archive the logs: %w", err)
+	}
+	return &S3Archiver{client: s3.New(sess), Bucket: bucket, Prefix: prefix}, nil
+}
+
+// Archive uploads the logs of the run to the bucket
+func (a *S3Archiver) Archive(ctx context.Context, run Run, logs string) (string, error) {
+	data, err := compress(logs)
+	if err != nil {
+		return "", err
+	}
+	key := path.Join(a.Prefix, run.Namespace, run.Name, run.ID+".log.gz")
+	if _, err := a.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(a.Bucket),
+		Key:             aws.String(key),
+		Body:            bytes.NewReader(data),
+		ContentType:     aws.String("text/plain"),
+		ContentEncoding: aws.String("gzip"),
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("s3://%s/%s", a.Bucket, key), nil
+}
+
+// Delete keeps the logs in the bucket, as S3 is for the long-term archive. Use the lifecycle rules of the bucket to
+// expire them.
+func (a *S3Archiver) Delete(_ context.Context, _ string) error {
+	return nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stack
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
+)
+
+// ConfigurationName is the name of the Configuration created for the Configuration `name` in the Stack
+func ConfigurationName(stack *v1beta2.Stack, name string) string {
+	return fmt.Sprintf("%s-%s", stack.Name, name)
+}
+
+// Plan is the Configurations in a Stack sorted in topological order, along with their dependencies in the Stack
+type Plan struct {
+	Configurations []v1beta2.StackConfiguration
+	Dependencies   map[string][]string
+}
+
+// Dependents returns the names of the Configurations in the Stack which depend on the Configuration `name`
+func (p *Plan) Dependents(name string) []string {
+	var dependents []string
+	for _, c := range p.Configurations {
+		for _, dep := range p.Dependencies[c.Name] {
+			if dep == name {
+				dependents = append(dependents, c.Name)
+			}
+		}
+	}
+	return dependents
+}
+
+// NewPlan validates the Configurations in the Stack and sorts them, so that a Configuration always comes after the
+// ones it depends on. The order in spec.configurations is kept as much as possible.
+func NewPlan(stack *v1beta2.Stack) (*Plan, error) {
+	names := make(map[string]bool, len(stack.Spec.Configurations))
+	for _, c := range stack.Spec.Configurations {
+		if c.Name == "" {
+			return nil, errors.New("the name of a Configuration in the Stack is empty")
+		}
+		if names[c.Name] {
+			return nil, errors.Errorf("Configuration %q is duplicated in the Stack", c.Name)
+		}
+		names[c.Name] = true
+	}
+
+	dependencies := make(map[string][]string, len(stack.Spec.Configurations))
+	for _, c := range stack.Spec.Configurations {
+		deps, err := getDependencies(c, names)
+		if err != nil {
+			return nil, err
+		}
+		dependencies[c.Name] = deps
+	}
+
+	var (
+		sorted []v1beta2.StackConfiguration
+		done   = make(map[string]bool, len(stack.Spec.Configurations))
+	)
+	for len(sorted) < len(stack.Spec.Configurations) {
+		progressed := false
+		for _, c := range stack.Spec.Configurations {
+			if done[c.Name] || !allDone(dependencies[c.Name], done) {
+				continue
+			}
+			sorted = append(sorted, c)
+			done[c.Name] = true
+			progressed = true
+		}
+		if !progressed {
+			var cycle []string
+			for _, c := range stack.Spec.Configurations {
+				if !done[c.Name] {
+					cycle = append(cycle, c.Name)
+				}
+			}
+			return nil, errors.Errorf("there is a circular dependency among Configurations %s", strings.Join(cycle, ", "))
+		}
+	}
+	return &Plan{Configurations: sorted, Dependencies: dependencies}, nil
+}
+
+func allDone(names []string, done map[string]bool) bool {
+	for _, name := range names {
+		if !done[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// getDependencies returns the names of the Configurations in the Stack which the Configuration depends on, including
+// the ones in dependsOn and the ones whose outputs are referenced
+func getDependencies(c v1beta2.StackConfiguration, names map[string]bool) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, dep := range c.DependsOn {
+		if !names[dep] {
+			return nil, errors.Errorf("Configuration %q depends on %q, which is not in the Stack", c.Name, dep)
+		}
+		seen[dep] = true
+	}
+	variables, err := tfcfg.RawExtension2Map(c.Spec.Variable)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range variables {
+		if ref := getOutputReference(v); ref != nil && names[fmt.Sprint(ref["name"])] {
+			seen[fmt.Sprint(ref["name"])] = true
+		}
+	}
+	if seen[c.Name] {
+		return nil, errors.Errorf("Configuration %q could not depend on itself", c.Name)
+	}
+
+	deps := make([]string, 0, len(seen))
+	for dep := range seen {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// getOutputReference returns the `configurationOutput` of a variable value if it references an output of a
+// Configuration in the same namespace without specifying the namespace
+func getOutputReference(value interface{}) map[string]interface{} {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	valueFrom, ok := m["valueFrom"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	ref, ok := valueFrom["configurationOutput"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if ns, ok := ref["namespace"]; ok && ns != "" {
+		return nil
+	}
+	return ref
+}
+
+// RenderConfiguration renders the Configuration `c` in the Stack. The references to the other Configurations in the
+// Stack are replaced with the names of the created Configurations.
+func (p *Plan) RenderConfiguration(stack *v1beta2.Stack, c v1beta2.StackConfiguration) (*v1beta2.Configuration, error) {
+	spec := c.Spec.DeepCopy()
+	for _, dep := range p.Dependencies[c.Name] {
+		spec.DependsOn = append(spec.DependsOn, v1beta2.ConfigurationReference{Name: ConfigurationName(stack, dep)})
+	}
+
+	if spec.Variable != nil {
+		variables, err := tfcfg.RawExtension2Map(spec.Variable)
+		if err != nil {
+			return nil, err
+		}
+		rewritten := false
+		for _, v := range variables {
+			ref := getOutputReference(v)
+			if ref == nil {
+				continue
+			}
+			name := fmt.Sprint(ref["name"])
+			if _, ok := p.Dependencies[name]; ok {
+				ref["name"] = ConfigurationName(stack, name)
+				rewritten = true
+			}
+		}
+		if rewritten {
+			data, err := json.Marshal(variables)
+			if err != nil {
+				return nil, err
+			}
+			spec.Variable = &runtime.RawExtension{Raw: data}
+		}
+	}
+
+	return &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ConfigurationName(stack, c.Name),
+			Namespace: stack.Namespace,
+			Labels: map[string]string{
+				types.LabelStack:              stack.Name,
+				types.LabelStackConfiguration: c.Name,
+			},
+		},
+		Spec: *spec,
+	}, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func newStack(configurations ...v1beta2.StackConfiguration) *v1beta2.Stack {
+	return &v1beta2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       v1beta2.StackSpec{Configurations: configurations},
+	}
+}
+
+func outputReference(variable string) v1beta2.ConfigurationSpec {
+	return v1beta2.ConfigurationSpec{Variable: &runtime.RawExtension{Raw: []byte(variable)}}
+}
+
+func TestNewPlan(t *testing.T) {
+	testcases := map[string]struct {
+		stack  *v1beta2.Stack
+		order  []string
+		deps   map[string][]string
+		errMsg string
+	}{
+		"sorted by dependencies": {
+			stack: newStack(
+				v1beta2.StackConfiguration{Name: "app", DependsOn: []string{"db"}},
+				v1beta2.StackConfiguration{Name: "db", Spec: outputReference(`{"vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"id"}}}}`)},
+				v1beta2.StackConfiguration{Name: "vpc"},
+			),
+			order: []string{"vpc", "db", "app"},
+			deps:  map[string][]string{"app": {"db"}, "db": {"vpc"}, "vpc": {}},
+		},
+		"reference to a Configuration out of the Stack": {
+			stack: newStack(
+				v1beta2.StackConfiguration{Name: "db", Spec: outputReference(`{"vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","namespace":"network","key":"id"}}}}`)},
+				v1beta2.StackConfiguration{Name: "vpc"},
+			),
+			order: []string{"db", "vpc"},
+			deps:  map[string][]string{"db": {}, "vpc": {}},
+		},
+		"duplicated names": {
+			stack:  newStack(v1beta2.StackConfiguration{Name: "vpc"}, v1beta2.StackConfiguration{Name: "vpc"}),
+			errMsg: `Configuration "vpc" is duplicated in the Stack`,
+		},
+		"unknown dependency": {
+			stack:  newStack(v1beta2.StackConfiguration{Name: "db", DependsOn: []string{"vpc"}}),
+			errMsg: `Configuration "db" depends on "vpc", which is not in the Stack`,
+		},
+		"depend on itself": {
+			stack:  newStack(v1beta2.StackConfiguration{Name: "db", DependsOn: []string{"db"}}),
+			errMsg: `Configuration "db" could not depend on itself`,
+		},
+		"circular dependency": {
+			stack: newStack(
+				v1beta2.StackConfiguration{Name: "a", DependsOn: []string{"b"}},
+				v1beta2.StackConfiguration{Name: "b", DependsOn: []string{"a"}},
+				v1beta2.StackConfiguration{Name: "c"},
+			),
+			errMsg: "there is a circular dependency among Configurations a, b",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			plan, err := NewPlan(tc.stack)
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+				return
+			}
+			assert.Nil(t, err)
+			var order []string
+			for _, c := range plan.Configurations {
+				order = append(order, c.Name)
+			}
+			assert.Equal(t, tc.order, order)
+			assert.Equal(t, tc.deps, plan.Dependencies)
+		})
+	}
+}
+
+func TestRenderConfiguration(t *testing.T) {
+	s := newStack(
+		v1beta2.StackConfiguration{Name: "vpc"},
+		v1beta2.StackConfiguration{
+			Name:      "db",
+			DependsOn: []string{"vpc"},
+			Spec: v1beta2.ConfigurationSpec{
+				HCL:      "c",
+				Variable: &runtime.RawExtension{Raw: []byte(`{"name":"db","vpc_id":{"valueFrom":{"configurationOutput":{"name":"vpc","key":"id"}}}}`)},
+			},
+		},
+	)
+	plan, err := NewPlan(s)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"db"}, plan.Dependents("vpc"))
+
+	configuration, err := plan.RenderConfiguration(s, s.Spec.Configurations[1])
+	assert.Nil(t, err)
+	assert.Equal(t, "app-db", configuration.Name)
+	assert.Equal(t, "default", configuration.Namespace)
+	assert.Equal(t, map[string]string{types.LabelStack: "app", types.LabelStackConfiguration: "db"}, configuration.Labels)
+	assert.Equal(t, []v1beta2.ConfigurationReference{{Name: "app-vpc"}}, configuration.Spec.DependsOn)
+	assert.JSONEq(t, `{"name":"db","vpc_id":{"valueFrom":{"configurationOutput":{"name":"app-vpc","key":"id"}}}}`,
+		string(configuration.Spec.Variable.Raw))
+	assert.Empty(t, s.Spec.Configurations[1].Spec.DependsOn, "the template should not be changed")
+}
@@ -0,0 +1,299 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
+	"github.com/oam-dev/terraform-controller/controllers/stack"
+)
+
+const stackFinalizer = "stack.finalizers.terraform-controller"
+
+// StackReconciler reconciles a Stack object
+type StackReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=stacks,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=stacks/status,verbs=get;update;patch
+
+// Reconcile creates the Configurations in the Stack in topological order, and deletes them in the reverse order
+func (r *StackReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	klog.InfoS("reconciling Terraform Stack...", "NamespacedName", req.NamespacedName)
+
+	var s v1beta2.Stack
+	if err := r.Get(ctx, req.NamespacedName, &s); err != nil {
+		if kerrors.IsNotFound(err) {
+			err = nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	children, err := r.getConfigurations(ctx, &s)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !s.DeletionTimestamp.IsZero() {
+		return r.delete(ctx, &s, children)
+	}
+
+	if !controllerutil.ContainsFinalizer(&s, stackFinalizer) {
+		controllerutil.AddFinalizer(&s, stackFinalizer)
+		if err := r.Update(ctx, &s); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to add finalizer")
+		}
+	}
+
+	plan, err := stack.NewPlan(&s)
+	if err != nil {
+		klog.ErrorS(err, "Stack is not valid", "NamespacedName", req.NamespacedName)
+		return ctrl.Result{}, r.updateStatus(ctx, &s, types.StackSpecNotValid, err.Error(), nil)
+	}
+
+	statuses := make([]v1beta2.StackConfigurationStatus, 0, len(plan.Configurations))
+	for _, c := range plan.Configurations {
+		status, err := r.apply(ctx, &s, plan, c, children)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		statuses = append(statuses, status)
+	}
+
+	// Prune the Configurations removed from the Stack
+	for name, child := range children {
+		if _, ok := plan.Dependencies[name]; ok || !child.DeletionTimestamp.IsZero() {
+			continue
+		}
+		klog.InfoS("deleting the Configuration removed from the Stack", "Namespace", child.Namespace, "Name", child.Name)
+		if err := r.Delete(ctx, child); err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+
+	state, message := aggregateState(statuses)
+	return ctrl.Result{}, r.updateStatus(ctx, &s, state, message, statuses)
+}
+
+// apply creates or updates the Configuration `c` when all the Configurations it depends on are Available
+func (r *StackReconciler) apply(ctx context.Context, s *v1beta2.Stack, plan *stack.Plan, c v1beta2.StackConfiguration,
+	children map[string]*v1beta2.Configuration) (v1beta2.StackConfigurationStatus, error) {
+	status := v1beta2.StackConfigurationStatus{Name: c.Name, ConfigurationName: stack.ConfigurationName(s, c.Name)}
+	desired, err := plan.RenderConfiguration(s, c)
+	if err != nil {
+		status.State = types.ConfigurationStaticCheckFailed
+		status.Message = err.Error()
+		return status, nil
+	}
+
+	existing, ok := children[c.Name]
+	if !ok {
+		var waiting []string
+		for _, dep := range plan.Dependencies[c.Name] {
+			if child, ok := children[dep]; !ok || child.Status.Apply.State != types.Available {
+				waiting = append(waiting, dep)
+			}
+		}
+		if len(waiting) != 0 {
+			status.State = types.WaitingForDependencies
+			status.Message = fmt.Sprintf("%s: %s", types.MessageWaitingForDependencies, strings.Join(waiting, ", "))
+			return status, nil
+		}
+		if err := controllerutil.SetControllerReference(s, desired, r.Scheme); err != nil {
+			return status, err
+		}
+		klog.InfoS("creating the Configuration in the Stack", "Namespace", desired.Namespace, "Name", desired.Name)
+		if err := r.Create(ctx, desired); err != nil {
+			return status, errors.Wrapf(err, "failed to create Configuration %s", desired.Name)
+		}
+		children[c.Name] = desired
+		status.State = types.Authorizing
+		return status, nil
+	}
+
+	if specChanged(existing.Spec, desired.Spec) {
+		existing.Spec = desired.Spec
+		if err := r.Update(ctx, existing); err != nil {
+			return status, errors.Wrapf(err, "failed to update Configuration %s", existing.Name)
+		}
+	}
+	status.State = existing.Status.Apply.State
+	status.Message = existing.Status.Apply.Message
+	if existing.Status.ObservedGeneration != existing.Generation && status.State == types.Available {
+		status.State = types.ConfigurationReloading
+	}
+	return status, nil
+}
+
+// specChanged tells whether the spec of the Configuration differs from the one rendered by the Stack. The variables
+// and JobEnv are compared after being decoded, as the API server re-serializes them, and the fields which the Stack
+// leaves empty but the API server defaults are ignored.
+func specChanged(existing, desired v1beta2.ConfigurationSpec) bool {
+	if !rawExtensionEqual(existing.Variable, desired.Variable) || !rawExtensionEqual(existing.JobEnv, desired.JobEnv) {
+		return true
+	}
+	existing.Variable, desired.Variable = nil, nil
+	existing.JobEnv, desired.JobEnv = nil, nil
+	if desired.DeleteResource == nil {
+		existing.DeleteResource = nil
+	}
+	return !equality.Semantic.DeepEqual(existing, desired)
+}
+
+func rawExtensionEqual(a, b *runtime.RawExtension) bool {
+	decode := func(raw *runtime.RawExtension) (interface{}, error) {
+		var v interface{}
+		if raw == nil || len(raw.Raw) == 0 {
+			return v, nil
+		}
+		err := json.Unmarshal(raw.Raw, &v)
+		return v, err
+	}
+	va, errA := decode(a)
+	vb, errB := decode(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// delete deletes the Configurations which no other Configurations in the Stack depend on, until all of them are gone
+func (r *StackReconciler) delete(ctx context.Context, s *v1beta2.Stack, children map[string]*v1beta2.Configuration) (ctrl.Result, error) {
+	if len(children) == 0 {
+		if controllerutil.ContainsFinalizer(s, stackFinalizer) {
+			controllerutil.RemoveFinalizer(s, stackFinalizer)
+			if err := r.Update(ctx, s); err != nil {
+				return ctrl.Result{}, errors.Wrap(err, "failed to remove finalizer")
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	plan, err := stack.NewPlan(s)
+	for name, child := range children {
+		if !child.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err == nil && hasRemainingDependents(plan, name, children) {
+			continue
+		}
+		klog.InfoS("deleting the Configuration in the Stack", "Namespace", child.Namespace, "Name", child.Name)
+		if err := r.Delete(ctx, child); err != nil && !kerrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+	}
+
+	remaining := make([]string, 0, len(children))
+	for name := range children {
+		remaining = append(remaining, name)
+	}
+	sort.Strings(remaining)
+	message := fmt.Sprintf("Waiting for the Configurations to be deleted: %s", strings.Join(remaining, ", "))
+	if err := r.updateStatus(ctx, s, types.StackDeleting, message, s.Status.Configurations); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+}
+
+func hasRemainingDependents(plan *stack.Plan, name string, children map[string]*v1beta2.Configuration) bool {
+	for _, dependent := range plan.Dependents(name) {
+		if _, ok := children[dependent]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getConfigurations returns the Configurations created by the Stack, keyed by their names in the Stack
+func (r *StackReconciler) getConfigurations(ctx context.Context, s *v1beta2.Stack) (map[string]*v1beta2.Configuration, error) {
+	var configurations v1beta2.ConfigurationList
+	if err := r.List(ctx, &configurations, client.InNamespace(s.Namespace), client.MatchingLabels{types.LabelStack: s.Name}); err != nil {
+		return nil, errors.Wrap(err, "failed to list the Configurations of the Stack")
+	}
+	children := make(map[string]*v1beta2.Configuration, len(configurations.Items))
+	for i := range configurations.Items {
+		c := &configurations.Items[i]
+		if !metav1.IsControlledBy(c, s) {
+			continue
+		}
+		children[c.Labels[types.LabelStackConfiguration]] = c
+	}
+	return children, nil
+}
+
+func (r *StackReconciler) updateStatus(ctx context.Context, s *v1beta2.Stack, state types.StackState, message string,
+	configurations []v1beta2.StackConfigurationStatus) error {
+	s.Status.ObservedGeneration = s.Generation
+	s.Status.State = state
+	s.Status.Message = message
+	s.Status.Configurations = configurations
+	if err := r.Status().Update(ctx, s); err != nil {
+		return errors.Wrap(err, errSettingStatus)
+	}
+	return nil
+}
+
+// aggregateState reports the Stack as Failed if any Configuration failed, and Available if all of them are Available
+func aggregateState(statuses []v1beta2.StackConfigurationStatus) (types.StackState, string) {
+	var failed, pending []string
+	for _, status := range statuses {
+		switch {
+		case tfcfg.IsFailedState(status.State):
+			failed = append(failed, status.Name)
+		case status.State != types.Available:
+			pending = append(pending, status.Name)
+		}
+	}
+	if len(failed) != 0 {
+		return types.StackFailed, fmt.Sprintf("Configurations failed: %s", strings.Join(failed, ", "))
+	}
+	if len(pending) != 0 {
+		return types.StackProvisioning, fmt.Sprintf("Configurations are being provisioned: %s", strings.Join(pending, ", "))
+	}
+	return types.StackAvailable, "All the Configurations are available"
+}
+
+// SetupWithManager setups with a manager
+func (r *StackReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta2.Stack{}).
+		Owns(&v1beta2.Configuration{}).
+		Complete(r)
+}
@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestStackReconcile(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+
+	stack := &v1beta2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "stack-uid"},
+		Spec: v1beta2.StackSpec{
+			Configurations: []v1beta2.StackConfiguration{
+				{Name: "db", DependsOn: []string{"vpc"}, Spec: v1beta2.ConfigurationSpec{HCL: "db"}},
+				{Name: "vpc", Spec: v1beta2.ConfigurationSpec{HCL: "vpc"}},
+			},
+		},
+	}
+	r := &StackReconciler{Scheme: s}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(stack).
+		WithStatusSubresource(stack, &v1beta2.Configuration{}).Build()
+	req := ctrl.Request{NamespacedName: k8stypes.NamespacedName{Name: "app", Namespace: "default"}}
+
+	getConfiguration := func(name string) (*v1beta2.Configuration, error) {
+		var c v1beta2.Configuration
+		err := r.Get(ctx, k8stypes.NamespacedName{Name: name, Namespace: "default"}, &c)
+		return &c, err
+	}
+	getStack := func() *v1beta2.Stack {
+		var got v1beta2.Stack
+		assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+		return &got
+	}
+
+	// Only the Configuration without dependencies is created
+	_, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	vpc, err := getConfiguration("app-vpc")
+	assert.Nil(t, err)
+	assert.True(t, metav1.IsControlledBy(vpc, getStack()))
+	_, err = getConfiguration("app-db")
+	assert.NotNil(t, err)
+	got := getStack()
+	assert.Contains(t, got.Finalizers, stackFinalizer)
+	assert.Equal(t, types.StackProvisioning, got.Status.State)
+	assert.Equal(t, []v1beta2.StackConfigurationStatus{
+		{Name: "vpc", ConfigurationName: "app-vpc", State: types.Authorizing},
+		{Name: "db", ConfigurationName: "app-db", State: types.WaitingForDependencies, Message: types.MessageWaitingForDependencies + ": vpc"},
+	}, got.Status.Configurations)
+
+	// The dependent is created once the dependency is Available
+	vpc.Status.Apply.State = types.Available
+	vpc.Status.ObservedGeneration = vpc.Generation
+	assert.Nil(t, r.Status().Update(ctx, vpc))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	db, err := getConfiguration("app-db")
+	assert.Nil(t, err)
+	assert.Equal(t, []v1beta2.ConfigurationReference{{Name: "app-vpc"}}, db.Spec.DependsOn)
+
+	db.Status.Apply.State = types.ConfigurationApplyFailed
+	assert.Nil(t, r.Status().Update(ctx, db))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, types.StackFailed, getStack().Status.State)
+
+	db.Status.Apply.State = types.Available
+	db.Status.ObservedGeneration = db.Generation
+	assert.Nil(t, r.Status().Update(ctx, db))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, types.StackAvailable, getStack().Status.State)
+
+	// The Configurations are deleted in the reverse order
+	assert.Nil(t, r.Delete(ctx, getStack()))
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 10*time.Second, result.RequeueAfter)
+	_, err = getConfiguration("app-db")
+	assert.NotNil(t, err)
+	_, err = getConfiguration("app-vpc")
+	assert.Nil(t, err)
+	assert.Equal(t, types.StackDeleting, getStack().Status.State)
+
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	_, err = getConfiguration("app-vpc")
+	assert.NotNil(t, err)
+
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.NotNil(t, r.Get(ctx, req.NamespacedName, &v1beta2.Stack{}))
+}
+
+func TestStackReconcileInvalidSpec(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+
+	stack := &v1beta2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: v1beta2.StackSpec{
+			Configurations: []v1beta2.StackConfiguration{
+				{Name: "a", DependsOn: []string{"b"}},
+				{Name: "b", DependsOn: []string{"a"}},
+			},
+		},
+	}
+	r := &StackReconciler{Scheme: s}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(stack).WithStatusSubresource(stack).Build()
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(stack)})
+	assert.Nil(t, err)
+	var got v1beta2.Stack
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(stack), &got))
+	assert.Equal(t, types.StackSpecNotValid, got.Status.State)
+	assert.Equal(t, "there is a circular dependency among Configurations a, b", got.Status.Message)
+	var configurations v1beta2.ConfigurationList
+	assert.Nil(t, r.List(ctx, &configurations))
+	assert.Empty(t, configurations.Items)
+}
+
+func TestSpecChanged(t *testing.T) {
+	desired := v1beta2.ConfigurationSpec{
+		HCL:      "vpc",
+		Variable: &runtime.RawExtension{Raw: []byte(`{"name":"a","tags":{"env":"prod"}}`)},
+	}
+	deleteResource := true
+
+	testcases := map[string]struct {
+		existing v1beta2.ConfigurationSpec
+		want     bool
+	}{
+		"re-serialized variables and defaulted fields": {
+			existing: v1beta2.ConfigurationSpec{
+				HCL:            "vpc",
+				Variable:       &runtime.RawExtension{Raw: []byte(`{"tags": {"env": "prod"}, "name": "a"}`)},
+				DeleteResource: &deleteResource,
+				DependsOn:      []v1beta2.ConfigurationReference{},
+			},
+		},
+		"changed variables": {
+			existing: v1beta2.ConfigurationSpec{
+				HCL:      "vpc",
+				Variable: &runtime.RawExtension{Raw: []byte(`{"name":"b","tags":{"env":"prod"}}`)},
+			},
+			want: true,
+		},
+		"changed HCL": {
+			existing: v1beta2.ConfigurationSpec{
+				HCL:      "db",
+				Variable: &runtime.RawExtension{Raw: []byte(`{"name":"a","tags":{"env":"prod"}}`)},
+			},
+			want: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, specChanged(tc.existing, desired))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+const (
+	// SeverityError is the severity of a Terraform error diagnostic
+	SeverityError = "error"
+	// SeverityWarning is the severity of a Terraform warning diagnostic
+	SeverityWarning = "warning"
+
+	// maxDiagnostics is the maximum number of diagnostics kept in the status
+	maxDiagnostics = 10
+	// maxDiagnosticDetailLength is the maximum length of the detail of a diagnostic kept in the status
+	maxDiagnosticDetailLength = 1024
+)
+
+// errorClasses maps the known classes of cloud errors to the states, the first matched class wins. The patterns match
+// the error codes of the cloud providers and the exact messages of Terraform, so that the errors about the
+// configuration itself, like `Unsupported argument "timeout"`, aren't taken as cloud errors.
+var errorClasses = []struct {
+	state   types.ConfigurationState
+	pattern *regexp.Regexp
+}{
+	{types.InvalidRegion, regexp.MustCompile(`\bInvalidRegion(Id)?(\.\w+)?\b|(?i:\binvalid (alibaba cloud |aws |azure )?region\b|\bunknown region\b)`)},
+	{types.AuthenticationFailed, regexp.MustCompile(`\b(InvalidAccessKeyId|InvalidClientTokenId|SignatureDoesNotMatch|AuthFailure|ExpiredToken|UnrecognizedClientException|InvalidSecurityToken|AuthorizationFailed|InvalidAuthenticationToken)(\.\w+)?\b|(?i:\bno valid credential sources\b)`)},
+	{types.QuotaExceeded, regexp.MustCompile(`\b\w*(LimitExceeded|QuotaExceeded)(\.\w+)?\b|\b(Throttling|ThrottlingException|TooManyRequests|InsufficientBalance|InsufficientInstanceCapacity)(\.\w+)?\b`)},
+	{types.ResourceConflict, regexp.MustCompile(`\b\w*AlreadyExists?(\.\w+)?\b|\b(ResourceInUse|ResourceInUseException|ConflictException|DependencyViolation)(\.\w+)?\b|\bCode="Conflict"`)},
+	{types.OperationTimeout, regexp.MustCompile(`\b(OperationTimeout|RequestTimeout|ServiceTimeout)(\.\w+)?\b|\btimeout while waiting for state\b|\btimeout - last error\b|\bcontext deadline exceeded\b`)},
+}
+
+// jsonLogLine is a line of the machine-readable output of `terraform apply -json` or `terraform destroy -json`
+type jsonLogLine struct {
+	Type       string          `json:"type"`
+	Diagnostic *jsonDiagnostic `json:"diagnostic,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	Address  string `json:"address"`
+	Range    *struct {
+		Filename string `json:"filename"`
+		Start    struct {
+			Line int `json:"line"`
+		} `json:"start"`
+	} `json:"range,omitempty"`
+}
+
+// ParseDiagnostics parses the diagnostics from the machine-readable output of Terraform, the lines which are not
+// JSON are ignored
+func ParseDiagnostics(logs string) []v1beta2.Diagnostic {
+	var diagnostics []v1beta2.Diagnostic
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var l jsonLogLine
+		if err := json.Unmarshal([]byte(line), &l); err != nil || l.Type != "diagnostic" || l.Diagnostic == nil {
+			continue
+		}
+		d := v1beta2.Diagnostic{
+			Severity: l.Diagnostic.Severity,
+			Summary:  l.Diagnostic.Summary,
+			Detail:   l.Diagnostic.Detail,
+			Address:  l.Diagnostic.Address,
+		}
+		d.Detail = truncate(d.Detail, maxDiagnosticDetailLength)
+		if l.Diagnostic.Range != nil {
+			d.Filename = l.Diagnostic.Range.Filename
+			d.Line = l.Diagnostic.Range.Start.Line
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	// keep the errors rather than the warnings when there are too many diagnostics
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Severity == SeverityError && diagnostics[j].Severity != SeverityError
+	})
+	if len(diagnostics) > maxDiagnostics {
+		diagnostics = diagnostics[:maxDiagnostics]
+	}
+	return diagnostics
+}
+
+// HasError checks whether there is an error in the diagnostics
+func HasError(diagnostics []v1beta2.Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyDiagnostics maps the first error in the diagnostics which belongs to a known class to its state, or returns
+// types.ConfigurationApplyFailed
+func ClassifyDiagnostics(diagnostics []v1beta2.Diagnostic) types.ConfigurationState {
+	for _, d := range diagnostics {
+		if d.Severity != SeverityError {
+			continue
+		}
+		if state := classifyErrorMessage(d.Summary + "\n" + d.Detail); state != "" {
+			return state
+		}
+	}
+	return types.ConfigurationApplyFailed
+}
+
+// FormatDiagnostics turns the errors in the diagnostics into a short single-line message
+func FormatDiagnostics(diagnostics []v1beta2.Diagnostic) string {
+	var errs []string
+	for _, d := range diagnostics {
+		if d.Severity != SeverityError {
+			continue
+		}
+		msg := d.Summary
+		if d.Detail != "" {
+			msg += ": " + d.Detail
+		}
+		if d.Address != "" {
+			msg = fmt.Sprintf("%s: %s", d.Address, msg)
+		}
+		errs = append(errs, msg)
+	}
+	return TrimErrorMessage("Error: " + strings.Join(errs, "; "))
+}
+
+// IsCloudErrorState checks whether the state is one of the known classes of cloud errors
+func IsCloudErrorState(state types.ConfigurationState) bool {
+	for _, c := range errorClasses {
+		if c.state == state {
+			return true
+		}
+	}
+	return false
+}
+
+func classifyErrorMessage(errMsg string) types.ConfigurationState {
+	for _, c := range errorClasses {
+		if c.pattern.MatchString(errMsg) {
+			return c.state
+		}
+	}
+	return ""
+}
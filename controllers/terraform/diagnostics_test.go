@@ -0,0 +1,119 @@
+package terraform
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	logs := strings.Join([]string{
+		"Initializing the backend...",
+		`{"@level":"info","@message":"Terraform 1.1.5","type":"version","terraform":"1.1.5","ui":"1.0"}`,
+		`{"@level":"warn","@message":"Warning: Deprecated attribute","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":"Use acl instead"}}`,
+		`{"@level":"error","@message":"Error: creating OSS bucket","type":"diagnostic","diagnostic":{"severity":"error","summary":"creating OSS bucket","detail":"BucketAlreadyExists: The requested bucket name is not available","address":"alicloud_oss_bucket.bucket","range":{"filename":"main.tf","start":{"line":5,"column":1,"byte":40},"end":{"line":5,"column":30,"byte":69}}}}`,
+		`{"broken json`,
+	}, "\n")
+	assert.Equal(t, []v1beta2.Diagnostic{
+		{
+			Severity: SeverityError,
+			Summary:  "creating OSS bucket",
+			Detail:   "BucketAlreadyExists: The requested bucket name is not available",
+			Address:  "alicloud_oss_bucket.bucket",
+			Filename: "main.tf",
+			Line:     5,
+		},
+		{
+			Severity: SeverityWarning,
+			Summary:  "Deprecated attribute",
+			Detail:   "Use acl instead",
+		},
+	}, ParseDiagnostics(logs))
+
+	assert.Empty(t, ParseDiagnostics("31mError: colored logs"))
+
+	var lines []string
+	for i := 0; i < maxDiagnostics+5; i++ {
+		lines = append(lines, fmt.Sprintf(`{"type":"diagnostic","diagnostic":{"severity":"error","summary":"error %d","detail":"%s"}}`, i, strings.Repeat("a", 2000)))
+	}
+	diagnostics := ParseDiagnostics(strings.Join(lines, "\n"))
+	assert.Len(t, diagnostics, maxDiagnostics)
+	assert.Len(t, diagnostics[0].Detail, maxDiagnosticDetailLength)
+
+	// The detail is cut without splitting a multi-byte character
+	diagnostics = ParseDiagnostics(fmt.Sprintf(`{"type":"diagnostic","diagnostic":{"severity":"error","summary":"error","detail":"%s"}}`, strings.Repeat("错", 500)))
+	assert.True(t, utf8.ValidString(diagnostics[0].Detail))
+	assert.LessOrEqual(t, len(diagnostics[0].Detail), maxDiagnosticDetailLength)
+}
+
+func TestClassifyDiagnostics(t *testing.T) {
+	testcases := map[string]struct {
+		diagnostics []v1beta2.Diagnostic
+		want        types.ConfigurationState
+	}{
+		"invalid region": {
+			diagnostics: []v1beta2.Diagnostic{{Severity: SeverityError, Summary: "Invalid Alibaba Cloud region", Detail: "xx is not valid"}},
+			want:        types.InvalidRegion,
+		},
+		"authentication": {
+			diagnostics: []v1beta2.Diagnostic{{Severity: SeverityError, Summary: "error configuring Terraform AWS Provider", Detail: "InvalidClientTokenId: The security token included in the request is invalid"}},
+			want:        types.AuthenticationFailed,
+		},
+		"quota": {
+			diagnostics: []v1beta2.Diagnostic{{Severity: SeverityError, Summary: "creating EC2 Instance", Detail: "VcpuLimitExceeded: You have requested more vCPU capacity than your current vCPU limit"}},
+			want:        types.QuotaExceeded,
+		},
+		"conflict": {
+			diagnostics: []v1beta2.Diagnostic{{Severity: SeverityError, Summary: "creating OSS bucket", Detail: "BucketAlreadyExists"}},
+			want:        types.ResourceConflict,
+		},
+		"timeout": {
+			diagnostics: []v1beta2.Diagnostic{{Severity: SeverityError, Summary: "waiting for RDS instance", Detail: "timeout while waiting for state to become 'Running'"}},
+			want:        types.OperationTimeout,
+		},
+		"configuration errors which look like cloud errors": {
+			diagnostics: []v1beta2.Diagnostic{
+				{Severity: SeverityError, Summary: "Unsupported argument", Detail: `An argument named "timeout" is not expected here.`},
+				{Severity: SeverityError, Summary: "Invalid value for variable", Detail: `The quota of "instance_count" must be less than 10, the region is invalid.`},
+				{Severity: SeverityError, Summary: "Duplicate resource \"alicloud_vpc\" configuration", Detail: "A resource named \"conflict\" was already declared"},
+			},
+			want: types.ConfigurationApplyFailed,
+		},
+		"warnings are ignored": {
+			diagnostics: []v1beta2.Diagnostic{
+				{Severity: SeverityWarning, Summary: "Quota is almost used up"},
+				{Severity: SeverityError, Summary: "Unsupported argument"},
+			},
+			want: types.ConfigurationApplyFailed,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ClassifyDiagnostics(tc.diagnostics))
+		})
+	}
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	diagnostics := []v1beta2.Diagnostic{
+		{Severity: SeverityWarning, Summary: "Deprecated attribute"},
+		{Severity: SeverityError, Summary: "creating OSS bucket", Detail: "BucketAlreadyExists:\nthe name is not available", Address: "alicloud_oss_bucket.bucket"},
+		{Severity: SeverityError, Summary: "Unsupported argument"},
+	}
+	assert.Equal(t, "Error: alicloud_oss_bucket.bucket: creating OSS bucket: BucketAlreadyExists: the name is not available; Unsupported argument",
+		FormatDiagnostics(diagnostics))
+	assert.True(t, HasError(diagnostics))
+	assert.False(t, HasError(diagnostics[:1]))
+}
+
+func TestIsCloudErrorState(t *testing.T) {
+	assert.True(t, IsCloudErrorState(types.InvalidRegion))
+	assert.True(t, IsCloudErrorState(types.QuotaExceeded))
+	assert.False(t, IsCloudErrorState(types.ConfigurationApplyFailed))
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -13,6 +14,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/controllers/client"
 )
 
 func getPods(ctx context.Context, client kubernetes.Interface, namespace, jobName string) (*v1.PodList, error) {
@@ -27,33 +29,25 @@ func getPods(ctx context.Context, client kubernetes.Interface, namespace, jobNam
 	return pods, nil
 }
 
-func getPodLog(ctx context.Context, client kubernetes.Interface, namespace, jobName, containerName, initContainerName string) (types.Stage, string, error) {
-	var (
-		targetContainer = containerName
-		stage           = types.ApplyStage
-	)
+func getPodLog(ctx context.Context, client kubernetes.Interface, namespace, jobName, containerName, initContainerName string) (types.Stage, string, bool, error) {
 	pods, err := getPods(ctx, client, namespace, jobName)
 	if err != nil || pods == nil || len(pods.Items) == 0 {
 		klog.V(4).InfoS("pods are not found", "PodName", jobName, "Namepspace", namespace, "Error", err)
-		return stage, "", nil
+		return types.ApplyStage, "", false, nil
 	}
-	pod := pods.Items[0]
-
-	// Here are two cases for Pending phase: 1) init container `terraform init` is not finished yet, 2) pod is not ready yet.
-	if pod.Status.Phase == v1.PodPending {
-		for _, c := range pod.Status.InitContainerStatuses {
-			if c.Name == initContainerName && !c.Ready {
-				targetContainer = initContainerName
-				stage = types.InitStage
-				break
-			}
-		}
+	pod := latestPod(pods.Items)
+
+	// The logs are only read when `terraform init`, `terraform import` or `terraform apply/destroy` exits with errors, as a running
+	// Terraform process hasn't reported any errors yet.
+	targetContainer, stage, previous, failed := getFailedContainer(pod, containerName, initContainerName)
+	if !failed {
+		return stage, "", false, nil
 	}
 
-	req := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: targetContainer})
+	req := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: targetContainer, Previous: previous})
 	logs, err := req.Stream(ctx)
 	if err != nil {
-		return stage, "", err
+		return stage, "", true, err
 	}
 	defer func(logs io.ReadCloser) {
 		err := logs.Close()
@@ -64,12 +58,107 @@ func getPodLog(ctx context.Context, client kubernetes.Interface, namespace, jobN
 
 	log, err := flushStream(logs, pod.Name)
 	if err != nil {
-		return stage, "", err
+		return stage, "", true, err
 	}
 
 	// To learn how it works, please refer to https://github.com/zzxwill/terraform-log-stripper.
 	strippedLog := stripColor(log)
-	return stage, strippedLog, nil
+	return stage, strippedLog, true, nil
+}
+
+// GetJobLogs gets the full logs of `terraform init`, `terraform import` and `terraform apply/destroy` of the latest pod
+// of the Job
+func GetJobLogs(ctx context.Context, namespace, jobName, containerName, initContainerName string) (string, error) {
+	clientSet, err := client.Init()
+	if err != nil {
+		return "", err
+	}
+	return getJobLogs(ctx, clientSet, namespace, jobName, containerName, initContainerName)
+}
+
+func getJobLogs(ctx context.Context, client kubernetes.Interface, namespace, jobName, containerName, initContainerName string) (string, error) {
+	pods, err := getPods(ctx, client, namespace, jobName)
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no pods of the Job %s/%s are found", namespace, jobName)
+	}
+	pod := latestPod(pods.Items)
+
+	containers := []string{initContainerName}
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name == types.TerraformImportContainerName {
+			containers = append(containers, c.Name)
+		}
+	}
+	containers = append(containers, containerName)
+
+	var buf strings.Builder
+	for _, c := range containers {
+		if c == "" {
+			continue
+		}
+		logs, err := client.CoreV1().Pods(namespace).GetLogs(pod.Name, &v1.PodLogOptions{Container: c}).Stream(ctx)
+		if err != nil {
+			return "", err
+		}
+		log, err := flushStream(logs, pod.Name)
+		_ = logs.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "==> %s <==\n%s\n", c, stripColor(log))
+	}
+	return buf.String(), nil
+}
+
+// latestPod is the latest pod of a Job, as a failed pod is retried by a new pod
+func latestPod(pods []v1.Pod) v1.Pod {
+	pod := pods[0]
+	for _, p := range pods[1:] {
+		if pod.CreationTimestamp.Before(&p.CreationTimestamp) {
+			pod = p
+		}
+	}
+	return pod
+}
+
+// getFailedContainer finds the Terraform container which exits with errors. The pods of the legacy Jobs restart on
+// failure, whose containers may be restarting, and then the logs of their previous runs are read.
+func getFailedContainer(pod v1.Pod, containerName, initContainerName string) (string, types.Stage, bool, bool) {
+	initStages := map[string]types.Stage{
+		initContainerName:                  types.InitStage,
+		types.TerraformImportContainerName: types.ImportStage,
+	}
+	for _, c := range pod.Status.InitContainerStatuses {
+		stage, ok := initStages[c.Name]
+		if !ok {
+			continue
+		}
+		if isFailed(c.State) {
+			return c.Name, stage, false, true
+		}
+		if isFailed(c.LastTerminationState) {
+			return c.Name, stage, true, true
+		}
+	}
+	for _, c := range pod.Status.ContainerStatuses {
+		if c.Name != containerName {
+			continue
+		}
+		if isFailed(c.State) {
+			return containerName, types.ApplyStage, false, true
+		}
+		if isFailed(c.LastTerminationState) {
+			return containerName, types.ApplyStage, true, true
+		}
+	}
+	return containerName, types.ApplyStage, false, false
+}
+
+func isFailed(state v1.ContainerState) bool {
+	return state.Terminated != nil && state.Terminated.ExitCode != 0
 }
 
 func flushStream(rc io.ReadCloser, podName string) (string, error) {
@@ -51,10 +51,30 @@ func TestGetPodLog(t *testing.T) {
 		},
 		Status: v1.PodStatus{
 			Phase: v1.PodRunning,
+			ContainerStatuses: []v1.ContainerStatus{{
+				Name:                 "terraform-executor",
+				LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}},
+			}},
+		},
+	}
+	runningPod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "p2",
+			Namespace: "default",
+			Labels: map[string]string{
+				"job-name": "j2",
+			},
+		},
+		Status: v1.PodStatus{
+			Phase: v1.PodPending,
+			InitContainerStatuses: []v1.ContainerStatus{{
+				Name:  "terraform-init",
+				State: v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+			}},
 		},
 	}
 
-	k8sClientSet := fakeclient.NewSimpleClientset(pod)
+	k8sClientSet := fakeclient.NewSimpleClientset(pod, runningPod)
 
 	patches := gomonkey.ApplyMethod(reflect.TypeOf(&fake.FakePods{}), "GetLogs",
 		func(_ *fake.FakePods, _ string, _ *v1.PodLogOptions) *rest.Request {
@@ -92,10 +112,23 @@ func TestGetPodLog(t *testing.T) {
 				errMsg: "client rate limiter Wait returned an error: can not be accept",
 			},
 		},
+		{
+			name: "Terraform is running, so the logs are not read",
+			args: args{
+				client:            k8sClientSet,
+				namespace:         "default",
+				name:              "j2",
+				containerName:     "terraform-executor",
+				initContainerName: "terraform-init",
+			},
+			want: want{
+				state: types.ApplyStage,
+			},
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			state, got, err := getPodLog(ctx, tc.args.client, tc.args.namespace, tc.args.name, tc.args.containerName, tc.args.initContainerName)
+			state, got, _, err := getPodLog(ctx, tc.args.client, tc.args.namespace, tc.args.name, tc.args.containerName, tc.args.initContainerName)
 			if tc.want.errMsg != "" || err != nil {
 				assert.EqualError(t, err, tc.want.errMsg)
 			} else {
@@ -106,6 +139,83 @@ func TestGetPodLog(t *testing.T) {
 	}
 }
 
+func TestGetJobLogs(t *testing.T) {
+	ctx := context.Background()
+	older := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:              "p1",
+		Namespace:         "default",
+		Labels:            map[string]string{"job-name": "j1"},
+		CreationTimestamp: metav1.Unix(100, 0),
+	}}
+	newer := older.DeepCopy()
+	newer.Name = "p2"
+	newer.CreationTimestamp = metav1.Unix(200, 0)
+	k8sClientSet := fakeclient.NewSimpleClientset(older, newer)
+
+	logs, err := getJobLogs(ctx, k8sClientSet, "default", "j1", "terraform-executor", "terraform-init")
+	assert.Nil(t, err)
+	assert.Equal(t, "==> terraform-init <==\nfake logs\n==> terraform-executor <==\nfake logs\n", logs)
+
+	_, err = getJobLogs(ctx, k8sClientSet, "default", "j2", "terraform-executor", "terraform-init")
+	assert.EqualError(t, err, "no pods of the Job default/j2 are found")
+}
+
+func TestGetFailedContainer(t *testing.T) {
+	failed := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1}}
+	succeeded := v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 0}}
+
+	testcases := map[string]struct {
+		pod      v1.Pod
+		name     string
+		stage    types.Stage
+		previous bool
+		failed   bool
+	}{
+		"init failed": {
+			pod:    v1.Pod{Status: v1.PodStatus{InitContainerStatuses: []v1.ContainerStatus{{Name: "terraform-init", State: failed}}}},
+			name:   "terraform-init",
+			stage:  types.InitStage,
+			failed: true,
+		},
+		"apply failed and restarting": {
+			pod: v1.Pod{Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{{Name: "terraform-init", State: succeeded}},
+				ContainerStatuses:     []v1.ContainerStatus{{Name: "terraform-executor", LastTerminationState: failed}},
+			}},
+			name:     "terraform-executor",
+			stage:    types.ApplyStage,
+			previous: true,
+			failed:   true,
+		},
+		"import failed": {
+			pod: v1.Pod{Status: v1.PodStatus{InitContainerStatuses: []v1.ContainerStatus{
+				{Name: "terraform-init", State: succeeded},
+				{Name: types.TerraformImportContainerName, State: failed},
+			}}},
+			name:   types.TerraformImportContainerName,
+			stage:  types.ImportStage,
+			failed: true,
+		},
+		"succeeded": {
+			pod: v1.Pod{Status: v1.PodStatus{
+				InitContainerStatuses: []v1.ContainerStatus{{Name: "terraform-init", State: succeeded}},
+				ContainerStatuses:     []v1.ContainerStatus{{Name: "terraform-executor", State: succeeded}},
+			}},
+			name:  "terraform-executor",
+			stage: types.ApplyStage,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			container, stage, previous, failed := getFailedContainer(tc.pod, "terraform-executor", "terraform-init")
+			assert.Equal(t, tc.name, container)
+			assert.Equal(t, tc.stage, stage)
+			assert.Equal(t, tc.previous, previous)
+			assert.Equal(t, tc.failed, failed)
+		})
+	}
+}
+
 func TestFlushStream(t *testing.T) {
 	type args struct {
 		rc   io.ReadCloser
@@ -2,44 +2,95 @@ package terraform
 
 import (
 	"context"
+	"regexp"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 	"k8s.io/klog/v2"
 
 	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	"github.com/oam-dev/terraform-controller/controllers/client"
 )
 
-// GetTerraformStatus will get Terraform execution status
-func GetTerraformStatus(ctx context.Context, jobNamespace, jobName, containerName, initContainerName string) (types.ConfigurationState, error) {
+// maxEventMessageLength is the maximum length of an error message in an Event
+const maxEventMessageLength = 512
+
+// maxLogTailLines is the number of the last lines of the logs reported when Terraform fails without error messages
+const maxLogTailLines = 10
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// GetTerraformStatus will get Terraform execution status. The diagnostics are parsed from the machine-readable output
+// of Terraform, they're empty if the execution succeeds or the failure happens in the init stage.
+func GetTerraformStatus(ctx context.Context, jobNamespace, jobName, containerName, initContainerName string) (types.ConfigurationState, []v1beta2.Diagnostic, error) {
 	klog.InfoS("checking Terraform init and execution status", "Namespace", jobNamespace, "Job", jobName)
 	clientSet, err := client.Init()
 	if err != nil {
 		klog.ErrorS(err, "failed to init clientSet")
-		return types.ConfigurationProvisioningAndChecking, err
+		return types.ConfigurationProvisioningAndChecking, nil, err
 	}
 
 	// check the stage of the pod
-	stage, logs, err := getPodLog(ctx, clientSet, jobNamespace, jobName, containerName, initContainerName)
+	stage, logs, failed, err := getPodLog(ctx, clientSet, jobNamespace, jobName, containerName, initContainerName)
 	if err != nil {
 		klog.ErrorS(err, "failed to get pod logs")
-		return types.ConfigurationProvisioningAndChecking, err
+		return types.ConfigurationProvisioningAndChecking, nil, err
+	}
+
+	return analyzeTerraformStatus(stage, logs, failed)
+}
+
+// analyzeTerraformStatus analyzes the logs of the Terraform container of the stage. A container which exits with
+// errors is always reported as failed, even if Terraform doesn't print any error messages, like when it's killed.
+func analyzeTerraformStatus(stage types.Stage, logs string, failed bool) (types.ConfigurationState, []v1beta2.Diagnostic, error) {
+	if stage == types.ApplyStage {
+		if diagnostics := ParseDiagnostics(logs); HasError(diagnostics) {
+			return ClassifyDiagnostics(diagnostics), diagnostics, errors.New(FormatDiagnostics(diagnostics))
+		}
 	}
 
 	success, state, errMsg := analyzeTerraformLog(logs, stage)
-	if success {
-		return state, nil
+	if !success {
+		return state, nil, errors.New(errMsg)
+	}
+	if !failed {
+		return state, nil, nil
+	}
+
+	state = types.ConfigurationApplyFailed
+	if stage == types.InitStage {
+		state = types.TerraformInitError
+	}
+	errMsg = "the Terraform container exited with errors"
+	if tail := logTail(logs, maxLogTailLines); tail != "" {
+		errMsg += ": " + tail
 	}
+	return state, nil, errors.New(errMsg)
+}
 
-	return state, errors.New(errMsg)
+// logTail returns the last n non-empty lines of the logs
+func logTail(logs string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(logs, ""), "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }
 
-// analyzeTerraformLog will analyze the logs of Terraform apply pod, returns true if check is ok.
+// analyzeTerraformLog will analyze the colored logs of Terraform, returns true if check is ok. It's the fallback when
+// the logs are not machine-readable, like the logs of `terraform init`.
 func analyzeTerraformLog(logs string, stage types.Stage) (bool, types.ConfigurationState, string) {
 	lines := strings.Split(logs, "\n")
 	for i, line := range lines {
-		if strings.Contains(line, "31mError:") {
+		// `terraform import` runs with -no-color
+		if strings.Contains(line, "31mError:") || (stage == types.ImportStage && strings.HasPrefix(line, "Error:")) {
 			errMsg := strings.Join(lines[i:], "\n")
 			if strings.Contains(errMsg, "Invalid Alibaba Cloud region") {
 				return false, types.InvalidRegion, errMsg
@@ -47,7 +98,10 @@ func analyzeTerraformLog(logs string, stage types.Stage) (bool, types.Configurat
 			switch stage {
 			case types.InitStage:
 				return false, types.TerraformInitError, errMsg
-			case types.ApplyStage:
+			case types.ImportStage, types.ApplyStage:
+				if state := classifyErrorMessage(errMsg); state != "" {
+					return false, state, errMsg
+				}
 				return false, types.ConfigurationApplyFailed, errMsg
 			}
 		}
@@ -55,3 +109,28 @@ func analyzeTerraformLog(logs string, stage types.Stage) (bool, types.Configurat
 
 	return true, types.ConfigurationProvisioningAndChecking, ""
 }
+
+// TrimErrorMessage turns the error analyzed from the Terraform logs into a short single-line message, which is
+// suitable for an Event
+func TrimErrorMessage(errMsg string) string {
+	var parts []string
+	for _, line := range strings.Split(ansiEscape.ReplaceAllString(errMsg, ""), "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "│╷╵ "))
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	return truncate(strings.Join(parts, " "), maxEventMessageLength)
+}
+
+// truncate cuts the string to at most max bytes with "..." at the end, without splitting a multi-byte character
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len("...")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
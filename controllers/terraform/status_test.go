@@ -2,6 +2,7 @@ package terraform
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/agiledragon/gomonkey/v2"
@@ -49,7 +50,7 @@ func TestGetTerraformStatus(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			state, err := GetTerraformStatus(ctx, tc.args.name, tc.args.namespace, tc.args.containerName, "")
+			state, _, err := GetTerraformStatus(ctx, tc.args.name, tc.args.namespace, tc.args.containerName, "")
 			if tc.want.errMsg != "" {
 				assert.EqualError(t, err, tc.want.errMsg)
 			} else {
@@ -58,6 +59,12 @@ func TestGetTerraformStatus(t *testing.T) {
 			}
 		})
 	}
+
+	// `terraform import` runs with -no-color
+	success, state, errMsg := analyzeTerraformLog("alicloud_oss_bucket.b: Importing from ID \"b\"...\nError: Cannot import non-existent remote object", types.ImportStage)
+	assert.False(t, success)
+	assert.Equal(t, types.ConfigurationApplyFailed, state)
+	assert.Equal(t, "Error: Cannot import non-existent remote object", errMsg)
 }
 
 func TestGetTerraformStatus2(t *testing.T) {
@@ -92,7 +99,7 @@ func TestGetTerraformStatus2(t *testing.T) {
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			state, err := GetTerraformStatus(ctx, tc.args.name, tc.args.namespace, tc.args.containerName, "")
+			state, _, err := GetTerraformStatus(ctx, tc.args.name, tc.args.namespace, tc.args.containerName, "")
 			if tc.want.errMsg != "" {
 				assert.Contains(t, err.Error(), tc.want.errMsg)
 			} else {
@@ -140,6 +147,26 @@ func TestAnalyzeTerraformLog(t *testing.T) {
 				errMsg:  "Invalid Alibaba Cloud region",
 			},
 		},
+		{
+			name: "known class of cloud errors",
+			args: args{
+				logs: "31mError:\nInvalidAccessKeyId.NotFound: Specified access key is not found",
+			},
+			want: want{
+				success: false,
+				state:   types.AuthenticationFailed,
+			},
+		},
+		{
+			name: "succeeded logs",
+			args: args{
+				logs: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+			},
+			want: want{
+				success: true,
+				state:   types.ConfigurationProvisioningAndChecking,
+			},
+		},
 	}
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -154,3 +181,74 @@ func TestAnalyzeTerraformLog(t *testing.T) {
 		})
 	}
 }
+
+func TestAnalyzeTerraformStatus(t *testing.T) {
+	testcases := map[string]struct {
+		stage  types.Stage
+		logs   string
+		failed bool
+		state  types.ConfigurationState
+		errMsg string
+	}{
+		"running": {
+			stage: types.ApplyStage,
+			state: types.ConfigurationProvisioningAndChecking,
+		},
+		"apply error": {
+			stage:  types.ApplyStage,
+			logs:   "31mError: Unsupported argument",
+			failed: true,
+			state:  types.ConfigurationApplyFailed,
+			errMsg: "31mError: Unsupported argument",
+		},
+		"apply exits without error messages": {
+			stage:  types.ApplyStage,
+			logs:   "line1\n\n" + strings.Repeat("line\n", maxLogTailLines-1) + "killed\n",
+			failed: true,
+			state:  types.ConfigurationApplyFailed,
+			errMsg: "the Terraform container exited with errors: " + strings.Repeat("line\n", maxLogTailLines-1) + "killed",
+		},
+		"init exits without logs": {
+			stage:  types.InitStage,
+			failed: true,
+			state:  types.TerraformInitError,
+			errMsg: "the Terraform container exited with errors",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			state, _, err := analyzeTerraformStatus(tc.stage, tc.logs, tc.failed)
+			assert.Equal(t, tc.state, state)
+			if tc.errMsg != "" {
+				assert.EqualError(t, err, tc.errMsg)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTrimErrorMessage(t *testing.T) {
+	testcases := map[string]struct {
+		errMsg string
+		want   string
+	}{
+		"terraform error box": {
+			errMsg: "\x1b[31m╷\x1b[0m\x1b[0m\n\x1b[31m│\x1b[0m \x1b[0m\x1b[1m\x1b[31mError: \x1b[0m\x1b[0m\x1b[1mInvalid region\x1b[0m\n\x1b[31m│\x1b[0m \n\x1b[31m│\x1b[0m \x1b[0mregion xx is not supported\n\x1b[31m╵\x1b[0m\x1b[0m\n",
+			want:   "Error: Invalid region region xx is not supported",
+		},
+		"long message": {
+			errMsg: "Error: " + strings.Repeat("a", 600),
+			want:   "Error: " + strings.Repeat("a", maxEventMessageLength-10) + "...",
+		},
+		"long message of multi-byte characters": {
+			errMsg: "Error: " + strings.Repeat("错", 200),
+			want:   "Error: " + strings.Repeat("错", (maxEventMessageLength-10)/3) + "...",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, TrimErrorMessage(tc.errMsg))
+		})
+	}
+}
@@ -0,0 +1,332 @@
+/*
+Copyright 2022 The KubeVela Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	tfcfg "github.com/oam-dev/terraform-controller/controllers/configuration"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
+	"github.com/oam-dev/terraform-controller/controllers/maintenance"
+	"github.com/oam-dev/terraform-controller/controllers/process"
+)
+
+const (
+	terraformRunFinalizer = "terraformrun.finalizers.terraform-controller"
+	// runResyncInterval is the interval to sync a running TerraformRun with its Job, in case the Job isn't labeled
+	runResyncInterval = time.Minute
+)
+
+// TerraformRunReconciler reconciles a TerraformRun object. The TerraformRuns created by the ConfigurationReconciler
+// are only synced with their Jobs, while the ones created by users get their Jobs created from the apply Jobs of
+// the Configurations.
+type TerraformRunReconciler struct {
+	client.Client
+	Log    logr.Logger
+	Scheme *runtime.Scheme
+	// Paused suspends all the Configurations, the TerraformRuns created by users wait for the controller to resume
+	Paused bool
+	// JobLimiter caps the number of the running Terraform Jobs, which is shared with the ConfigurationReconciler
+	JobLimiter *limiter.JobLimiter
+}
+
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=terraformruns,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=terraform.core.oam.dev,resources=terraformruns/status,verbs=get;update;patch
+
+// Reconcile creates the Job of a TerraformRun and syncs the phase of the TerraformRun with the Job
+func (r *TerraformRunReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	klog.InfoS("reconciling TerraformRun...", "NamespacedName", req.NamespacedName)
+
+	var run v1beta2.TerraformRun
+	if err := r.Get(ctx, req.NamespacedName, &run); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	createdByController := run.Labels[types.LabelCreatedBy] == types.CreatedByController
+
+	if !run.DeletionTimestamp.IsZero() {
+		if !controllerutil.ContainsFinalizer(&run, terraformRunFinalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := r.deleteJob(ctx, &run); err != nil {
+			return ctrl.Result{}, err
+		}
+		controllerutil.RemoveFinalizer(&run, terraformRunFinalizer)
+		return ctrl.Result{}, r.Update(ctx, &run)
+	}
+
+	if !createdByController && !controllerutil.ContainsFinalizer(&run, terraformRunFinalizer) {
+		controllerutil.AddFinalizer(&run, terraformRunFinalizer)
+		if err := r.Update(ctx, &run); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to add finalizer")
+		}
+	}
+
+	switch {
+	case run.Status.Phase == types.RunOutcomeSucceeded || run.Status.Phase == types.RunOutcomeFailed:
+		return ctrl.Result{}, nil
+	case run.Status.JobName == "" && createdByController:
+		// The status is set right after the TerraformRun is created
+		return ctrl.Result{}, nil
+	case run.Status.JobName == "":
+		return r.startRun(ctx, &run)
+	default:
+		return r.syncRun(ctx, &run)
+	}
+}
+
+// startRun creates the Job of a TerraformRun created by users
+func (r *TerraformRunReconciler) startRun(ctx context.Context, run *v1beta2.TerraformRun) (ctrl.Result, error) {
+	configuration, err := tfcfg.Get(ctx, r.Client, k8stypes.NamespacedName{Name: run.Spec.ConfigurationName, Namespace: run.Namespace})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, fmt.Sprintf("Configuration %s is not found", run.Spec.ConfigurationName))
+		}
+		return ctrl.Result{}, err
+	}
+	if !configuration.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, fmt.Sprintf("Configuration %s is being deleted", configuration.Name))
+	}
+
+	// The cloud resources of a protected Configuration can't be destroyed by a run either
+	if run.Spec.Type == types.TerraformDestroy && configuration.Spec.DeletionProtection {
+		return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed,
+			fmt.Sprintf("Configuration %s is protected from deletion, its cloud resources can't be destroyed", configuration.Name))
+	}
+
+	if r.Paused || configuration.Spec.Suspend {
+		if err := r.updateStatus(ctx, run, types.RunOutcomePending, "Waiting for the Configuration to be resumed"); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	applyJob, activeJob, err := r.getConfigurationJobs(ctx, &configuration, run)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if applyJob == nil {
+		if err := r.updateStatus(ctx, run, types.RunOutcomePending, "Waiting for the Configuration to be applied"); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+	// The Terraform Jobs run with -lock=false, so only one of them could write the state at a time
+	if activeJob != nil {
+		msg := fmt.Sprintf("Waiting for the Terraform Job %s/%s to finish", activeJob.Namespace, activeJob.Name)
+		if err := r.updateStatus(ctx, run, types.RunOutcomePending, msg); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	open, next, err := r.checkWindow(ctx, &configuration, run.Spec.Type)
+	if err != nil {
+		return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, err.Error())
+	}
+	if !open {
+		message := types.MessageWaitingForApplyWindow
+		if run.Spec.Type == types.TerraformDestroy {
+			message = types.MessageWaitingForDestroyWindow
+		}
+		if err := r.updateStatus(ctx, run, types.RunOutcomePending, windowMessage(message, next)); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfterWindow(next)}, nil
+	}
+
+	job, err := process.AssembleRunJob(run, applyJob)
+	if err != nil {
+		return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, err.Error())
+	}
+	// Destroying is never queued, the same as the destroy Jobs of the Configurations
+	if run.Spec.Type == types.TerraformDestroy {
+		err = r.Create(ctx, job)
+	} else {
+		var queued string
+		queued, err = r.JobLimiter.Create(ctx, r.Client, job)
+		if err == nil && queued != "" {
+			if err := r.updateStatus(ctx, run, types.RunOutcomePending, queued); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+		}
+	}
+	if err != nil && !kerrors.IsAlreadyExists(err) {
+		return ctrl.Result{}, err
+	}
+	klog.InfoS("created the Job of TerraformRun", "Namespace", run.Namespace, "Name", run.Name, "Job", job.Name)
+
+	now := metav1.Now()
+	run.Status = v1beta2.TerraformRunStatus{
+		Phase:        types.RunOutcomeRunning,
+		JobName:      job.Name,
+		JobNamespace: job.Namespace,
+		StartTime:    &now,
+	}
+	for _, c := range job.Spec.Template.Spec.Containers {
+		if c.Name == types.TerraformContainerName {
+			run.Status.Image = c.Image
+		}
+	}
+	var cm v1.ConfigMap
+	if err := r.Get(ctx, client.ObjectKey{Name: fmt.Sprintf(types.TFInputConfigMapName, configuration.Name), Namespace: job.Namespace}, &cm); err == nil {
+		run.Status.ConfigurationHash = process.ConfigurationHash(cm.Data[types.TerraformHCLConfigurationName])
+	}
+	var secret v1.Secret
+	if err := r.Get(ctx, client.ObjectKey{Name: fmt.Sprintf(types.TFVariableSecret, configuration.Name), Namespace: job.Namespace}, &secret); err == nil {
+		run.Status.VariableHash = process.VariableHash(secret.Data)
+	}
+	if !configuration.Spec.InlineCredentials {
+		run.Status.ProviderReference = tfcfg.GetProviderNamespacedName(configuration)
+	}
+	return ctrl.Result{RequeueAfter: runResyncInterval}, r.Status().Update(ctx, run)
+}
+
+// syncRun syncs the phase of the TerraformRun with its Job
+func (r *TerraformRunReconciler) syncRun(ctx context.Context, run *v1beta2.TerraformRun) (ctrl.Result, error) {
+	var job batchv1.Job
+	if err := r.Get(ctx, client.ObjectKey{Name: run.Status.JobName, Namespace: run.Status.JobNamespace}, &job); err != nil {
+		if kerrors.IsNotFound(err) {
+			return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, "The Job is deleted before it finishes")
+		}
+		return ctrl.Result{}, err
+	}
+	// The Jobs created by the controller are recreated with the same names
+	if name, ok := job.Labels[types.LabelRun]; ok && name != run.Name {
+		return ctrl.Result{}, r.updateStatus(ctx, run, types.RunOutcomeFailed, fmt.Sprintf("The Job is replaced by TerraformRun %s before it finishes", name))
+	}
+
+	phase, message := process.RunPhase(&job)
+	if phase == types.RunOutcomeRunning {
+		return ctrl.Result{RequeueAfter: runResyncInterval}, nil
+	}
+	run.Status.CompletionTime = job.Status.CompletionTime
+	if run.Status.CompletionTime == nil {
+		now := metav1.Now()
+		run.Status.CompletionTime = &now
+	}
+	return ctrl.Result{}, r.updateStatus(ctx, run, phase, message)
+}
+
+// getConfigurationJobs finds the apply Job of the Configuration wherever the controller namespace is, and a Job of the
+// Configuration which is still running, like its apply or destroy Job, or the Job of another TerraformRun
+func (r *TerraformRunReconciler) getConfigurationJobs(ctx context.Context, configuration *v1beta2.Configuration, run *v1beta2.TerraformRun) (*batchv1.Job, *batchv1.Job, error) {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.MatchingLabels{
+		types.LabelCreatedBy:      types.CreatedByController,
+		types.LabelOwnedBy:        configuration.Name,
+		types.LabelOwnedNamespace: configuration.Namespace,
+	}); err != nil {
+		return nil, nil, err
+	}
+	var applyJob, activeJob *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if job.Name == configuration.Name+"-"+string(types.TerraformApply) {
+			applyJob = job
+		}
+		if job.Labels[types.LabelRun] == run.Name {
+			continue
+		}
+		if phase, _ := process.RunPhase(job); phase == types.RunOutcomeRunning && activeJob == nil {
+			activeJob = job
+		}
+	}
+	return applyJob, activeJob, nil
+}
+
+// checkWindow tells whether the maintenance window of the Configuration is open for the type of the run, plan isn't
+// restricted
+func (r *TerraformRunReconciler) checkWindow(ctx context.Context, configuration *v1beta2.Configuration, runType types.TerraformExecutionType) (bool, time.Time, error) {
+	if runType == types.TerraformPlan {
+		return true, time.Time{}, nil
+	}
+	applyWindow, destroyWindow, err := maintenance.GetWindows(ctx, r.Client, configuration)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	window, name := applyWindow, "apply"
+	if runType == types.TerraformDestroy {
+		window, name = destroyWindow, "destroy"
+	}
+	open, next, err := maintenance.Check(window, time.Now())
+	if err != nil {
+		return false, time.Time{}, errors.Wrapf(err, "invalid %s window", name)
+	}
+	return open, next, nil
+}
+
+func (r *TerraformRunReconciler) deleteJob(ctx context.Context, run *v1beta2.TerraformRun) error {
+	if run.Status.JobName == "" {
+		return nil
+	}
+	var job batchv1.Job
+	if err := r.Get(ctx, client.ObjectKey{Name: run.Status.JobName, Namespace: run.Status.JobNamespace}, &job); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if job.Labels[types.LabelRun] != run.Name {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+}
+
+func (r *TerraformRunReconciler) updateStatus(ctx context.Context, run *v1beta2.TerraformRun, phase, message string) error {
+	if run.Status.Phase == phase && run.Status.Message == message {
+		return nil
+	}
+	run.Status.Phase, run.Status.Message = phase, message
+	return r.Status().Update(ctx, run)
+}
+
+// SetupWithManager setups with a manager
+func (r *TerraformRunReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1beta2.TerraformRun{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(findTerraformRunForJob)).
+		Complete(r)
+}
+
+// findTerraformRunForJob maps a Terraform Job to its TerraformRun, which is in the namespace of the Configuration
+func findTerraformRunForJob(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels[types.LabelRun] == "" || labels[types.LabelOwnedNamespace] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: k8stypes.NamespacedName{
+		Name:      labels[types.LabelRun],
+		Namespace: labels[types.LabelOwnedNamespace],
+	}}}
+}
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/oam-dev/terraform-controller/api/types"
+	"github.com/oam-dev/terraform-controller/api/v1beta2"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
+)
+
+func TestTerraformRunReconcile(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	corev1.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	configuration := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"},
+		Spec:       v1beta2.ConfigurationSpec{HCL: "hcl"},
+	}
+	run := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan-1", Namespace: "default", UID: "run-uid"},
+		Spec:       v1beta2.TerraformRunSpec{ConfigurationName: "a", Type: types.TerraformPlan},
+	}
+	r := &TerraformRunReconciler{Scheme: s}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(configuration, run).
+		WithStatusSubresource(run, &batchv1.Job{}).Build()
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(run)}
+	getRun := func() *v1beta2.TerraformRun {
+		var got v1beta2.TerraformRun
+		assert.Nil(t, r.Get(ctx, req.NamespacedName, &got))
+		return &got
+	}
+
+	// The run waits for the controller to resume
+	r.Paused = true
+	result, err := r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, result.RequeueAfter)
+	assert.Equal(t, "Waiting for the Configuration to be resumed", getRun().Status.Message)
+	r.Paused = false
+
+	// The run waits for the apply Job of the Configuration
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, result.RequeueAfter)
+	got := getRun()
+	assert.Contains(t, got.Finalizers, terraformRunFinalizer)
+	assert.Equal(t, types.RunOutcomePending, got.Status.Phase)
+
+	applyJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-apply",
+			Namespace: "default",
+			Labels: map[string]string{
+				types.LabelCreatedBy:      types.CreatedByController,
+				types.LabelOwnedBy:        "a",
+				types.LabelOwnedNamespace: "default",
+			},
+		},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: types.TerraformContainerName, Image: "terraform:1.0"}},
+		}}},
+	}
+	assert.Nil(t, r.Create(ctx, applyJob))
+	assert.Nil(t, r.Create(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "tf-a", Namespace: "default"},
+		Data:       map[string]string{types.TerraformHCLConfigurationName: "hcl"},
+	}))
+
+	// The run waits for the apply Job to finish
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, result.RequeueAfter)
+	assert.Equal(t, "Waiting for the Terraform Job default/a-apply to finish", getRun().Status.Message)
+	applyJob.Status.Succeeded = 1
+	assert.Nil(t, r.Status().Update(ctx, applyJob))
+
+	// A refresh run waits for the apply window, and is queued by the limits of the Terraform Jobs
+	refresh := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "refresh-1", Namespace: "default"},
+		Spec:       v1beta2.TerraformRunSpec{ConfigurationName: "a", Type: types.TerraformRefresh},
+	}
+	assert.Nil(t, r.Create(ctx, refresh))
+	refreshReq := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(refresh)}
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(configuration), configuration))
+	configuration.Spec.ApplyWindow = &v1beta2.MaintenanceWindow{Schedule: "0 0 1 1 *", Duration: metav1.Duration{Duration: time.Minute}}
+	assert.Nil(t, r.Update(ctx, configuration))
+	_, err = r.Reconcile(ctx, refreshReq)
+	assert.Nil(t, err)
+	assert.Nil(t, r.Get(ctx, refreshReq.NamespacedName, refresh))
+	assert.Contains(t, refresh.Status.Message, types.MessageWaitingForApplyWindow)
+	configuration.Spec.ApplyWindow = nil
+	assert.Nil(t, r.Update(ctx, configuration))
+
+	r.JobLimiter = limiter.NewJobLimiter(limiter.Limits{Global: 1})
+	running := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name: "b-apply", Namespace: "default", Labels: map[string]string{types.LabelCreatedBy: types.CreatedByController},
+	}}
+	assert.Nil(t, r.Create(ctx, running))
+	result, err = r.Reconcile(ctx, refreshReq)
+	assert.Nil(t, err)
+	assert.Equal(t, 30*time.Second, result.RequeueAfter)
+	assert.Nil(t, r.Get(ctx, refreshReq.NamespacedName, refresh))
+	assert.Contains(t, refresh.Status.Message, types.MessageJobQueued)
+	assert.Nil(t, r.Delete(ctx, running))
+	assert.Nil(t, r.Delete(ctx, refresh))
+	_, err = r.Reconcile(ctx, refreshReq)
+	assert.Nil(t, err)
+	r.JobLimiter = nil
+
+	// The Job of the run is created from the apply Job
+	result, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, runResyncInterval, result.RequeueAfter)
+	got = getRun()
+	assert.Equal(t, types.RunOutcomeRunning, got.Status.Phase)
+	assert.Equal(t, "terraform:1.0", got.Status.Image)
+	assert.NotEmpty(t, got.Status.ConfigurationHash)
+	assert.NotNil(t, got.Status.ProviderReference)
+	var job batchv1.Job
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Name: got.Status.JobName, Namespace: "default"}, &job))
+	assert.Equal(t, "plan-1", job.Labels[types.LabelRun])
+	assert.Equal(t, []string{"terraform", "plan", "-lock=false", "-json"}, job.Spec.Template.Spec.Containers[0].Command)
+
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.Equal(t, types.RunOutcomeRunning, getRun().Status.Phase)
+
+	job.Status.Succeeded = 1
+	assert.Nil(t, r.Status().Update(ctx, &job))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	got = getRun()
+	assert.Equal(t, types.RunOutcomeSucceeded, got.Status.Phase)
+	assert.NotNil(t, got.Status.CompletionTime)
+
+	// The Job is deleted with the run
+	assert.Nil(t, r.Delete(ctx, got))
+	_, err = r.Reconcile(ctx, req)
+	assert.Nil(t, err)
+	assert.NotNil(t, r.Get(ctx, client.ObjectKeyFromObject(&job), &batchv1.Job{}))
+	assert.NotNil(t, r.Get(ctx, req.NamespacedName, &v1beta2.TerraformRun{}))
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(applyJob), &batchv1.Job{}))
+}
+
+func TestTerraformRunReconcileFailed(t *testing.T) {
+	ctx := context.Background()
+	s := runtime.NewScheme()
+	v1beta2.AddToScheme(s)
+	batchv1.AddToScheme(s)
+
+	missing := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "default"},
+		Spec:       v1beta2.TerraformRunSpec{ConfigurationName: "not-exist", Type: types.TerraformPlan},
+	}
+	controllerRun := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-apply-100",
+			Namespace: "default",
+			Labels:    map[string]string{types.LabelCreatedBy: types.CreatedByController},
+		},
+		Spec: v1beta2.TerraformRunSpec{ConfigurationName: "a", Type: types.TerraformApply},
+		Status: v1beta2.TerraformRunStatus{
+			Phase:        types.RunOutcomeRunning,
+			JobName:      "a-apply",
+			JobNamespace: "vela-system",
+		},
+	}
+	protected := &v1beta2.Configuration{
+		ObjectMeta: metav1.ObjectMeta{Name: "protected", Namespace: "default"},
+		Spec:       v1beta2.ConfigurationSpec{HCL: "hcl", DeletionProtection: true},
+	}
+	destroyProtected := &v1beta2.TerraformRun{
+		ObjectMeta: metav1.ObjectMeta{Name: "destroy-protected", Namespace: "default"},
+		Spec:       v1beta2.TerraformRunSpec{ConfigurationName: "protected", Type: types.TerraformDestroy},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "a-apply",
+			Namespace: "vela-system",
+			Labels:    map[string]string{types.LabelRun: "a-apply-200"},
+		},
+	}
+	r := &TerraformRunReconciler{Scheme: s}
+	r.Client = fake.NewClientBuilder().WithScheme(s).WithObjects(missing, controllerRun, job, protected, destroyProtected).
+		WithStatusSubresource(missing, controllerRun, destroyProtected).Build()
+	assert.Nil(t, r.Status().Update(ctx, controllerRun))
+
+	testcases := map[string]struct {
+		run     *v1beta2.TerraformRun
+		message string
+	}{
+		"Configuration not found": {
+			run:     missing,
+			message: "Configuration not-exist is not found",
+		},
+		"Destroying a protected Configuration": {
+			run:     destroyProtected,
+			message: "Configuration protected is protected from deletion, its cloud resources can't be destroyed",
+		},
+		"Job replaced by another run": {
+			run:     controllerRun,
+			message: "The Job is replaced by TerraformRun a-apply-200 before it finishes",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tc.run)})
+			assert.Nil(t, err)
+			var got v1beta2.TerraformRun
+			assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(tc.run), &got))
+			assert.Equal(t, types.RunOutcomeFailed, got.Status.Phase)
+			assert.Equal(t, tc.message, got.Status.Message)
+		})
+	}
+
+	// The Jobs of the runs created by the controller are not deleted with the runs
+	var got v1beta2.TerraformRun
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(controllerRun), &got))
+	assert.Empty(t, got.Finalizers)
+}
+
+func TestFindTerraformRunForJob(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:      "a-apply",
+		Namespace: "vela-system",
+		Labels:    map[string]string{types.LabelRun: "a-apply-100", types.LabelOwnedNamespace: "default"},
+	}}
+	assert.Equal(t, []ctrl.Request{{NamespacedName: k8stypes.NamespacedName{Name: "a-apply-100", Namespace: "default"}}},
+		findTerraformRunForJob(context.Background(), job))
+	assert.Nil(t, findTerraformRunForJob(context.Background(), &batchv1.Job{}))
+}
@@ -19,8 +19,13 @@ package main
 import (
 	"os"
 	"time"
+	// embed the time zone database for the time zones of the maintenance windows
+	_ "time/tzdata"
 
 	"github.com/spf13/pflag"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apiserver/pkg/util/feature"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -28,12 +33,18 @@ import (
 	"k8s.io/klog/v2/textlogger"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/oam-dev/terraform-controller/api/types"
 	terraformv1beta1 "github.com/oam-dev/terraform-controller/api/v1beta1"
 	"github.com/oam-dev/terraform-controller/api/v1beta2"
 	"github.com/oam-dev/terraform-controller/controllers"
+	"github.com/oam-dev/terraform-controller/controllers/limiter"
+	"github.com/oam-dev/terraform-controller/controllers/metrics"
+	"github.com/oam-dev/terraform-controller/controllers/process"
+	"github.com/oam-dev/terraform-controller/controllers/runlog"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -55,12 +66,35 @@ func main() {
 	var syncPeriod time.Duration
 	var namespace string
 	var controllerNamespace string
+	var allowedOutputNamespaces []string
+	var allowedDependencyNamespaces []string
+	var configurationConcurrency, providerConcurrency int
+	var jobLimits limiter.Limits
+	var runLogOptions runlog.Options
+	var pauseReconciliation bool
+	var enableWebhook bool
+	var webhookPort int
 
 	pflag.BoolVar(&enableLeaderElection, "enable-leader-election", false, "Enable leader election for controller manager, this will ensure there is only one active controller manager.")
 	pflag.DurationVar(&syncPeriod, "informer-re-sync-interval", 10*time.Second, "controller shared informer lister full re-sync period")
 	pflag.StringVar(&metricsAddr, "metrics-addr", ":38080", "The address the metric endpoint binds to.")
 	pflag.StringVar(&namespace, "namespace", "", "Namespace to watch for resources, defaults to all namespaces")
 	pflag.StringVar(&controllerNamespace, "controller-namespace", "", "Namespace to run the terraform jobs")
+	pflag.StringSliceVar(&allowedOutputNamespaces, "allowed-output-namespaces", nil, "Namespaces which Configurations can publish outputs to besides their own namespaces, `*` allows all namespaces")
+	pflag.StringSliceVar(&allowedDependencyNamespaces, "allowed-dependency-namespaces", nil, "Namespaces whose Configurations can be depended on by the Configurations in other namespaces, `*` allows all namespaces")
+	pflag.IntVar(&configurationConcurrency, "configuration-max-concurrent-reconciles", 1, "The maximum number of Configurations reconciled at the same time")
+	pflag.IntVar(&providerConcurrency, "provider-max-concurrent-reconciles", 1, "The maximum number of Providers reconciled at the same time")
+	pflag.IntVar(&jobLimits.Global, "max-concurrent-jobs", 0, "The maximum number of Terraform Jobs running at the same time, 0 means unlimited. The destroy Jobs are counted but never queued")
+	pflag.IntVar(&jobLimits.PerNamespace, "max-concurrent-jobs-per-namespace", 0, "The maximum number of Terraform Jobs of the Configurations in a namespace running at the same time, 0 means unlimited. The destroy Jobs are counted but never queued")
+	pflag.IntVar(&jobLimits.PerProvider, "max-concurrent-jobs-per-provider", 0, "The maximum number of Terraform Jobs of the Configurations using a Provider running at the same time, 0 means unlimited. The destroy Jobs are counted but never queued")
+	pflag.StringVar(&runLogOptions.Sink, "run-log-sink", "", "Where the full logs of the Terraform runs are archived, configmap or s3, empty means the logs are not archived")
+	pflag.StringVar(&runLogOptions.S3Bucket, "run-log-s3-bucket", "", "The S3 bucket to archive the logs of the Terraform runs in")
+	pflag.StringVar(&runLogOptions.S3Prefix, "run-log-s3-prefix", "", "The prefix of the S3 objects which archive the logs of the Terraform runs")
+	pflag.StringVar(&runLogOptions.S3Region, "run-log-s3-region", "", "The region of the S3 bucket which archives the logs of the Terraform runs")
+	pflag.DurationVar(&runLogOptions.Retention, "run-log-retention", 30*24*time.Hour, "How long the archived logs of the Terraform runs are kept in ConfigMaps, 0 means forever. The logs in S3 are expired by the lifecycle rules of the bucket")
+	pflag.BoolVar(&pauseReconciliation, "pause-reconciliation", false, "Suspend all the Configurations, no Terraform Job is created or deleted while the status is still reported")
+	pflag.BoolVar(&enableWebhook, "enable-webhook", false, "Enable the validating webhook which rejects deleting the Configurations protected by spec.deletionProtection")
+	pflag.IntVar(&webhookPort, "webhook-port", 9443, "The port the webhook server listens on, the certificates are read from /tmp/k8s-webhook-server/serving-certs")
 	feature.DefaultMutableFeatureGate.AddFlag(pflag.CommandLine)
 
 	// embed klog
@@ -77,17 +111,27 @@ func main() {
 			BindAddress: metricsAddr,
 		},
 		WebhookServer: webhook.NewServer(webhook.Options{
-			Port: 9443,
+			Port: webhookPort,
 		}),
 		Scheme: scheme,
 	}
 
+	// Only the Terraform Jobs and their pods are cached. The Secrets and the ConfigMaps, including the ones referenced
+	// by variableFrom, are read from the API server, as only their metadata is watched.
+	createdByController := labels.SelectorFromSet(labels.Set{types.LabelCreatedBy: types.CreatedByController})
+	mgmOptions.Cache = cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&batchv1.Job{}: {Label: createdByController},
+			&corev1.Pod{}:  {Label: createdByController},
+		},
+	}
+	mgmOptions.Client = client.Options{
+		Cache: &client.CacheOptions{DisableFor: []client.Object{&corev1.Secret{}, &corev1.ConfigMap{}}},
+	}
 	// Only set specific namespace if provided, otherwise watch all namespaces
 	if namespace != "" {
-		mgmOptions.Cache = cache.Options{
-			DefaultNamespaces: map[string]cache.Config{
-				namespace: {},
-			},
+		mgmOptions.Cache.DefaultNamespaces = map[string]cache.Config{
+			namespace: {},
 		}
 	}
 
@@ -97,23 +141,78 @@ func main() {
 		os.Exit(1)
 	}
 
+	logArchiver, err := runlog.NewArchiver(runLogOptions, mgr.GetClient())
+	if err != nil {
+		setupLog.Error(err, "unable to create the archiver of the Terraform run logs")
+		os.Exit(1)
+	}
+	if retention := runlog.NewRetention(logArchiver, runLogOptions.Retention); retention != nil {
+		if err := mgr.Add(retention); err != nil {
+			setupLog.Error(err, "unable to set up the retention of the Terraform run logs")
+			os.Exit(1)
+		}
+	}
+
+	if err := metrics.RegisterConfigurationCollector(mgr.GetClient()); err != nil {
+		setupLog.Error(err, "unable to register the metrics of the Configurations")
+		os.Exit(1)
+	}
+
+	// The Jobs created before they were labeled are invisible to the cache
+	if err := mgr.Add(process.NewLegacyJobLabeler(mgr.GetClient(), mgr.GetAPIReader(), namespace)); err != nil {
+		setupLog.Error(err, "unable to set up the labeling of the legacy Terraform Jobs")
+		os.Exit(1)
+	}
+
+	jobLimiter := limiter.NewJobLimiter(jobLimits)
 	if err = (&controllers.ConfigurationReconciler{
-		Client:              mgr.GetClient(),
-		ControllerNamespace: controllerNamespace,
-		Log:                 ctrl.Log.WithName("controllers").WithName("Configuration"),
-		Scheme:              mgr.GetScheme(),
+		Client:                      mgr.GetClient(),
+		ControllerNamespace:         controllerNamespace,
+		AllowedOutputNamespaces:     allowedOutputNamespaces,
+		AllowedDependencyNamespaces: allowedDependencyNamespaces,
+		MaxConcurrentReconciles:     configurationConcurrency,
+		JobLimiter:                  jobLimiter,
+		LogArchiver:                 logArchiver,
+		Paused:                      pauseReconciliation,
+		Log:                         ctrl.Log.WithName("controllers").WithName("Configuration"),
+		Scheme:                      mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Configuration")
 		os.Exit(1)
 	}
 	if err = (&controllers.ProviderReconciler{
+		Client:                  mgr.GetClient(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("Provider"),
+		Scheme:                  mgr.GetScheme(),
+		MaxConcurrentReconciles: providerConcurrency,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Provider")
+		os.Exit(1)
+	}
+	if err = (&controllers.StackReconciler{
 		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Provider"),
+		Log:    ctrl.Log.WithName("controllers").WithName("Stack"),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Provider")
+		setupLog.Error(err, "unable to create controller", "controller", "Stack")
+		os.Exit(1)
+	}
+	if err = (&controllers.TerraformRunReconciler{
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("TerraformRun"),
+		Scheme:     mgr.GetScheme(),
+		Paused:     pauseReconciliation,
+		JobLimiter: jobLimiter,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TerraformRun")
 		os.Exit(1)
 	}
+	if enableWebhook {
+		if err = (&v1beta2.ConfigurationValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Configuration")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")